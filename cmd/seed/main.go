@@ -21,6 +21,31 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Create default equipment types and their checklists
+	if err := database.SeedEquipmentTypes(db); err != nil {
+		log.Fatalf("Failed to seed equipment types: %v", err)
+	}
+
+	// Create the default organization portals belong to
+	if err := database.SeedOrganizations(db); err != nil {
+		log.Fatalf("Failed to seed organizations: %v", err)
+	}
+
+	// Create the default maintenance reminder rules
+	if err := database.SeedReminderRules(db); err != nil {
+		log.Fatalf("Failed to seed reminder rules: %v", err)
+	}
+
+	var organization models.Organization
+	if err := db.Order("id").First(&organization).Error; err != nil {
+		log.Fatalf("Failed to find default organization: %v", err)
+	}
+
+	var equipmentType models.EquipmentType
+	if err := db.Where("code = ?", models.EquipmentTypeCodeGate).First(&equipmentType).Error; err != nil {
+		log.Fatalf("Failed to find default equipment type: %v", err)
+	}
+
 	// Create a sample portal
 	portal := models.Portal{
 		UUID:              uuid.New().String(),
@@ -32,6 +57,8 @@ func main() {
 		ContactPhone:      "+1-555-0123",
 		ContactEmail:      "contact@portalsolutions.com",
 		InstallationDate:  time.Now().AddDate(0, -1, 0), // Installed 1 month ago
+		EquipmentTypeID:   &equipmentType.ID,
+		OrganizationID:    &organization.ID,
 	}
 
 	// Insert the portal into database
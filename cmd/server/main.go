@@ -1,7 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
@@ -11,6 +18,11 @@ import (
 	"github.com/troptropcontent/qr_code_maintenance/internal/handlers"
 	authmiddleware "github.com/troptropcontent/qr_code_maintenance/internal/middleware"
 	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/notifications"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/scheduler"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
 	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
 )
 
@@ -25,8 +37,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to instanciate email service: %v", err)
 	}
+	storageService, err := storage.NewServiceFromEnv()
+	if err != nil {
+		log.Fatalf("failed to instanciate storage service: %v", err)
+	}
 	// Initialize handlers
-	h := &handlers.Handlers{DB: db, EmailNotificationService: emailService}
+	h := &handlers.Handlers{DB: db, EmailNotificationService: emailService, Storage: storageService}
 
 	e := echo.New()
 
@@ -52,25 +68,127 @@ func main() {
 	e.GET("/portals/:id", h.GetPortal, authmiddleware.RequireAuth())
 	e.GET("/qr_codes/:uuid", h.QRRedirect)
 
+	// Public report verification
+	e.GET(interventions.VerificationPath+":code", h.GetVerifyReport)
+	e.POST(interventions.VerificationPath+":code", h.PostVerifyReport)
+
+	// Public unsubscribe page linked from the notification emails
+	e.GET(notifications.UnsubscribePath+":token", h.GetUnsubscribe)
+	e.POST(notifications.UnsubscribePath+":token", h.PostUnsubscribe)
+
+	// Signed download URLs
+	e.GET(storage.FilesPath+"*", h.GetFile)
+
 	// Admin routes (require authentication)
 	admin_routes := e.Group("/admin", authmiddleware.RequireAuth())
 	admin_routes.GET("/portals", h.GetAdminPortals)
 	admin_routes.GET("/portals/:id", h.GetAdminPortal)
 	admin_routes.GET("/portals/:id/edit", h.GetAdminPortalEdit)
 	admin_routes.POST("/portals/:id", h.UpdatePortal)
+	admin_routes.POST("/portals/:id/status", h.UpdatePortalStatus, authmiddleware.RequireSupervisor(db))
 	admin_routes.POST("/portals/:id/qr-code/associate", h.AssociateQRCode)
 	admin_routes.POST("/portals/:id/qr-code/remove", h.RemoveQRCode)
+	admin_routes.GET("/portals/:id/chain", h.GetAdminPortalChain)
 	admin_routes.GET("/portals/:id/interventions/new", h.GetNewIntervention)
 	admin_routes.POST("/portals/:id/interventions", h.PostIntervention)
+	admin_routes.POST("/portals/:id/interventions/autosave", h.PostInterventionAutosave)
+	admin_routes.GET("/interventions", h.GetAdminInterventions)
+	admin_routes.GET("/interventions/:id/edit", h.GetEditIntervention)
 	admin_routes.GET("/interventions/:id/report", h.GetInterventionReport)
+	admin_routes.GET("/interventions/:id/report.pdf", h.GetInterventionReportPDF)
+	admin_routes.POST("/interventions/:id/report/regenerate", h.RegenerateInterventionReport, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/control_photos/:id", h.GetControlPhoto)
+	admin_routes.GET("/intervention_signatures/:id", h.GetInterventionSignature)
 	admin_routes.GET("/portals/scan", h.GetAdminPortalsScan)
+	admin_routes.GET("/reviews", h.GetAdminReviews, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/interventions/:id/validate", h.PostValidateIntervention, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/interventions/:id/reject", h.PostRejectIntervention, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/interventions/:id/history", h.GetInterventionHistory)
+	admin_routes.GET("/interventions/:id/amend", h.GetAmendIntervention, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/interventions/:id/amend", h.PostAmendIntervention, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/non_conformities/:id", h.UpdateNonConformity, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/jobs", h.GetAdminJobs, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/jobs/:id/retry", h.PostRetryJob, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/tasks", h.GetAdminTasks, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/tasks/:id/run", h.PostRunTask, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/reminders", h.GetAdminReminders, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/reminders/rules", h.PostReminderRule, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/reminders/rules/:id", h.UpdateReminderRule, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/emails/:id/resend", h.PostResendEmail, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/contracts", h.GetAdminContracts, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/contracts", h.PostContract, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/contracts/:id", h.GetAdminContract, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/contracts/:id", h.UpdateContract, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/contracts/:id/recipients", h.PostContractRecipient, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/portals/:id/recipients", h.PostPortalRecipient, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/notification_recipients/:id", h.UpdateNotificationRecipient, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/notification_recipients/:id/delete", h.DeleteNotificationRecipient, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/equipment_types", h.GetAdminEquipmentTypes, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/equipment_types", h.PostEquipmentType, authmiddleware.RequireSupervisor(db))
+	admin_routes.GET("/equipment_types/:id", h.GetAdminEquipmentType, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/equipment_types/:id", h.UpdateEquipmentType, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/equipment_types/:id/items", h.PostChecklistItem, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/equipment_types/:id/items/:item_id", h.UpdateChecklistItem, authmiddleware.RequireSupervisor(db))
+	admin_routes.POST("/equipment_types/:id/items/:item_id/delete", h.DeleteChecklistItem, authmiddleware.RequireSupervisor(db))
+
+	// Development tools
+	if utils.IsDevelopment() {
+		e.GET("/dev/emails", h.GetEmailPreviews)
+		e.GET("/dev/emails/logo", h.GetEmailPreviewLogo)
+		e.GET("/dev/emails/:name", h.GetEmailPreview)
+	}
 
 	// 404 handler
 	e.RouteNotFound("/*", h.NotFound)
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs
+	worker := jobs.NewWorker(db, jobs.WorkerOptions{})
+	h.RegisterJobs(worker)
+	workerDone := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(workerDone)
+	}()
+
+	// Recurring tasks
+	taskScheduler := scheduler.New(db, 0)
+	if err := h.RegisterTasks(taskScheduler); err != nil {
+		log.Fatalf("Failed to register scheduled tasks: %v", err)
+	}
+	schedulerDone := make(chan struct{})
+	go func() {
+		taskScheduler.Run(ctx)
+		close(schedulerDone)
+	}()
+
 	// Start server
-	log.Println("Server starting on :8080")
-	if err := e.Start(":8080"); err != nil {
-		log.Fatal(err)
+	go func() {
+		log.Println("Server starting on :8080")
+		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop accepting requests, jobs and tasks on shutdown, then let the
+	// running ones finish
+	<-ctx.Done()
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server: %v", err)
+	}
+	select {
+	case <-workerDone:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for running jobs")
+	}
+	select {
+	case <-schedulerDone:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for running tasks")
 	}
 }
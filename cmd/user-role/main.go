@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/database"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+func main() {
+	var (
+		email = flag.String("email", "", "Email of the user")
+		role  = flag.String("role", string(models.UserRoleSupervisor), "Role to give: technician or supervisor")
+		help  = flag.Bool("help", false, "Show help message")
+	)
+	flag.Parse()
+
+	if *help || *email == "" {
+		fmt.Println("User Role Manager")
+		fmt.Println()
+		fmt.Println("Gives a role to a user. Supervisors validate the interventions submitted by")
+		fmt.Println("technicians. The user must log in again for the navigation to reflect it.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Printf("  %s -email user@example.com [-role supervisor]\n", os.Args[0])
+		fmt.Println()
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		if *email == "" && !*help {
+			os.Exit(1)
+		}
+		return
+	}
+
+	userRole := models.UserRole(*role)
+	if !userRole.IsValid() {
+		log.Fatalf("Invalid role %q", *role)
+	}
+
+	// Connect to database with GORM
+	db, err := database.ConnectGORM()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get underlying sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	result := db.Model(&models.User{}).Where("email = ?", *email).Update("role", userRole)
+	if result.Error != nil {
+		log.Fatalf("Failed to update user: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Fatalf("No user found with email %s", *email)
+	}
+
+	fmt.Printf("✅ %s is now %s\n", *email, userRole.Label())
+}
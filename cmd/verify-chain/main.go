@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/database"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+)
+
+func main() {
+	var (
+		portalID = flag.Uint("portal", 0, "ID of the portal to verify, all portals when omitted")
+		help     = flag.Bool("help", false, "Show help message")
+	)
+	flag.Parse()
+
+	if *help {
+		fmt.Println("Intervention Hash Chain Verifier")
+		fmt.Println()
+		fmt.Println("Walks the hash chain of the interventions of each portal and reports any")
+		fmt.Println("break. Exits with status 1 when a chain is broken.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Printf("  %s [options]\n", os.Args[0])
+		fmt.Println()
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		return
+	}
+
+	// Connect to database with GORM
+	db, err := database.ConnectGORM()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get underlying sql.DB: %v", err)
+	}
+	defer sqlDB.Close()
+
+	var portals []models.Portal
+	query := db.Order("id")
+	if *portalID != 0 {
+		query = query.Where("id = ?", *portalID)
+	}
+	if err := query.Find(&portals).Error; err != nil {
+		log.Fatalf("Failed to fetch portals: %v", err)
+	}
+	if len(portals) == 0 {
+		log.Fatal("No portal found")
+	}
+
+	broken := 0
+	for _, portal := range portals {
+		report, err := interventions.VerifyPortalChain(db, portal.ID)
+		if err != nil {
+			log.Fatalf("Failed to verify portal %d: %v", portal.ID, err)
+		}
+
+		if report.Valid() {
+			fmt.Printf("✅ Portal %d (%s): %d sealed intervention(s), chain intact\n", portal.ID, portal.Name, report.Length)
+		} else {
+			broken++
+			fmt.Printf("❌ Portal %d (%s): %d sealed intervention(s), %d break(s)\n", portal.ID, portal.Name, report.Length, len(report.Breaks))
+			for _, chainBreak := range report.Breaks {
+				fmt.Printf("   - position %d, intervention #%d: %s\n", chainBreak.ChainIndex, chainBreak.InterventionID, chainBreak.Reason.Description())
+			}
+		}
+		if report.Unsealed > 0 {
+			fmt.Printf("   %d intervention(s) outside of the chain\n", report.Unsealed)
+		}
+	}
+
+	if broken > 0 {
+		fmt.Printf("\n%d broken chain(s) out of %d portal(s)\n", broken, len(portals))
+		os.Exit(1)
+	}
+}
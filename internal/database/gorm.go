@@ -72,17 +72,62 @@ func MustConnectGORM() *gorm.DB {
 func AutoMigrate(db *gorm.DB) error {
 	log.Println("Running auto migrations...")
 
+	if err := migrateInterventionStatuses(db); err != nil {
+		return err
+	}
+
+	if err := migrateUserRoles(db); err != nil {
+		return err
+	}
+
+	// Interventions recorded before checklists were versioned are linked to a
+	// checklist once the schema is migrated
+	unversionedInterventions := db.Migrator().HasTable(&models.Intervention{}) && !db.Migrator().HasColumn(&models.Intervention{}, "checklist_version_id")
+
 	err := db.AutoMigrate(
+		&models.Organization{},
+		&models.ReportSequence{},
+		&models.EquipmentType{},
+		&models.ChecklistVersion{},
+		&models.ChecklistItem{},
+		&models.Contract{},
 		&models.Portal{},
 		&models.QRCode{},
 		&models.User{},
 		&models.Intervention{},
 		&models.Control{},
+		&models.ControlPhoto{},
+		&models.InterventionReport{},
+		&models.InterventionSignature{},
+		&models.InterventionReview{},
+		&models.InterventionRevision{},
+		&models.PortalNotApplicableItem{},
+		&models.NonConformity{},
+		&models.CorrectiveAction{},
+		&models.PortalStatusChange{},
+		&models.Job{},
+		&models.ScheduledTask{},
+		&models.TaskRun{},
+		&models.ReminderRule{},
+		&models.MaintenanceReminder{},
+		&models.OutboxEmail{},
+		&models.EmailDeliveryAttempt{},
+		&models.NotificationRecipient{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := migrateControlResultsToOutcomes(db); err != nil {
+		return err
+	}
+
+	if unversionedInterventions {
+		if err := migrateInterventionChecklistVersions(db); err != nil {
+			return err
+		}
+	}
+
 	log.Println("Migrations completed successfully")
 	return nil
 }
@@ -97,5 +142,17 @@ func InitializeDatabase() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	if err := SeedEquipmentTypes(db); err != nil {
+		return nil, err
+	}
+
+	if err := SeedOrganizations(db); err != nil {
+		return nil, err
+	}
+
+	if err := SeedReminderRules(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
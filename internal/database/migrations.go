@@ -0,0 +1,114 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+// migrateInterventionChecklistVersions links interventions recorded before
+// checklists were versioned to the first version of their portal checklist. It
+// runs once, after AutoMigrate added the checklist_version_id column, and seeds
+// the default equipment types first so that every portal has a checklist.
+// Interventions that have a type were recorded since, those without a
+// checklist have none on purpose.
+func migrateInterventionChecklistVersions(db *gorm.DB) error {
+	log.Println("Linking interventions to checklist versions...")
+
+	if err := SeedEquipmentTypes(db); err != nil {
+		return err
+	}
+
+	result := db.Exec(`UPDATE interventions i SET checklist_version_id = cv.id
+		FROM portals p, checklist_versions cv
+		WHERE p.id = i.portal_id AND cv.equipment_type_id = p.equipment_type_id AND cv.version = 1
+		AND i.checklist_version_id IS NULL AND i.type IS NULL`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to link intervention checklist versions: %w", result.Error)
+	}
+	return nil
+}
+
+// migrateControlResultsToOutcomes converts the former nullable boolean result
+// of controls into an outcome: true becomes compliant, false non compliant and
+// NULL not checked
+func migrateControlResultsToOutcomes(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Control{}, "result") {
+		return nil
+	}
+
+	log.Println("Migrating control results to outcomes...")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`UPDATE controls SET outcome = CASE
+				WHEN result IS TRUE THEN 'compliant'
+				WHEN result IS FALSE THEN 'non_compliant'
+				ELSE 'not_checked'
+			END`,
+			`ALTER TABLE controls DROP COLUMN result`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to migrate control results: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateInterventionStatuses adds the workflow status of interventions.
+// Interventions recorded before the workflow existed were final as soon as
+// created, they are marked validated while new ones start as drafts. It runs
+// before AutoMigrate, which would give existing rows the draft default.
+func migrateInterventionStatuses(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Intervention{}) || migrator.HasColumn(&models.Intervention{}, "status") {
+		return nil
+	}
+
+	log.Println("Migrating interventions to the validation workflow...")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE interventions ADD COLUMN status varchar(20) NOT NULL DEFAULT 'validated'`,
+			`ALTER TABLE interventions ALTER COLUMN status SET DEFAULT 'draft'`,
+			`ALTER TABLE interventions ADD COLUMN IF NOT EXISTS validated_at timestamptz`,
+			`UPDATE interventions SET validated_at = created_at`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to migrate intervention statuses: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateUserRoles adds the role of users. Users created before roles existed
+// could do everything, they become supervisors while new users start as
+// technicians.
+func migrateUserRoles(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.User{}) || migrator.HasColumn(&models.User{}, "role") {
+		return nil
+	}
+
+	log.Println("Migrating users to roles...")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'supervisor'`,
+			`ALTER TABLE users ALTER COLUMN role SET DEFAULT 'technician'`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to migrate user roles: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+)
+
+// Integration tests that require a Postgres database configured by the DB_*
+// environment variables. They are skipped unless INTEGRATION_TEST is set.
+
+func TestRestartKeepsSealedChains(t *testing.T) {
+	if os.Getenv("INTEGRATION_TEST") == "" {
+		t.Skip("Skipping integration test. Set INTEGRATION_TEST=1 to run")
+	}
+
+	db, err := InitializeDatabase()
+	require.NoError(t, err)
+
+	user := models.User{Email: uuid.NewString() + "@example.com", Password: "x", FirstName: "Jean", LastName: "Dupont"}
+	require.NoError(t, db.Create(&user).Error)
+	portal := models.Portal{
+		UUID:              uuid.NewString(),
+		InternalId:        uuid.NewString(),
+		Name:              "Portail",
+		AddressStreet:     "1 rue de la Paix",
+		AddressZipcode:    "75000",
+		AddressCity:       "Paris",
+		ContractorCompany: "Entreprise",
+		ContactPhone:      "0100000000",
+		InstallationDate:  time.Now(),
+	}
+	require.NoError(t, db.Create(&portal).Error)
+	t.Cleanup(func() {
+		db.Unscoped().Where("portal_id = ?", portal.ID).Delete(&models.Intervention{})
+		db.Unscoped().Delete(&portal)
+		db.Unscoped().Delete(&user)
+	})
+
+	// An emergency intervention has no checklist on purpose
+	emergency := models.InterventionTypeEmergency
+	validatedAt := time.Now()
+	sealed := models.Intervention{
+		Date:        time.Now(),
+		Type:        &emergency,
+		Status:      models.InterventionStatusValidated,
+		ValidatedAt: &validatedAt,
+		UserID:      user.ID,
+		UserName:    user.FullName(),
+		PortalID:    portal.ID,
+	}
+	require.NoError(t, db.Omit(clause.Associations).Create(&sealed).Error)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return interventions.SealIntervention(tx, sealed.ID)
+	}))
+
+	// An intervention recorded before types and checklist versions existed
+	legacy := models.Intervention{
+		Date:        time.Now(),
+		Status:      models.InterventionStatusValidated,
+		ValidatedAt: &validatedAt,
+		UserID:      user.ID,
+		UserName:    user.FullName(),
+		PortalID:    portal.ID,
+	}
+	require.NoError(t, db.Omit(clause.Associations).Create(&legacy).Error)
+
+	// Restart, linking unversioned interventions as on the first start after
+	// the upgrade
+	require.NoError(t, AutoMigrate(db))
+	require.NoError(t, migrateInterventionChecklistVersions(db))
+	require.NoError(t, SeedEquipmentTypes(db))
+
+	require.NoError(t, db.First(&sealed, sealed.ID).Error)
+	assert.Nil(t, sealed.ChecklistVersionID)
+	require.NoError(t, db.First(&legacy, legacy.ID).Error)
+	assert.NotNil(t, legacy.ChecklistVersionID)
+
+	report, err := interventions.VerifyPortalChain(db, portal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Length)
+	assert.Empty(t, report.Breaks)
+}
@@ -0,0 +1,235 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
+)
+
+type defaultChecklistItem struct {
+	Code  string
+	Kind  models.ControlKind
+	Label string
+}
+
+type defaultEquipmentType struct {
+	Code  string
+	Name  string
+	Items []defaultChecklistItem
+}
+
+type defaultMeasurement struct {
+	Unit     string
+	MinValue *float64
+	MaxValue *float64
+}
+
+// defaultMeasurements turns the matching default items into measurements with
+// the EN 12453 limits
+var defaultMeasurements = map[string]defaultMeasurement{
+	"force_limiter": {Unit: "N", MaxValue: floatPtr(400)},
+	"pressure_bar":  {Unit: "N", MaxValue: floatPtr(150)},
+}
+
+// defaultEquipmentTypes are created on first start so that every portal has a
+// checklist; admins can edit them afterwards
+var defaultEquipmentTypes = []defaultEquipmentType{
+	{
+		Code: models.EquipmentTypeCodeGate,
+		Name: "Portail coulissant ou sectionnel",
+		Items: []defaultChecklistItem{
+			{"warning_lights", models.ControlKindSecurity, "Feux d'avertissement"},
+			{"area_lighting", models.ControlKindSecurity, "Éclairage de zone"},
+			{"safety_cells", models.ControlKindSecurity, "Cellules de sécurité"},
+			{"pressure_bar", models.ControlKindSecurity, "Barre de pression"},
+			{"floor_loop", models.ControlKindSecurity, "Boucle de sol"},
+			{"force_limiter", models.ControlKindSecurity, "Limiteur d'effort"},
+			{"safety_springs", models.ControlKindSecurity, "Ressorts de sécurité"},
+			{"floor_markings", models.ControlKindSecurity, "Marquage au sol"},
+			{"apron_condition", models.ControlKindOther, "État du tablier"},
+			{"horizontal_rails", models.ControlKindOther, "Rails horizontaux"},
+			{"vertical_rails", models.ControlKindOther, "Rails verticaux"},
+			{"roller_condition", models.ControlKindOther, "État des galets"},
+			{"drive_system", models.ControlKindOther, "Système d'entraînement"},
+			{"limit_switches", models.ControlKindOther, "Interrupteurs de fin de course"},
+			{"control_devices", models.ControlKindOther, "Organes de commande"},
+			{"control_panel", models.ControlKindOther, "Coffret de commande"},
+			{"manual_override", models.ControlKindOther, "Manœuvre de secours"},
+		},
+	},
+	{
+		Code: "barrier",
+		Name: "Barrière levante",
+		Items: []defaultChecklistItem{
+			{"warning_lights", models.ControlKindSecurity, "Feux d'avertissement"},
+			{"safety_cells", models.ControlKindSecurity, "Cellules de sécurité"},
+			{"floor_loop", models.ControlKindSecurity, "Boucle de sol"},
+			{"force_limiter", models.ControlKindSecurity, "Limiteur d'effort"},
+			{"floor_markings", models.ControlKindSecurity, "Marquage au sol"},
+			{"boom_condition", models.ControlKindOther, "État de la lisse"},
+			{"balancing_springs", models.ControlKindOther, "Ressorts d'équilibrage"},
+			{"drive_system", models.ControlKindOther, "Système d'entraînement"},
+			{"limit_switches", models.ControlKindOther, "Interrupteurs de fin de course"},
+			{"control_devices", models.ControlKindOther, "Organes de commande"},
+			{"control_panel", models.ControlKindOther, "Coffret de commande"},
+			{"manual_override", models.ControlKindOther, "Manœuvre de secours"},
+		},
+	},
+	{
+		Code: "bollard",
+		Name: "Borne escamotable",
+		Items: []defaultChecklistItem{
+			{"warning_lights", models.ControlKindSecurity, "Feux d'avertissement"},
+			{"safety_cells", models.ControlKindSecurity, "Cellules de sécurité"},
+			{"floor_loop", models.ControlKindSecurity, "Boucle de sol"},
+			{"floor_markings", models.ControlKindSecurity, "Marquage au sol"},
+			{"emergency_lowering", models.ControlKindSecurity, "Descente d'urgence"},
+			{"bollard_head", models.ControlKindOther, "État de la tête de borne"},
+			{"hydraulic_unit", models.ControlKindOther, "Groupe hydraulique"},
+			{"drainage", models.ControlKindOther, "Drainage du fourreau"},
+			{"control_devices", models.ControlKindOther, "Organes de commande"},
+			{"control_panel", models.ControlKindOther, "Coffret de commande"},
+		},
+	},
+	{
+		Code: "garage_door",
+		Name: "Porte de garage",
+		Items: []defaultChecklistItem{
+			{"warning_lights", models.ControlKindSecurity, "Feux d'avertissement"},
+			{"safety_cells", models.ControlKindSecurity, "Cellules de sécurité"},
+			{"pressure_bar", models.ControlKindSecurity, "Barre de pression"},
+			{"force_limiter", models.ControlKindSecurity, "Limiteur d'effort"},
+			{"safety_springs", models.ControlKindSecurity, "Ressorts de sécurité"},
+			{"fall_arrest", models.ControlKindSecurity, "Parachute anti-chute"},
+			{"apron_condition", models.ControlKindOther, "État du tablier"},
+			{"vertical_rails", models.ControlKindOther, "Rails verticaux"},
+			{"drive_system", models.ControlKindOther, "Système d'entraînement"},
+			{"limit_switches", models.ControlKindOther, "Interrupteurs de fin de course"},
+			{"control_devices", models.ControlKindOther, "Organes de commande"},
+			{"manual_override", models.ControlKindOther, "Manœuvre de secours"},
+		},
+	},
+	{
+		Code: "pedestrian_gate",
+		Name: "Portillon piéton",
+		Items: []defaultChecklistItem{
+			{"electric_lock", models.ControlKindSecurity, "Ventouse ou gâche électrique"},
+			{"emergency_release", models.ControlKindSecurity, "Déverrouillage d'urgence"},
+			{"door_closer", models.ControlKindOther, "Ferme-porte"},
+			{"hinges", models.ControlKindOther, "Gonds et paumelles"},
+			{"control_devices", models.ControlKindOther, "Organes de commande"},
+		},
+	},
+}
+
+// SeedEquipmentTypes creates the default equipment types that do not exist yet
+// and assigns the default gate type to portals without an equipment type
+func SeedEquipmentTypes(db *gorm.DB) error {
+	for _, defaultType := range defaultEquipmentTypes {
+		var count int64
+		if err := db.Model(&models.EquipmentType{}).Where("code = ?", defaultType.Code).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check equipment type %s: %w", defaultType.Code, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		version := models.ChecklistVersion{Version: 1}
+		for i, item := range defaultType.Items {
+			checklistItem := models.ChecklistItem{
+				Code:     item.Code,
+				Kind:     item.Kind,
+				Label:    item.Label,
+				Position: i,
+				Type:     models.ChecklistItemTypeCheck,
+			}
+			if measurement, ok := defaultMeasurements[item.Code]; ok {
+				checklistItem.Type = models.ChecklistItemTypeMeasure
+				checklistItem.Unit = measurement.Unit
+				checklistItem.MinValue = measurement.MinValue
+				checklistItem.MaxValue = measurement.MaxValue
+			}
+			version.Items = append(version.Items, checklistItem)
+		}
+
+		equipmentType := models.EquipmentType{
+			Code:              defaultType.Code,
+			Name:              defaultType.Name,
+			ChecklistVersions: []models.ChecklistVersion{version},
+		}
+		if err := db.Create(&equipmentType).Error; err != nil {
+			return fmt.Errorf("failed to create equipment type %s: %w", defaultType.Code, err)
+		}
+		log.Printf("Created equipment type %s", defaultType.Code)
+	}
+
+	var gate models.EquipmentType
+	if err := db.Where("code = ?", models.EquipmentTypeCodeGate).First(&gate).Error; err != nil {
+		return fmt.Errorf("failed to find default equipment type: %w", err)
+	}
+
+	result := db.Model(&models.Portal{}).Where("equipment_type_id IS NULL").Update("equipment_type_id", gate.ID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to assign default equipment type: %w", result.Error)
+	}
+
+	return nil
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+// SeedOrganizations creates the default organization on first start and
+// attaches the portals without organization to it
+func SeedOrganizations(db *gorm.DB) error {
+	var organization models.Organization
+	err := db.Order("id").First(&organization).Error
+	if err == gorm.ErrRecordNotFound {
+		organization = models.Organization{Name: utils.GetEnv("ORGANIZATION_NAME", "Organisation par défaut")}
+		if err := db.Create(&organization).Error; err != nil {
+			return fmt.Errorf("failed to create default organization: %w", err)
+		}
+		log.Printf("Created default organization %q", organization.Name)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch organizations: %w", err)
+	}
+
+	result := db.Model(&models.Portal{}).Where("organization_id IS NULL").Update("organization_id", organization.ID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to backfill portal organizations: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Attached %d portal(s) to organization %q", result.RowsAffected, organization.Name)
+	}
+	return nil
+}
+
+var defaultReminderRules = []models.ReminderRule{
+	{Name: "Un mois avant", OffsetDays: -30, Active: true},
+	{Name: "Jour de l'échéance", OffsetDays: 0, Active: true},
+	{Name: "Chaque semaine en retard", OffsetDays: 7, RepeatEveryDays: 7, Active: true},
+}
+
+// SeedReminderRules creates the default maintenance reminder rules on first
+// start. Rules edited or removed afterwards are left alone.
+func SeedReminderRules(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.ReminderRule{}).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count reminder rules: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	rules := append([]models.ReminderRule(nil), defaultReminderRules...)
+	if err := db.Create(&rules).Error; err != nil {
+		return fmt.Errorf("failed to create reminder rules: %w", err)
+	}
+	log.Printf("Created %d reminder rules", len(rules))
+	return nil
+}
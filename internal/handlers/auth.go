@@ -51,6 +51,7 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 
 	sess.Values["user_id"] = user.ID
 	sess.Values["user_email"] = user.Email
+	sess.Values["user_role"] = string(user.Role)
 
 	if err := sess.Save(c.Request(), c.Response()); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save session")
@@ -87,6 +88,7 @@ func (h *Handlers) PostRegister(c echo.Context) error {
 		Email:     email,
 		FirstName: firstName,
 		LastName:  lastName,
+		Role:      models.UserRoleTechnician,
 		IsActive:  true,
 	}
 
@@ -114,6 +116,7 @@ func (h *Handlers) PostRegister(c echo.Context) error {
 
 	sess.Values["user_id"] = user.ID
 	sess.Values["user_email"] = user.Email
+	sess.Values["user_role"] = string(user.Role)
 
 	if err := sess.Save(c.Request(), c.Response()); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save session")
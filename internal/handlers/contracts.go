@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/portals"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+func (h *Handlers) GetAdminContracts(c echo.Context) error {
+	var contracts []models.Contract
+	result := h.DB.Preload("Portals").Order("end_date, reference").Find(&contracts)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch contracts")
+	}
+
+	return templates.AdminContracts(contracts, time.Now(), c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) PostContract(c echo.Context) error {
+	var contract models.Contract
+	if err := contractFromForm(c, &contract); err != nil {
+		return err
+	}
+	if err := h.checkContractReference(&contract); err != nil {
+		return err
+	}
+
+	if err := h.DB.Create(&contract).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create contract")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/contracts/"+strconv.Itoa(int(contract.ID)))
+}
+
+func (h *Handlers) GetAdminContract(c echo.Context) error {
+	contract, err := h.findContract(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Where("contract_id = ?", contract.ID).Order("name").Find(&contract.Portals).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch portals")
+	}
+	portalIDs := make([]uint, 0, len(contract.Portals))
+	for _, portal := range contract.Portals {
+		portalIDs = append(portalIDs, portal.ID)
+	}
+	lastVisits, err := portals.LastPreventiveVisits(h.DB, portalIDs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch last visits")
+	}
+	if err := h.DB.Where("contract_id = ?", contract.ID).Order("id").Find(&contract.NotificationRecipients).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notification recipients")
+	}
+
+	return templates.AdminContract(*contract, lastVisits, time.Now(), c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) UpdateContract(c echo.Context) error {
+	contract, err := h.findContract(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	if err := contractFromForm(c, contract); err != nil {
+		return err
+	}
+	if err := h.checkContractReference(contract); err != nil {
+		return err
+	}
+
+	if err := h.DB.Save(contract).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update contract")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/contracts/"+strconv.Itoa(int(contract.ID)))
+}
+
+func (h *Handlers) findContract(id string) (*models.Contract, error) {
+	var contract models.Contract
+	if result := h.DB.First(&contract, id); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Contract not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return &contract, nil
+}
+
+// checkContractReference rejects a reference already used by another
+// contract
+func (h *Handlers) checkContractReference(contract *models.Contract) error {
+	var count int64
+	err := h.DB.Model(&models.Contract{}).Where("reference = ? AND id <> ?", contract.Reference, contract.ID).Count(&count).Error
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if count > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "A contract with this reference already exists")
+	}
+	return nil
+}
+
+// contractFromForm validates the contract form and copies its values to the
+// contract
+func contractFromForm(c echo.Context, contract *models.Contract) error {
+	reference := strings.TrimSpace(c.FormValue("reference"))
+	contractor := strings.TrimSpace(c.FormValue("contractor"))
+	if reference == "" || len(reference) > 50 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Reference is required and must not exceed 50 characters")
+	}
+	if contractor == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Contractor is required")
+	}
+	contractorEmail := strings.TrimSpace(c.FormValue("contractor_email"))
+	if contractorEmail != "" {
+		if _, err := mail.ParseAddress(contractorEmail); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid contractor email")
+		}
+	}
+
+	startDate, err := time.Parse("2006-01-02", c.FormValue("start_date"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid start date")
+	}
+	endDate, err := time.Parse("2006-01-02", c.FormValue("end_date"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid end date")
+	}
+	if !endDate.After(startDate) {
+		return echo.NewHTTPError(http.StatusBadRequest, "End date must be after start date")
+	}
+
+	visitsPerYear, err := strconv.Atoi(c.FormValue("visits_per_year"))
+	if err != nil || visitsPerYear < models.MinVisitsPerYear || visitsPerYear > 12 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Visits per year must be between 2 and 12")
+	}
+	callOutHours, err := strconv.Atoi(c.FormValue("call_out_hours"))
+	if err != nil || callOutHours <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid call-out delay")
+	}
+	priceCents, err := parsePriceCents(c.FormValue("annual_price"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid annual price")
+	}
+
+	contract.Reference = reference
+	contract.Contractor = contractor
+	contract.ContractorEmail = contractorEmail
+	contract.StartDate = startDate
+	contract.EndDate = endDate
+	contract.VisitsPerYear = visitsPerYear
+	contract.CallOutHours = callOutHours
+	contract.AnnualPriceCents = priceCents
+	return nil
+}
+
+// parsePriceCents parses an amount in euros, with a comma or a dot as
+// decimal separator, into cents
+func parsePriceCents(value string) (int64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	price, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil {
+		return 0, err
+	}
+	if price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		return 0, errors.New("price must be a positive amount")
+	}
+	return int64(math.Round(price * 100)), nil
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+// emailPreview is an email template rendered with fixture data
+type emailPreview struct {
+	templates.EmailPreview
+	component templ.Component
+	// notification is set for the emails sent to notification recipients,
+	// which show an unsubscribe link
+	notification bool
+}
+
+// GetEmailPreviews lists the email templates, in development only
+func (h *Handlers) GetEmailPreviews(c echo.Context) error {
+	var previews []templates.EmailPreview
+	for _, preview := range emailPreviews(time.Now()) {
+		previews = append(previews, preview.EmailPreview)
+	}
+	return templates.EmailPreviews(previews, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// GetEmailPreview renders an email template with fixture data, as HTML or
+// as plain text with ?format=text, in development only
+func (h *Handlers) GetEmailPreview(c echo.Context) error {
+	for _, preview := range emailPreviews(time.Now()) {
+		if preview.Name != c.Param("name") {
+			continue
+		}
+
+		options := templates.EmailOptions{}
+		if email.LogoPath() != "" {
+			options.LogoSrc = "/dev/emails/logo"
+		}
+		if preview.notification {
+			options.UnsubscribeURL = "#"
+		}
+		var html strings.Builder
+		if err := preview.component.Render(templates.WithEmailOptions(c.Request().Context(), options), &html); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to render email")
+		}
+
+		if c.QueryParam("format") == "text" {
+			return c.String(http.StatusOK, email.HTMLToText(html.String()))
+		}
+		return c.HTML(http.StatusOK, html.String())
+	}
+	return echo.NewHTTPError(http.StatusNotFound, "Email template not found")
+}
+
+// GetEmailPreviewLogo serves the logo sent inline with the emails, in
+// development only
+func (h *Handlers) GetEmailPreviewLogo(c echo.Context) error {
+	logo := email.LogoPath()
+	if logo == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "No email logo configured")
+	}
+	return c.File(logo)
+}
+
+// emailPreviews returns every email template with fixture data
+func emailPreviews(now time.Time) []emailPreview {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	contractReference := "CT-2025-01"
+	summary := "Remplacement des cellules de sécurité, graissage des gonds."
+	reportNumber := "RI-2025-0042"
+
+	portal := models.Portal{
+		ID:             1,
+		Name:           "Portail Nord",
+		AddressStreet:  "12 rue du Port",
+		AddressZipcode: "69001",
+		AddressCity:    "Lyon",
+		ContactEmail:   "gardien@example.com",
+		ContactPhone:   "06 12 34 56 78",
+	}
+	intervention := models.Intervention{
+		ID:           42,
+		PortalID:     portal.ID,
+		Portal:       portal,
+		Date:         today,
+		UserName:     "Jean Dupont",
+		Summary:      &summary,
+		ReportNumber: &reportNumber,
+		Revision:     2,
+		Revisions:    []models.InterventionRevision{{Number: 2, Reason: "Correction du relevé des cellules"}},
+	}
+	opened := []models.NonConformity{
+		{Portal: portal, Label: "Cellules de sécurité", Severity: models.NonConformitySeverityMajor, DueDate: today.AddDate(0, 0, 15)},
+		{Portal: portal, Label: "Graissage", Severity: models.NonConformitySeverityMinor, DueDate: today.AddDate(0, 3, 0)},
+	}
+	change := models.PortalStatusChange{
+		PortalID:   portal.ID,
+		FromStatus: models.PortalStatusInService,
+		ToStatus:   models.PortalStatusOutOfService,
+		Reason:     "Cellules de sécurité hors d'usage",
+		CreatedAt:  now,
+	}
+
+	lastVisit := today.AddDate(0, -7, 0)
+	dueDate := today.AddDate(0, 0, -14)
+	daysOverdue := 14
+	overdue := models.PortalMaintenance{
+		PortalID:          portal.ID,
+		Name:              portal.Name,
+		AddressStreet:     portal.AddressStreet,
+		AddressZipcode:    portal.AddressZipcode,
+		AddressCity:       portal.AddressCity,
+		ContractReference: &contractReference,
+		LastVisit:         &lastVisit,
+		DueDate:           &dueDate,
+		DaysOverdue:       &daysOverdue,
+	}
+	dueSoon := today.AddDate(0, 0, 10)
+	dueIn := -10
+	dueThisMonth := models.PortalMaintenance{
+		PortalID:    2,
+		Name:        "Portail Sud",
+		AddressCity: "Vienne",
+		DueDate:     &dueSoon,
+		DaysOverdue: &dueIn,
+	}
+
+	return []emailPreview{
+		{
+			EmailPreview: templates.EmailPreview{Name: "intervention_report", Title: "Rapport d'intervention"},
+			component:    templates.InterventionReportEmail(&intervention),
+			notification: true,
+		},
+		{
+			EmailPreview: templates.EmailPreview{Name: "non_conformities", Title: "Non-conformités ouvertes"},
+			component:    templates.NonConformitiesEmail(&portal, &intervention, opened),
+			notification: true,
+		},
+		{
+			EmailPreview: templates.EmailPreview{Name: "portal_status", Title: "Changement de statut du portail"},
+			component:    templates.PortalStatusEmail(&portal, &change),
+			notification: true,
+		},
+		{
+			EmailPreview: templates.EmailPreview{Name: "maintenance_reminder", Title: "Rappel de visite de maintenance"},
+			component:    templates.MaintenanceReminderEmail(&portal, &overdue),
+		},
+		{
+			EmailPreview: templates.EmailPreview{Name: "weekly_digest", Title: "Point hebdomadaire"},
+			component:    templates.WeeklyDigestEmail([]models.PortalMaintenance{overdue}, []models.PortalMaintenance{dueThisMonth}, opened),
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"gorm.io/gorm"
+)
+
+// PostResendEmail queues a failed email again and goes back to the page
+// showing it
+func (h *Handlers) PostResendEmail(c echo.Context) error {
+	var outgoing models.OutboxEmail
+	if err := h.DB.First(&outgoing, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Email not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	if err := outbox.Resend(h.DB, outgoing.ID); err != nil {
+		if errors.Is(err, outbox.ErrNotResendable) {
+			return echo.NewHTTPError(http.StatusConflict, "Only failed emails can be sent again")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to resend email")
+	}
+
+	return c.Redirect(http.StatusSeeOther, emailReturnPath(&outgoing))
+}
+
+// emailReturnPath returns the admin page listing an outgoing email
+func emailReturnPath(outgoing *models.OutboxEmail) string {
+	switch {
+	case outgoing.Kind == models.OutboxEmailKindMaintenanceReminder && outgoing.PortalID != nil:
+		return "/admin/reminders?portal_id=" + strconv.Itoa(int(*outgoing.PortalID))
+	case outgoing.Kind == models.OutboxEmailKindMaintenanceReminder:
+		return "/admin/reminders"
+	case outgoing.PortalID != nil:
+		return "/admin/portals/" + strconv.Itoa(int(*outgoing.PortalID))
+	}
+	return "/admin/portals"
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/checklists"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+var checklistCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+func (h *Handlers) GetAdminEquipmentTypes(c echo.Context) error {
+	var equipmentTypes []models.EquipmentType
+	result := h.DB.Preload("ChecklistVersions.Items").Order("name").Find(&equipmentTypes)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch equipment types")
+	}
+
+	return templates.AdminEquipmentTypes(equipmentTypes, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) PostEquipmentType(c echo.Context) error {
+	var formData struct {
+		Code string `form:"code"`
+		Name string `form:"name"`
+	}
+	if err := c.Bind(&formData); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form data")
+	}
+
+	code := strings.TrimSpace(formData.Code)
+	name := strings.TrimSpace(formData.Name)
+	if !checklistCodePattern.MatchString(code) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Code must only contain lowercase letters, digits and underscores")
+	}
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.EquipmentType{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if count > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "An equipment type with this code already exists")
+	}
+
+	equipmentType := models.EquipmentType{Code: code, Name: name, VisitsPerYear: models.MinVisitsPerYear}
+	if err := checklists.NewService(h.DB).CreateEquipmentType(&equipmentType); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create equipment type")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/equipment_types/"+strconv.Itoa(int(equipmentType.ID)))
+}
+
+func (h *Handlers) GetAdminEquipmentType(c echo.Context) error {
+	equipmentType, err := h.findEquipmentType(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	// Count interventions recorded against each version of the checklist
+	var usages []struct {
+		ChecklistVersionID uint
+		Count              int
+	}
+	result := h.DB.Model(&models.Intervention{}).
+		Select("checklist_version_id, COUNT(*) AS count").
+		Joins("JOIN checklist_versions ON checklist_versions.id = interventions.checklist_version_id").
+		Where("checklist_versions.equipment_type_id = ?", equipmentType.ID).
+		Group("checklist_version_id").
+		Scan(&usages)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	usageByVersion := make(map[uint]int, len(usages))
+	for _, usage := range usages {
+		usageByVersion[usage.ChecklistVersionID] = usage.Count
+	}
+
+	return templates.AdminEquipmentType(*equipmentType, usageByVersion, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) UpdateEquipmentType(c echo.Context) error {
+	equipmentType, err := h.findEquipmentType(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	visitsPerYear, err := strconv.Atoi(c.FormValue("visits_per_year"))
+	if err != nil || visitsPerYear < models.MinVisitsPerYear || visitsPerYear > 12 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Visits per year must be between 2 and 12")
+	}
+
+	err = h.DB.Model(equipmentType).Updates(map[string]any{
+		"name":            name,
+		"visits_per_year": visitsPerYear,
+	}).Error
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update equipment type")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/equipment_types/"+strconv.Itoa(int(equipmentType.ID)))
+}
+
+func (h *Handlers) PostChecklistItem(c echo.Context) error {
+	equipmentType, err := h.findEquipmentType(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	var item models.ChecklistItem
+	if err := bindChecklistItem(c, &item); err != nil {
+		return err
+	}
+
+	code := strings.TrimSpace(c.FormValue("code"))
+	if !checklistCodePattern.MatchString(code) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Code must only contain lowercase letters, digits and underscores")
+	}
+	item.Code = code
+
+	_, err = checklists.NewService(h.DB).Edit(equipmentType.ID, func(tx *gorm.DB, version *models.ChecklistVersion) error {
+		for _, existing := range version.Items {
+			if existing.Code == code {
+				return echo.NewHTTPError(http.StatusBadRequest, "This checklist already has an item with this code")
+			}
+		}
+		item.ChecklistVersionID = version.ID
+		return tx.Create(&item).Error
+	})
+	if err != nil {
+		return checklistEditError(err, "Failed to create checklist item")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/equipment_types/"+strconv.Itoa(int(equipmentType.ID)))
+}
+
+func (h *Handlers) UpdateChecklistItem(c echo.Context) error {
+	equipmentType, item, err := h.findCurrentChecklistItem(c.Param("id"), c.Param("item_id"))
+	if err != nil {
+		return err
+	}
+
+	var updated models.ChecklistItem
+	if err := bindChecklistItem(c, &updated); err != nil {
+		return err
+	}
+
+	_, err = checklists.NewService(h.DB).Edit(equipmentType.ID, func(tx *gorm.DB, version *models.ChecklistVersion) error {
+		target, err := findVersionItem(version, item.Code)
+		if err != nil {
+			return err
+		}
+		target.Label = updated.Label
+		target.Kind = updated.Kind
+		target.Position = updated.Position
+		target.Type = updated.Type
+		target.Unit = updated.Unit
+		target.MinValue = updated.MinValue
+		target.MaxValue = updated.MaxValue
+		return tx.Save(target).Error
+	})
+	if err != nil {
+		return checklistEditError(err, "Failed to update checklist item")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/equipment_types/"+strconv.Itoa(int(equipmentType.ID)))
+}
+
+func (h *Handlers) DeleteChecklistItem(c echo.Context) error {
+	equipmentType, item, err := h.findCurrentChecklistItem(c.Param("id"), c.Param("item_id"))
+	if err != nil {
+		return err
+	}
+
+	_, err = checklists.NewService(h.DB).Edit(equipmentType.ID, func(tx *gorm.DB, version *models.ChecklistVersion) error {
+		target, err := findVersionItem(version, item.Code)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(target).Error
+	})
+	if err != nil {
+		return checklistEditError(err, "Failed to delete checklist item")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/equipment_types/"+strconv.Itoa(int(equipmentType.ID)))
+}
+
+func (h *Handlers) findEquipmentType(id string) (*models.EquipmentType, error) {
+	var equipmentType models.EquipmentType
+	result := h.DB.Preload("ChecklistVersions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("version DESC")
+	}).Preload("ChecklistVersions.Items").First(&equipmentType, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Equipment type not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return &equipmentType, nil
+}
+
+// findCurrentChecklistItem finds an item of the current checklist version of
+// an equipment type
+func (h *Handlers) findCurrentChecklistItem(equipmentTypeID, itemID string) (*models.EquipmentType, *models.ChecklistItem, error) {
+	equipmentType, err := h.findEquipmentType(equipmentTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := equipmentType.CurrentChecklist()
+	if current == nil {
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Checklist item not found")
+	}
+
+	for i := range current.Items {
+		if strconv.Itoa(int(current.Items[i].ID)) == itemID {
+			return equipmentType, &current.Items[i], nil
+		}
+	}
+	return nil, nil, echo.NewHTTPError(http.StatusNotFound, "Checklist item not found")
+}
+
+// findVersionItem finds an item by code, as item IDs change when an edit
+// creates a new checklist version
+func findVersionItem(version *models.ChecklistVersion, code string) (*models.ChecklistItem, error) {
+	for i := range version.Items {
+		if version.Items[i].Code == code {
+			return &version.Items[i], nil
+		}
+	}
+	return nil, echo.NewHTTPError(http.StatusNotFound, "Checklist item not found")
+}
+
+func checklistEditError(err error, message string) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	if errors.Is(err, checklists.ErrNoChecklist) {
+		return echo.NewHTTPError(http.StatusNotFound, "Equipment type has no checklist")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, message)
+}
+
+// bindChecklistItem reads the editable fields of a checklist item from the form
+func bindChecklistItem(c echo.Context, item *models.ChecklistItem) error {
+	label := strings.TrimSpace(c.FormValue("label"))
+	if label == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Label is required")
+	}
+
+	kind := models.ControlKind(c.FormValue("kind"))
+	if !kind.IsValid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid control kind")
+	}
+
+	position, err := strconv.Atoi(c.FormValue("position"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid position")
+	}
+
+	item.Label = label
+	item.Kind = kind
+	item.Position = position
+	item.Type = models.ChecklistItemTypeCheck
+	item.Unit = ""
+	item.MinValue = nil
+	item.MaxValue = nil
+
+	if models.ChecklistItemType(c.FormValue("type")) != models.ChecklistItemTypeMeasure {
+		return nil
+	}
+
+	minValue, err := parseOptionalFloat(c.FormValue("min_value"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid minimum value")
+	}
+	maxValue, err := parseOptionalFloat(c.FormValue("max_value"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid maximum value")
+	}
+	if minValue != nil && maxValue != nil && *minValue > *maxValue {
+		return echo.NewHTTPError(http.StatusBadRequest, "Minimum value must be lower than maximum value")
+	}
+
+	item.Type = models.ChecklistItemTypeMeasure
+	item.Unit = strings.TrimSpace(c.FormValue("unit"))
+	item.MinValue = minValue
+	item.MaxValue = maxValue
+	return nil
+}
+
+// errNotFinite is returned for NaN and infinite numbers, which compare false
+// to any limit and cannot be encoded in the chain hash
+var errNotFinite = errors.New("number is not finite")
+
+// parseOptionalFloat parses a finite decimal number typed with either a dot or
+// a comma, returning nil for an empty value
+func parseOptionalFloat(value string) (*float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", ".")
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return nil, errNotFinite
+	}
+	return &parsed, nil
+}
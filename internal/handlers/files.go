@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+// GetFile streams a stored file to the holder of a signed download URL
+func (h *Handlers) GetFile(c echo.Context) error {
+	key := c.Param("*")
+
+	if err := h.Storage.VerifySignedURL(key, c.QueryParam("expires"), c.QueryParam("signature")); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "Invalid or expired link")
+	}
+
+	reader, err := h.Storage.Open(c.Request().Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "File not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open file")
+	}
+	defer reader.Close()
+
+	contentType := storage.ContentTypeOf(key)
+	c.Response().Header().Set("Cache-Control", "private, max-age=300")
+	if contentType == "image/svg+xml" {
+		// SVG files are documents, never let them run scripts when opened
+		c.Response().Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	}
+	return c.Stream(http.StatusOK, contentType, reader)
+}
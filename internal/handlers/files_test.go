@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+func TestHandlers_GetFile(t *testing.T) {
+	backend := storage.NewLocalBackend(t.TempDir())
+	require.NoError(t, backend.Put(context.Background(), "reports/ab/cd/abcd.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf"))
+	h := &Handlers{Storage: storage.NewService(backend, "secret")}
+
+	e := echo.New()
+	e.GET(storage.FilesPath+"*", h.GetFile)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get(h.Storage.SignedURL("reports/ab/cd/abcd.pdf", time.Minute))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/pdf", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "%PDF-1.4", rec.Body.String())
+
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+
+	require.NoError(t, backend.Put(context.Background(), "signatures/ab/cd/abcd.svg", strings.NewReader("<svg/>"), 6, "image/svg+xml"))
+	rec = get(h.Storage.SignedURL("signatures/ab/cd/abcd.svg", time.Minute))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "sandbox")
+
+	rec = get(storage.FilesPath + "reports/ab/cd/abcd.pdf")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = get(h.Storage.SignedURL("reports/ab/cd/missing.pdf", time.Minute))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,24 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/troptropcontent/qr_code_maintenance/internal/middleware"
 	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/checklists"
 	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
 	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/portals"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
 	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Handlers struct {
 	DB                       *gorm.DB
 	EmailNotificationService email.EmailService
+	Storage                  *storage.Service
 }
 
 func (h *Handlers) GetPortal(c echo.Context) error {
@@ -26,8 +32,8 @@ func (h *Handlers) GetPortal(c echo.Context) error {
 
 	var portal models.Portal
 	result := h.DB.Preload("Interventions", func(db *gorm.DB) *gorm.DB {
-		return db.Order("date DESC").Limit(5)
-	}).Preload("Interventions.Controls").Where("id = ?", id).First(&portal)
+		return db.Where("status = ?", models.InterventionStatusValidated).Order("date DESC").Limit(5)
+	}).Preload("Interventions.Controls.Photos").Preload("Interventions.ChecklistVersion.Items").Preload("Interventions.Reports").Preload("NonConformities", "status = ?", models.NonConformityStatusOpen).Preload("Contract").Where("id = ?", id).First(&portal)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
@@ -35,7 +41,20 @@ func (h *Handlers) GetPortal(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
 
-	return templates.PortalShow(portal, c).Render(c.Request().Context(), c.Response().Writer)
+	// Reports are shared through signed links valid for the time of the visit
+	reportURLs := make(map[uint]string)
+	for _, intervention := range portal.Interventions {
+		if report := intervention.LatestReport(); report != nil {
+			reportURLs[intervention.ID] = h.Storage.SignedURL(report.Key, time.Hour)
+		}
+	}
+
+	nextVisit, err := h.nextVisitDue(&portal)
+	if err != nil {
+		return err
+	}
+
+	return templates.PortalShow(portal, reportURLs, nextVisit, c).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *Handlers) NotFound(c echo.Context) error {
@@ -46,21 +65,27 @@ func (h *Handlers) GetAdminPortalsScan(c echo.Context) error {
 	return templates.AdminPortalsScan(c).Render(c.Request().Context(), c.Response().Writer)
 }
 
+// GetAdminPortals shows the maintenance dashboard: portals grouped by how
+// soon their next preventive visit is due
 func (h *Handlers) GetAdminPortals(c echo.Context) error {
-	var portals []models.Portal
-	result := h.DB.Order("name").Find(&portals)
-	if result.Error != nil {
+	now := time.Now()
+	schedules, err := portals.MaintenanceSchedules(h.DB, now)
+	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch portals")
 	}
 
-	return templates.AdminPortals(portals, c).Render(c.Request().Context(), c.Response().Writer)
+	return templates.AdminPortals(models.NewMaintenanceDashboard(schedules, now), len(schedules), c).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *Handlers) GetAdminPortal(c echo.Context) error {
 	id := c.Param("id")
 
 	var portal models.Portal
-	result := h.DB.First(&portal, id)
+	result := h.DB.Preload("EquipmentType").Preload("Contract").Preload("Technician").Preload("NonConformities", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Preload("NonConformities.Assignee").Preload("NonConformities.OpenedIntervention").Preload("NonConformities.ClosedIntervention").Preload("StatusChanges", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).First(&portal, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
@@ -79,19 +104,49 @@ func (h *Handlers) GetAdminPortal(c echo.Context) error {
 	// Fetch associated QR code if exists
 	var interventions []models.Intervention
 
-	result = h.DB.Preload("Controls").Order("date desc").Find(&interventions, "portal_id = ?", portal.ID)
+	result = h.DB.Preload("Controls.Photos").Preload("ChecklistVersion.Items").Preload("Signatures").Preload("Reviews").Preload("Reports", func(db *gorm.DB) *gorm.DB {
+		return db.Order("revision")
+	}).Preload("Emails", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC")
+	}).Order("date desc").Find(&interventions, "portal_id = ?", portal.ID)
 	if result.Error != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "Failed to fetch interventions")
 	}
 
-	return templates.AdminPortal(portal, qrCodePtr, interventions, c).Render(c.Request().Context(), c.Response().Writer)
+	// Users non-conformities can be assigned to
+	var users []models.User
+	if result := h.DB.Where("is_active = ?", true).Order("first_name, last_name").Find(&users); result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch users")
+	}
+
+	nextVisit, err := h.nextVisitDue(&portal)
+	if err != nil {
+		return err
+	}
+
+	return templates.AdminPortal(portal, qrCodePtr, interventions, users, nextVisit, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// nextVisitDue returns the next preventive visit due under the contract of
+// the portal, which must be loaded
+func (h *Handlers) nextVisitDue(portal *models.Portal) (*time.Time, error) {
+	if portal.Contract == nil {
+		return nil, nil
+	}
+	lastVisit, err := portals.LastPreventiveVisit(h.DB, portal.ID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch last visit")
+	}
+	return portal.NextVisitDue(lastVisit), nil
 }
 
 func (h *Handlers) GetAdminPortalEdit(c echo.Context) error {
 	id := c.Param("id")
 
 	var portal models.Portal
-	result := h.DB.First(&portal, id)
+	result := h.DB.Preload("NotificationRecipients", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).First(&portal, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
@@ -99,7 +154,22 @@ func (h *Handlers) GetAdminPortalEdit(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
 
-	return templates.AdminPortalEdit(portal, c).Render(c.Request().Context(), c.Response().Writer)
+	var equipmentTypes []models.EquipmentType
+	if err := h.DB.Order("name").Find(&equipmentTypes).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch equipment types")
+	}
+
+	var contracts []models.Contract
+	if err := h.DB.Order("reference").Find(&contracts).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch contracts")
+	}
+
+	var technicians []models.User
+	if err := h.DB.Where("is_active = ?", true).Order("first_name, last_name").Find(&technicians).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch users")
+	}
+
+	return templates.AdminPortalEdit(portal, equipmentTypes, contracts, technicians, c).Render(c.Request().Context(), c.Response().Writer)
 }
 
 func (h *Handlers) AssociateQRCode(c echo.Context) error {
@@ -243,6 +313,10 @@ func (h *Handlers) UpdatePortal(c echo.Context) error {
 		ContactPhone      string `json:"contact_phone" form:"contact_phone"`
 		ContactEmail      string `json:"contact_email" form:"contact_email"`
 		InstallationDate  string `json:"installation_date" form:"installation_date"`
+		EquipmentTypeID   uint   `json:"equipment_type_id" form:"equipment_type_id"`
+		ContractID        uint   `json:"contract_id" form:"contract_id"`
+		TechnicianID      uint   `json:"technician_id" form:"technician_id"`
+		RemindersOptOut   bool   `json:"reminders_opt_out" form:"reminders_opt_out"`
 	}
 
 	if err := c.Bind(&updateData); err != nil {
@@ -279,6 +353,34 @@ func (h *Handlers) UpdatePortal(c echo.Context) error {
 	}
 	portal.ContactEmail = updateData.ContactEmail
 
+	if updateData.EquipmentTypeID != 0 {
+		var equipmentType models.EquipmentType
+		if err := h.DB.First(&equipmentType, updateData.EquipmentTypeID).Error; err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid equipment type")
+		}
+		portal.EquipmentTypeID = &equipmentType.ID
+	}
+
+	// The contract select offers no contract as an empty value
+	portal.ContractID = nil
+	if updateData.ContractID != 0 {
+		var contract models.Contract
+		if err := h.DB.First(&contract, updateData.ContractID).Error; err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid contract")
+		}
+		portal.ContractID = &contract.ID
+	}
+
+	portal.TechnicianID = nil
+	if updateData.TechnicianID != 0 {
+		var technician models.User
+		if err := h.DB.Where("is_active = ?", true).First(&technician, updateData.TechnicianID).Error; err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid technician")
+		}
+		portal.TechnicianID = &technician.ID
+	}
+	portal.RemindersOptOut = updateData.RemindersOptOut
+
 	result = h.DB.Save(&portal)
 	if result.Error != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update portal")
@@ -319,7 +421,7 @@ func (h *Handlers) GetNewIntervention(c echo.Context) error {
 	id := c.Param("id")
 
 	var portal models.Portal
-	result := h.DB.Where("id = ?", id).First(&portal)
+	result := h.DB.Preload("EquipmentType").Preload("NotApplicableItems").Preload("NonConformities", "status = ?", models.NonConformityStatusOpen).Preload("Contract").Where("id = ?", id).First(&portal)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
@@ -327,148 +429,625 @@ func (h *Handlers) GetNewIntervention(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
 
-	return templates.AdminInterventionNew(portal, *user, c).Render(c.Request().Context(), c.Response().Writer)
+	var checklist *models.ChecklistVersion
+	if portal.EquipmentTypeID != nil {
+		checklist, err = checklists.NewService(h.DB).CurrentVersion(*portal.EquipmentTypeID)
+		if err != nil && !errors.Is(err, checklists.ErrNoChecklist) {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch checklist")
+		}
+	}
+
+	interventionType := models.InterventionType(c.QueryParam("type"))
+	if !interventionType.IsValid() {
+		interventionType = models.InterventionTypePreventive
+	}
+
+	config := templates.InterventionFormConfig{Portal: portal, Checklist: checklist, Type: interventionType, User: *user}
+	return templates.AdminInterventionNew(config, c).Render(c.Request().Context(), c.Response().Writer)
 }
 
-func (h *Handlers) PostIntervention(c echo.Context) error {
+// GetEditIntervention renders the intervention form filled in with a draft or
+// rejected intervention, against the checklist version it was started with
+func (h *Handlers) GetEditIntervention(c echo.Context) error {
 	user, err := middleware.GetCurrentUser(c, h.DB)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
 	}
 
+	var intervention models.Intervention
+	result := h.DB.Preload("Controls.Photos").Preload("Signatures").Preload("Reviews").Preload("CorrectiveActions").First(&intervention, c.Param("id"))
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := checkInterventionEditable(&intervention, user); err != nil {
+		return err
+	}
+
+	var portal models.Portal
+	result = h.DB.Preload("EquipmentType").Preload("NotApplicableItems").Preload("NonConformities", "status = ?", models.NonConformityStatusOpen).First(&portal, intervention.PortalID)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	var versionID uint
+	if intervention.ChecklistVersionID != nil {
+		versionID = *intervention.ChecklistVersionID
+	}
+	checklist, err := h.interventionChecklist(&portal, versionID)
+	if err != nil {
+		return err
+	}
+
+	config := templates.InterventionFormConfig{
+		Portal:       portal,
+		Checklist:    checklist,
+		Type:         intervention.InterventionType(),
+		User:         *user,
+		Intervention: &intervention,
+	}
+	return templates.AdminInterventionNew(config, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// interventionSaveMode tells what saving the intervention form does
+type interventionSaveMode int
+
+const (
+	// interventionAutosave keeps what was typed so far. Photos and signatures
+	// are only stored by an explicit save.
+	interventionAutosave interventionSaveMode = iota
+	// interventionSaveDraft stores the whole form and keeps the intervention
+	// editable
+	interventionSaveDraft
+	// interventionSubmit stores the whole form and submits the intervention
+	// for validation
+	interventionSubmit
+)
+
+func (h *Handlers) PostIntervention(c echo.Context) error {
+	mode := interventionSubmit
+	if c.FormValue("action") == "draft" {
+		mode = interventionSaveDraft
+	}
+
+	intervention, err := h.saveInterventionForm(c, mode)
+	if err != nil {
+		return err
+	}
+
+	if mode == interventionSaveDraft {
+		return c.Redirect(http.StatusSeeOther, "/admin/interventions/"+strconv.Itoa(int(intervention.ID))+"/edit")
+	}
+
+	// Redirect to portal admin page
+	return c.Redirect(http.StatusSeeOther, "/admin/portals/"+strconv.Itoa(int(intervention.PortalID)))
+}
+
+// PostInterventionAutosave saves the intervention form as a draft while the
+// technician fills it in, and answers with the draft status shown on the form
+func (h *Handlers) PostInterventionAutosave(c echo.Context) error {
+	intervention, err := h.saveInterventionForm(c, interventionAutosave)
+	if err != nil {
+		return err
+	}
+
+	return templates.AdminInterventionDraftStatus(intervention).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// saveInterventionForm creates or updates the intervention filled in on the
+// intervention form of a portal. The intervention given by the intervention_id
+// field is updated, otherwise a draft is created.
+func (h *Handlers) saveInterventionForm(c echo.Context, mode interventionSaveMode) (*models.Intervention, error) {
+	user, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
 	id := c.Param("id")
 	portalID, err := strconv.ParseUint(id, 10, 32)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid portal ID")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid portal ID")
 	}
 
 	var portal models.Portal
-	result := h.DB.Where("id = ?", id).First(&portal)
+	result := h.DB.Preload("EquipmentType").Where("id = ?", id).First(&portal)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Portal not found")
 		}
-		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
 
 	// Parse form data
 	var formData struct {
-		Date    string `form:"date"`
-		Summary string `form:"summary"`
+		InterventionID     uint   `form:"intervention_id"`
+		Type               string `form:"type"`
+		Date               string `form:"date"`
+		Summary            string `form:"summary"`
+		Fault              string `form:"fault"`
+		WorkDone           string `form:"work_done"`
+		ArrivedAt          string `form:"arrived_at"`
+		DepartedAt         string `form:"departed_at"`
+		PortalStatusAfter  string `form:"portal_status_after"`
+		ChecklistVersionID uint   `form:"checklist_version_id"`
 	}
 
 	if err := c.Bind(&formData); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form data")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form data")
+	}
+
+	interventionType := models.InterventionType(formData.Type)
+	if formData.Type == "" {
+		interventionType = models.InterventionTypePreventive
+	}
+	if !interventionType.IsValid() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid intervention type")
 	}
+	sections := interventionType.Sections()
 
 	// Parse intervention date
 	interventionDate, err := time.Parse("2006-01-02", formData.Date)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid date format")
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid date format")
 	}
 
-	// Create intervention
-	intervention := models.Intervention{
-		Date:     interventionDate,
-		UserID:   user.ID,
-		UserName: user.FullName(),
-		PortalID: uint(portalID),
+	arrivedAt, err := parseFormDateTime(formData.ArrivedAt)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid arrival time")
+	}
+	departedAt, err := parseFormDateTime(formData.DepartedAt)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid departure time")
+	}
+	if arrivedAt != nil && departedAt != nil && departedAt.Before(*arrivedAt) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Departure must be after arrival")
 	}
 
-	// Set summary if provided
-	if formData.Summary != "" {
-		intervention.Summary = &formData.Summary
+	var portalStatusAfter *models.PortalStatus
+	if formData.PortalStatusAfter != "" {
+		status := models.PortalStatus(formData.PortalStatusAfter)
+		if !status.IsValid() {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid portal status")
+		}
+		portalStatusAfter = &status
+	}
+
+	var checklist *models.ChecklistVersion
+	if sections.Checklist {
+		checklist, err = h.interventionChecklist(&portal, formData.ChecklistVersionID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Start transaction
 	tx := h.DB.Begin()
 	if tx.Error != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to start transaction")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to start transaction")
 	}
 	defer tx.Rollback()
 
+	intervention := &models.Intervention{
+		Status:   models.InterventionStatusDraft,
+		UserID:   user.ID,
+		UserName: user.FullName(),
+		PortalID: uint(portalID),
+	}
+	if formData.InterventionID != 0 {
+		intervention, err = lockEditableIntervention(tx, formData.InterventionID, user)
+		if err != nil {
+			return nil, err
+		}
+		if intervention.PortalID != uint(portalID) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Intervention does not belong to the portal")
+		}
+		// Only drafts are autosaved, a rejected intervention is changed by an
+		// explicit save
+		if mode == interventionAutosave && intervention.Status != models.InterventionStatusDraft {
+			return nil, echo.NewHTTPError(http.StatusConflict, "Intervention is no longer a draft")
+		}
+	}
+
+	intervention.Type = &interventionType
+	intervention.Date = interventionDate
+	intervention.ArrivedAt = arrivedAt
+	intervention.DepartedAt = departedAt
+	intervention.PortalStatusAfter = portalStatusAfter
+	intervention.ChecklistVersionID = nil
+	if checklist != nil {
+		intervention.ChecklistVersionID = &checklist.ID
+	}
+
+	// Set text fields if provided, only for the sections of the type
+	intervention.Summary = nil
+	if formData.Summary != "" {
+		intervention.Summary = &formData.Summary
+	}
+	intervention.Fault = nil
+	if sections.Fault && formData.Fault != "" {
+		intervention.Fault = &formData.Fault
+	}
+	intervention.WorkDone = nil
+	if sections.Work && formData.WorkDone != "" {
+		intervention.WorkDone = &formData.WorkDone
+	}
+
 	// Save intervention
-	if result := tx.Create(&intervention); result.Error != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create intervention")
-	}
-
-	// Process control results
-	allControlTypes := append(models.ControlTypesByKind[models.ControlKindSecurity], models.ControlTypesByKind[models.ControlKindOther]...)
-
-	for _, controlType := range allControlTypes {
-		controlValue := c.FormValue("control_" + controlType)
-
-		// Only create control records for non-empty values (user made a selection)
-		if controlValue != "" {
-			var result models.ControlResult
-			switch controlValue {
-			case "true":
-				trueVal := true
-				result = &trueVal
-			case "false":
-				falseVal := false
-				result = &falseVal
-			}
-			// If controlValue is empty string, result stays nil (not controlled)
+	if result := tx.Omit(clause.Associations).Save(intervention); result.Error != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save intervention")
+	}
+
+	// Process control results against the checklist version the form was rendered with
+	var items models.ChecklistItems
+	if checklist != nil {
+		items = checklist.Items
+	}
+
+	// Controls saved earlier are updated in place to keep their photos
+	previousControls := make(map[string]models.Control, len(intervention.Controls))
+	for _, control := range intervention.Controls {
+		previousControls[control.Kind] = control
+	}
 
-			control := models.Control{
-				Kind:           controlType,
-				Result:         result,
-				InterventionID: intervention.ID,
+	var controls []models.Control
+	for _, item := range items {
+		control, err := controlFromForm(c, item)
+		if err != nil {
+			return nil, err
+		}
+
+		control.InterventionID = intervention.ID
+		if previous, ok := previousControls[item.Code]; ok {
+			control.ID = previous.ID
+			control.CreatedAt = previous.CreatedAt
+			delete(previousControls, item.Code)
+		}
+		if res := tx.Omit(clause.Associations).Save(control); res.Error != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save control")
+		}
+
+		if mode != interventionAutosave {
+			// Photos are stored under content addressed keys before the transaction
+			// commits, an aborted intervention only leaves unreferenced objects behind
+			photos, err := h.saveControlPhotos(c, control)
+			if err != nil {
+				return nil, err
+			}
+			if len(photos) > 0 {
+				if res := tx.Create(&photos); res.Error != nil {
+					return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save photos")
+				}
 			}
+		}
+		controls = append(controls, *control)
+	}
 
-			if res := tx.Create(&control); res.Error != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create control")
+	// Controls of items that are no longer on the form, after the type changed
+	for _, control := range previousControls {
+		if res := tx.Delete(&control); res.Error != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove control")
+		}
+	}
+
+	if err := saveCorrectiveActions(tx, c, intervention); err != nil {
+		return nil, err
+	}
+
+	if mode != interventionAutosave {
+		signatures, err := h.saveSignatures(c, intervention)
+		if err != nil {
+			return nil, err
+		}
+		if len(signatures) > 0 {
+			if res := tx.Create(&signatures); res.Error != nil {
+				return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save signatures")
 			}
 		}
 	}
 
+	// Submitted interventions wait for a supervisor, who gives them their
+	// report number and sends them to the customer
+	if mode == interventionSubmit {
+		if err := rememberNotApplicableItems(tx, portal.ID, controls); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save not applicable items")
+		}
+		if err := interventions.SubmitIntervention(tx, intervention, time.Now()); err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to submit intervention")
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save intervention")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save intervention")
 	}
 
-	// Send email notification (don't fail the request if this fails)
-	go func() {
-		var reloadedIntervention models.Intervention
-		h.DB.Preload("User").Preload("Portal").Preload("Controls").First(&reloadedIntervention, intervention.ID)
-		if err := h.sendInterventionNotification(&reloadedIntervention, &portal, user); err != nil {
-			log.Printf("Failed to send intervention notification: %v", err)
+	return intervention, nil
+}
+
+// lockEditableIntervention locks an intervention the user may still change,
+// with its controls, signatures and corrective actions loaded
+func lockEditableIntervention(tx *gorm.DB, interventionID uint, user *models.User) (*models.Intervention, error) {
+	intervention, err := interventions.LockIntervention(tx, interventionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
 		}
-	}()
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := checkInterventionEditable(intervention, user); err != nil {
+		return nil, err
+	}
 
-	// Redirect to portal admin page
-	return c.Redirect(http.StatusSeeOther, "/admin/portals/"+id)
+	if err := tx.Where("intervention_id = ?", intervention.ID).Find(&intervention.Controls).Error; err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := tx.Where("intervention_id = ?", intervention.ID).Find(&intervention.Signatures).Error; err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := tx.Where("intervention_id = ?", intervention.ID).Find(&intervention.CorrectiveActions).Error; err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return intervention, nil
+}
+
+// checkInterventionViewable lets every user see a validated intervention. One
+// that is not validated yet is only seen by its technician and supervisors.
+func checkInterventionViewable(intervention *models.Intervention, user *models.User) error {
+	if intervention.Status != models.InterventionStatusValidated && intervention.UserID != user.ID && !user.IsSupervisor() {
+		return echo.NewHTTPError(http.StatusForbidden, "Intervention belongs to another technician")
+	}
+	return nil
+}
+
+// checkInterventionEditable only lets the technician of a draft or rejected
+// intervention, or a supervisor, change it
+func checkInterventionEditable(intervention *models.Intervention, user *models.User) error {
+	if intervention.UserID != user.ID && !user.IsSupervisor() {
+		return echo.NewHTTPError(http.StatusForbidden, "Intervention belongs to another technician")
+	}
+	if !intervention.Status.IsEditable() {
+		return echo.NewHTTPError(http.StatusConflict, "Intervention can no longer be edited")
+	}
+	return nil
 }
 
 func (h *Handlers) GetInterventionReport(c echo.Context) error {
+	user, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
 	id := c.Param("id")
 
 	var intervention models.Intervention
-	result := h.DB.Preload("Portal").Preload("Controls").First(&intervention, id)
+	result := interventions.PreloadReport(h.DB).First(&intervention, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
 	}
+	if err := checkInterventionViewable(&intervention, user); err != nil {
+		return err
+	}
 
 	return templates.InterventionReport(templates.InterventionReportConfig{Intervention: &intervention}).Render(c.Request().Context(), c.Response().Writer)
 }
 
-// sendInterventionNotification sends an email notification with PDF report
-func (h *Handlers) sendInterventionNotification(intervention *models.Intervention, portal *models.Portal, user *models.User) error {
-	// Initialize PDF service
-	gotenbergURL := "http://gotemberg:3000" // From docker-compose.yml
-	pdfService := interventions.NewPDFService(gotenbergURL)
+func (h *Handlers) GetControlPhoto(c echo.Context) error {
+	id := c.Param("id")
+
+	var photo models.ControlPhoto
+	result := h.DB.Preload("Control").First(&photo, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, "Photo not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := h.checkFileViewable(c, photo.Control.InterventionID); err != nil {
+		return err
+	}
 
-	// Initialize notification service
-	notificationService := interventions.NewNotificationService(pdfService, h.EmailNotificationService)
+	key := photo.Key
+	if c.QueryParam("size") == "thumbnail" {
+		key = photo.ThumbnailOrWebKey()
+	}
+	return c.Redirect(http.StatusFound, h.Storage.SignedURL(key, 15*time.Minute))
+}
 
-	// Set the related entities on the intervention for the notification
-	intervention.Portal = *portal
-	intervention.User = *user
+// GetInterventionSignature redirects to a short lived download URL of a
+// signature image
+func (h *Handlers) GetInterventionSignature(c echo.Context) error {
+	var signature models.InterventionSignature
+	result := h.DB.First(&signature, "id = ?", c.Param("id"))
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return echo.NewHTTPError(http.StatusNotFound, "Signature not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := h.checkFileViewable(c, signature.InterventionID); err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusFound, h.Storage.SignedURL(signature.Key, 15*time.Minute))
+}
 
-	// Send notification
-	return notificationService.SendInterventionReport(intervention)
+// checkFileViewable lets the current user download a photo or signature of an
+// intervention only when they can see the intervention
+func (h *Handlers) checkFileViewable(c echo.Context, interventionID uint) error {
+	user, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
+	var intervention models.Intervention
+	if err := h.DB.Select("id", "user_id", "status").First(&intervention, interventionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return checkInterventionViewable(&intervention, user)
+}
+
+// interventionChecklist returns the checklist version an intervention on the
+// portal is recorded against: the version the form was rendered with when it
+// belongs to the portal equipment type, otherwise the current version
+func (h *Handlers) interventionChecklist(portal *models.Portal, versionID uint) (*models.ChecklistVersion, error) {
+	if portal.EquipmentTypeID == nil {
+		return nil, nil
+	}
+
+	service := checklists.NewService(h.DB)
+	if versionID != 0 {
+		version, err := service.Version(versionID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid checklist version")
+			}
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch checklist")
+		}
+		if version.EquipmentTypeID != *portal.EquipmentTypeID {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Checklist version does not match the portal equipment type")
+		}
+		return version, nil
+	}
+
+	version, err := service.CurrentVersion(*portal.EquipmentTypeID)
+	if err != nil {
+		if errors.Is(err, checklists.ErrNoChecklist) {
+			return nil, nil
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch checklist")
+	}
+	return version, nil
+}
+
+// controlFromForm builds the control submitted for a checklist item. The
+// outcome of measure items is derived from the measured value and the item
+// thresholds unless the item was marked not applicable.
+func controlFromForm(c echo.Context, item models.ChecklistItem) (*models.Control, error) {
+	outcome := models.ControlOutcome(c.FormValue("control_" + item.Code))
+	if !outcome.IsValid() {
+		outcome = models.ControlOutcomeNotChecked
+	}
+
+	control := &models.Control{Kind: item.Code, Outcome: outcome}
+	if remark := strings.TrimSpace(c.FormValue("remark_" + item.Code)); remark != "" {
+		control.Remark = &remark
+	}
+
+	if !item.IsMeasure() || outcome == models.ControlOutcomeNotApplicable {
+		return control, nil
+	}
+
+	value, err := parseOptionalFloat(c.FormValue("measure_" + item.Code))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid measured value for "+item.Label)
+	}
+	control.MeasuredValue = value
+	control.Outcome = models.ControlOutcomeNotChecked
+	if value != nil {
+		control.Outcome = models.ControlOutcomeNonCompliant
+		if item.IsWithinLimits(*value) {
+			control.Outcome = models.ControlOutcomeCompliant
+		}
+	}
+	return control, nil
+}
+
+// saveControlPhotos stores the photos uploaded for a control and returns the
+// records to persist
+func (h *Handlers) saveControlPhotos(c echo.Context, control *models.Control) ([]models.ControlPhoto, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		// Forms without file inputs are not sent as multipart
+		return nil, nil
+	}
+
+	var photos []models.ControlPhoto
+	for _, fileHeader := range form.File["photos_"+control.Kind] {
+		photo, err := interventions.SaveControlPhoto(c.Request().Context(), h.Storage, control.ID, fileHeader)
+		if err != nil {
+			if errors.Is(err, interventions.ErrInvalidPhoto) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid photo: "+fileHeader.Filename)
+			}
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save photo")
+		}
+		photos = append(photos, *photo)
+	}
+	return photos, nil
+}
+
+// parseFormDateTime parses the value of a datetime-local input, in the time
+// zone of the server. Empty values are nil.
+func parseFormDateTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// saveSignatures stores the signatures captured on the signature pads of the
+// intervention form and returns the records to persist. Pads left blank, and
+// parties who already signed, are ignored.
+func (h *Handlers) saveSignatures(c echo.Context, intervention *models.Intervention) ([]models.InterventionSignature, error) {
+	signedAt := time.Now()
+
+	var signatures []models.InterventionSignature
+	for _, party := range models.SignatureParties {
+		input := interventions.SignatureInput{
+			Party:      party,
+			DataURL:    c.FormValue("signature_" + string(party)),
+			SignerName: c.FormValue("signature_" + string(party) + "_name"),
+			SignerRole: c.FormValue("signature_" + string(party) + "_role"),
+		}
+		if input.DataURL == "" || intervention.Signature(party) != nil {
+			continue
+		}
+
+		signature, err := interventions.SaveSignature(c.Request().Context(), h.Storage, intervention.ID, input, signedAt)
+		if err != nil {
+			if errors.Is(err, interventions.ErrInvalidSignature) {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid signature: "+party.Label())
+			}
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Failed to save signature")
+		}
+		signatures = append(signatures, *signature)
+	}
+	return signatures, nil
+}
+
+// rememberNotApplicableItems records the items marked not applicable on the
+// portal so later visits default to it, and forgets items that were checked
+func rememberNotApplicableItems(tx *gorm.DB, portalID uint, controls []models.Control) error {
+	for _, control := range controls {
+		switch control.Outcome {
+		case models.ControlOutcomeNotApplicable:
+			item := models.PortalNotApplicableItem{PortalID: portalID, Code: control.Kind}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&item).Error; err != nil {
+				return err
+			}
+		case models.ControlOutcomeCompliant, models.ControlOutcomeNonCompliant:
+			if err := tx.Where("portal_id = ? AND code = ?", portalID, control.Kind).Delete(&models.PortalNotApplicableItem{}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reportService returns the service generating and storing intervention reports
+func (h *Handlers) reportService() *interventions.ReportService {
+	gotenbergURL := "http://gotemberg:3000" // From docker-compose.yml
+	pdfService := interventions.NewPDFService(gotenbergURL, h.Storage)
+	return interventions.NewReportService(h.DB, pdfService, h.Storage)
 }
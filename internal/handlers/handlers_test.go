@@ -3,12 +3,15 @@ package handlers
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
 )
 
 func TestHandlers_NotFound(t *testing.T) {
@@ -100,3 +103,262 @@ func TestHandlers_GetAdminPortalsScan(t *testing.T) {
 	assert.Contains(t, body, "Instructions:")
 	assert.Contains(t, body, "Assurez-vous que votre caméra est activée")
 }
+
+// newContext returns the context of a request posting the form
+func newContext(form url.Values) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestControlFromForm(t *testing.T) {
+	maxValue := 400.0
+	measure := models.ChecklistItem{Code: "force_limiter", Type: models.ChecklistItemTypeMeasure, Unit: "N", MaxValue: &maxValue}
+	check := models.ChecklistItem{Code: "safety_cells", Type: models.ChecklistItemTypeCheck}
+
+	control, err := controlFromForm(newContext(url.Values{"measure_force_limiter": {"412,5"}}), measure)
+	require.NoError(t, err)
+	require.NotNil(t, control)
+	assert.Equal(t, 412.5, *control.MeasuredValue)
+	assert.Equal(t, models.ControlOutcomeNonCompliant, control.Outcome)
+
+	control, err = controlFromForm(newContext(url.Values{"measure_force_limiter": {"250"}}), measure)
+	require.NoError(t, err)
+	assert.Equal(t, models.ControlOutcomeCompliant, control.Outcome)
+
+	control, err = controlFromForm(newContext(url.Values{}), measure)
+	require.NoError(t, err)
+	assert.Equal(t, models.ControlOutcomeNotChecked, control.Outcome)
+
+	control, err = controlFromForm(newContext(url.Values{"control_force_limiter": {"not_applicable"}, "measure_force_limiter": {"250"}}), measure)
+	require.NoError(t, err)
+	assert.Equal(t, models.ControlOutcomeNotApplicable, control.Outcome)
+	assert.Nil(t, control.MeasuredValue)
+
+	for _, value := range []string{"abc", "NaN", "Inf", "-Inf"} {
+		_, err = controlFromForm(newContext(url.Values{"measure_force_limiter": {value}}), measure)
+		assert.Error(t, err, value)
+	}
+
+	control, err = controlFromForm(newContext(url.Values{"control_safety_cells": {"non_compliant"}, "remark_safety_cells": {"  Cellule encrassée "}}), check)
+	require.NoError(t, err)
+	assert.Equal(t, models.ControlOutcomeNonCompliant, control.Outcome)
+	assert.Equal(t, "Cellule encrassée", *control.Remark)
+	assert.Nil(t, control.MeasuredValue)
+
+	control, err = controlFromForm(newContext(url.Values{"control_safety_cells": {"bogus"}}), check)
+	require.NoError(t, err)
+	assert.Equal(t, models.ControlOutcomeNotChecked, control.Outcome)
+}
+
+func TestBindChecklistItem(t *testing.T) {
+	form := func(minValue, maxValue string) url.Values {
+		return url.Values{"label": {"Limiteur d'effort"}, "kind": {"security"}, "position": {"1"}, "type": {"measure"}, "unit": {"N"}, "min_value": {minValue}, "max_value": {maxValue}}
+	}
+
+	var item models.ChecklistItem
+	require.NoError(t, bindChecklistItem(newContext(form("", "400")), &item))
+	assert.Equal(t, models.ChecklistItemTypeMeasure, item.Type)
+	assert.Nil(t, item.MinValue)
+	assert.Equal(t, 400.0, *item.MaxValue)
+
+	for _, values := range [][2]string{{"NaN", ""}, {"", "NaN"}, {"-Inf", "400"}, {"0", "Inf"}, {"500", "400"}} {
+		assert.Error(t, bindChecklistItem(newContext(form(values[0], values[1])), &models.ChecklistItem{}), values)
+	}
+}
+
+func TestCheckInterventionEditable(t *testing.T) {
+	technician := &models.User{ID: 1, Role: models.UserRoleTechnician}
+	colleague := &models.User{ID: 2, Role: models.UserRoleTechnician}
+	supervisor := &models.User{ID: 3, Role: models.UserRoleSupervisor}
+
+	statusCode := func(err error) int {
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		return httpErr.Code
+	}
+
+	draft := &models.Intervention{UserID: 1, Status: models.InterventionStatusDraft}
+	assert.NoError(t, checkInterventionEditable(draft, technician))
+	assert.NoError(t, checkInterventionEditable(draft, supervisor))
+	assert.Equal(t, http.StatusForbidden, statusCode(checkInterventionEditable(draft, colleague)))
+
+	rejected := &models.Intervention{UserID: 1, Status: models.InterventionStatusRejected}
+	assert.NoError(t, checkInterventionEditable(rejected, technician))
+
+	submitted := &models.Intervention{UserID: 1, Status: models.InterventionStatusSubmitted}
+	assert.Equal(t, http.StatusConflict, statusCode(checkInterventionEditable(submitted, technician)))
+}
+
+func TestCheckInterventionViewable(t *testing.T) {
+	technician := &models.User{ID: 1, Role: models.UserRoleTechnician}
+	colleague := &models.User{ID: 2, Role: models.UserRoleTechnician}
+	supervisor := &models.User{ID: 3, Role: models.UserRoleSupervisor}
+
+	submitted := &models.Intervention{UserID: 1, Status: models.InterventionStatusSubmitted}
+	assert.NoError(t, checkInterventionViewable(submitted, technician))
+	assert.NoError(t, checkInterventionViewable(submitted, supervisor))
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, checkInterventionViewable(submitted, colleague), &httpErr)
+	assert.Equal(t, http.StatusForbidden, httpErr.Code)
+
+	validated := &models.Intervention{UserID: 1, Status: models.InterventionStatusValidated}
+	assert.NoError(t, checkInterventionViewable(validated, colleague))
+}
+
+func TestContractFromForm(t *testing.T) {
+	form := func(overrides map[string]string) url.Values {
+		values := url.Values{
+			"reference":        {" CT-2025-001 "},
+			"contractor":       {"Portails Services"},
+			"contractor_email": {" contact@portails-services.fr "},
+			"start_date":       {"2025-01-01"},
+			"end_date":         {"2025-12-31"},
+			"visits_per_year":  {"2"},
+			"call_out_hours":   {"4"},
+			"annual_price":     {"1 250,50"},
+		}
+		for name, value := range overrides {
+			values.Set(name, value)
+		}
+		return values
+	}
+
+	var contract models.Contract
+	require.NoError(t, contractFromForm(newContext(form(nil)), &contract))
+	assert.Equal(t, "CT-2025-001", contract.Reference)
+	assert.Equal(t, "Portails Services", contract.Contractor)
+	assert.Equal(t, "contact@portails-services.fr", contract.ContractorEmail)
+	assert.Equal(t, 2, contract.VisitsPerYear)
+	assert.Equal(t, 4, contract.CallOutHours)
+	assert.Equal(t, int64(125050), contract.AnnualPriceCents)
+
+	for name, value := range map[string]string{
+		"reference":        "",
+		"contractor":       " ",
+		"contractor_email": "not an email",
+		"end_date":         "2024-12-31",
+		"visits_per_year":  "1",
+		"call_out_hours":   "0",
+		"annual_price":     "-10",
+	} {
+		err := contractFromForm(newContext(form(map[string]string{name: value})), &models.Contract{})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParsePriceCents(t *testing.T) {
+	cents, err := parsePriceCents("1200")
+	require.NoError(t, err)
+	assert.Equal(t, int64(120000), cents)
+
+	cents, err = parsePriceCents("99.99")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9999), cents)
+
+	_, err = parsePriceCents("abc")
+	assert.Error(t, err)
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "IR-2024", escapeLike("IR-2024"))
+	assert.Equal(t, `100\%`, escapeLike("100%"))
+	assert.Equal(t, `IR\_2024`, escapeLike("IR_2024"))
+	assert.Equal(t, `a\\b`, escapeLike(`a\b`))
+}
+
+func TestReminderRuleFromForm(t *testing.T) {
+	var rule models.ReminderRule
+	form := url.Values{"name": {" Un mois avant "}, "days": {"30"}, "when": {"before"}, "active": {"true"}}
+	require.NoError(t, reminderRuleFromForm(newContext(form), &rule))
+	assert.Equal(t, "Un mois avant", rule.Name)
+	assert.Equal(t, -30, rule.OffsetDays)
+	assert.Equal(t, 0, rule.RepeatEveryDays)
+	assert.True(t, rule.Active)
+
+	form = url.Values{"name": {"Hebdomadaire"}, "days": {"7"}, "when": {"after"}, "repeat_every_days": {"7"}}
+	require.NoError(t, reminderRuleFromForm(newContext(form), &rule))
+	assert.Equal(t, 7, rule.OffsetDays)
+	assert.Equal(t, 7, rule.RepeatEveryDays)
+	assert.False(t, rule.Active)
+
+	for _, form := range []url.Values{
+		{"name": {" "}, "days": {"30"}, "when": {"before"}},
+		{"name": {"Règle"}, "days": {"-1"}, "when": {"before"}},
+		{"name": {"Règle"}, "days": {"30"}, "when": {"sometime"}},
+		{"name": {"Règle"}, "days": {"0"}, "when": {"after"}, "repeat_every_days": {"-7"}},
+	} {
+		assert.Error(t, reminderRuleFromForm(newContext(form), &models.ReminderRule{}), form.Encode())
+	}
+}
+
+func TestEmailReturnPath(t *testing.T) {
+	portalID := uint(12)
+
+	assert.Equal(t, "/admin/portals/12", emailReturnPath(&models.OutboxEmail{Kind: models.OutboxEmailKindInterventionReport, PortalID: &portalID}))
+	assert.Equal(t, "/admin/reminders?portal_id=12", emailReturnPath(&models.OutboxEmail{Kind: models.OutboxEmailKindMaintenanceReminder, PortalID: &portalID}))
+	assert.Equal(t, "/admin/portals", emailReturnPath(&models.OutboxEmail{Kind: models.OutboxEmailKindWeeklyDigest}))
+}
+
+func TestNotificationRecipientFromForm(t *testing.T) {
+	var recipient models.NotificationRecipient
+	form := url.Values{
+		"name":   {" Syndic "},
+		"email":  {"Syndic Nord <syndic@example.com>"},
+		"field":  {"cc"},
+		"events": {"intervention_validated", "portal_out_of_service"},
+	}
+	require.NoError(t, notificationRecipientFromForm(newContext(form), &recipient))
+	assert.Equal(t, "Syndic", recipient.Name)
+	assert.Equal(t, "syndic@example.com", recipient.Email)
+	assert.Equal(t, models.RecipientFieldCc, recipient.Field)
+	assert.Equal(t, []models.NotificationEvent{models.NotificationEventInterventionValidated, models.NotificationEventPortalOutOfService}, recipient.Events)
+
+	require.NoError(t, notificationRecipientFromForm(newContext(url.Values{"email": {"syndic@example.com"}, "field": {"to"}}), &recipient))
+	assert.Empty(t, recipient.Events)
+
+	for _, form := range []url.Values{
+		{"email": {"not an address"}, "field": {"to"}},
+		{"email": {"syndic@example.com"}, "field": {"reply-to"}},
+		{"email": {"syndic@example.com"}, "field": {"to"}, "events": {"ticket_created"}},
+	} {
+		assert.Error(t, notificationRecipientFromForm(newContext(form), &models.NotificationRecipient{}), form.Encode())
+	}
+
+	portalID, contractID := uint(3), uint(5)
+	assert.Equal(t, "/admin/portals/3/edit", notificationRecipientReturnPath(&models.NotificationRecipient{PortalID: &portalID}))
+	assert.Equal(t, "/admin/contracts/5", notificationRecipientReturnPath(&models.NotificationRecipient{ContractID: &contractID}))
+}
+
+func TestGetEmailPreview(t *testing.T) {
+	h := &Handlers{}
+	e := echo.New()
+
+	for _, preview := range emailPreviews(time.Now()) {
+		req := httptest.NewRequest(http.MethodGet, "/dev/emails/"+preview.Name, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("name")
+		c.SetParamValues(preview.Name)
+
+		require.NoError(t, h.GetEmailPreview(c), preview.Name)
+		assert.Contains(t, rec.Body.String(), "Cordialement", preview.Name)
+		assert.Equal(t, preview.notification, strings.Contains(rec.Body.String(), "Se désabonner"), preview.Name)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dev/emails/portal_status?format=text", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues("portal_status")
+	require.NoError(t, h.GetEmailPreview(c))
+	assert.Contains(t, rec.Body.String(), "Nouveau statut : Hors service")
+	assert.NotContains(t, rec.Body.String(), "<")
+
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/dev/emails/unknown", nil), httptest.NewRecorder())
+	c.SetParamNames("name")
+	c.SetParamValues("unknown")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, h.GetEmailPreview(c), &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.Code)
+}
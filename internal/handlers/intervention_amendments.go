@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/middleware"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetAmendIntervention renders the amendment form of a validated intervention,
+// filled in with its current content
+func (h *Handlers) GetAmendIntervention(c echo.Context) error {
+	var intervention models.Intervention
+	result := h.DB.Preload("Portal").Preload("Controls.Photos").Preload("ChecklistVersion.Items").Preload("Revisions").First(&intervention, c.Param("id"))
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if intervention.Status != models.InterventionStatusValidated {
+		return echo.NewHTTPError(http.StatusConflict, "Only validated interventions can be amended")
+	}
+
+	return templates.AdminInterventionAmend(&intervention, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// PostAmendIntervention records the amendment of a validated intervention as
+// its next revision, and queues the amended report to be emailed
+func (h *Handlers) PostAmendIntervention(c echo.Context) error {
+	author, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
+	interventionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid intervention ID")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		intervention, err := interventions.LockIntervention(tx, uint(interventionID))
+		if err != nil {
+			return err
+		}
+		if err := tx.Preload("Controls").Preload("ChecklistVersion.Items").First(intervention, intervention.ID).Error; err != nil {
+			return err
+		}
+
+		after, err := amendmentFromForm(c, intervention)
+		if err != nil {
+			return err
+		}
+		if _, err := interventions.AmendIntervention(tx, intervention, author, c.FormValue("reason"), after, time.Now()); err != nil {
+			return err
+		}
+		return interventions.QueueInterventionReport(c.Request().Context(), tx, intervention.ID)
+	})
+	var httpError *echo.HTTPError
+	switch {
+	case err == nil:
+	case errors.As(err, &httpError):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+	case errors.Is(err, interventions.ErrNotSupervisor):
+		return echo.NewHTTPError(http.StatusForbidden, "Supervisor role required")
+	case errors.Is(err, interventions.ErrNotAmendable):
+		return echo.NewHTTPError(http.StatusConflict, "Only validated interventions can be amended")
+	case errors.Is(err, interventions.ErrAmendmentReasonRequired):
+		return echo.NewHTTPError(http.StatusBadRequest, "A reason is required to amend an intervention")
+	case errors.Is(err, interventions.ErrNoChanges):
+		return echo.NewHTTPError(http.StatusBadRequest, "The amendment does not change the intervention")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to amend intervention")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/interventions/"+strconv.Itoa(int(interventionID))+"/history")
+}
+
+// GetInterventionHistory lists the revisions of an intervention with their
+// changes, and every report sent, the original one included
+func (h *Handlers) GetInterventionHistory(c echo.Context) error {
+	var intervention models.Intervention
+	result := h.DB.Preload("Portal").Preload("ChecklistVersion.Items").Preload("Revisions").Preload("Reports", func(db *gorm.DB) *gorm.DB {
+		return db.Order("revision")
+	}).First(&intervention, c.Param("id"))
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	return templates.AdminInterventionHistory(&intervention, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// amendmentFromForm returns the content of the intervention as amended on the
+// form. Only the controls recorded at the time of the intervention can be
+// changed, against the checklist version they were filled in with.
+func amendmentFromForm(c echo.Context, intervention *models.Intervention) (models.InterventionSnapshot, error) {
+	amended := *intervention
+	amended.Controls = append([]models.Control(nil), intervention.Controls...)
+
+	date, err := time.Parse("2006-01-02", c.FormValue("date"))
+	if err != nil {
+		return models.InterventionSnapshot{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid date format")
+	}
+	arrivedAt, err := parseFormDateTime(c.FormValue("arrived_at"))
+	if err != nil {
+		return models.InterventionSnapshot{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid arrival time")
+	}
+	departedAt, err := parseFormDateTime(c.FormValue("departed_at"))
+	if err != nil {
+		return models.InterventionSnapshot{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid departure time")
+	}
+	if arrivedAt != nil && departedAt != nil && departedAt.Before(*arrivedAt) {
+		return models.InterventionSnapshot{}, echo.NewHTTPError(http.StatusBadRequest, "Departure must be after arrival")
+	}
+	amended.Date = date
+	amended.ArrivedAt = arrivedAt
+	amended.DepartedAt = departedAt
+
+	amended.PortalStatusAfter = nil
+	if value := c.FormValue("portal_status_after"); value != "" {
+		status := models.PortalStatus(value)
+		if !status.IsValid() {
+			return models.InterventionSnapshot{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid portal status")
+		}
+		amended.PortalStatusAfter = &status
+	}
+
+	sections := intervention.Sections()
+	amended.Summary = optionalFormValue(c, "summary")
+	amended.Fault = nil
+	if sections.Fault {
+		amended.Fault = optionalFormValue(c, "fault")
+	}
+	amended.WorkDone = nil
+	if sections.Work {
+		amended.WorkDone = optionalFormValue(c, "work_done")
+	}
+
+	checklist := intervention.Checklist()
+	for index := range amended.Controls {
+		control := &amended.Controls[index]
+		item := checklist.Find(control.Kind)
+		if item == nil {
+			continue
+		}
+		submitted, err := controlFromForm(c, *item)
+		if err != nil {
+			return models.InterventionSnapshot{}, err
+		}
+		control.Outcome = submitted.Outcome
+		control.MeasuredValue = submitted.MeasuredValue
+		control.Remark = submitted.Remark
+	}
+
+	return amended.Snapshot(), nil
+}
+
+// optionalFormValue returns the value of a form field, nil when left empty
+func optionalFormValue(c echo.Context, name string) *string {
+	value := c.FormValue(name)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
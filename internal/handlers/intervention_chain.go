@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetAdminPortalChain walks the hash chain of the interventions of a portal
+// and reports any break
+func (h *Handlers) GetAdminPortalChain(c echo.Context) error {
+	var portal models.Portal
+	if result := h.DB.First(&portal, c.Param("id")); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	report, err := interventions.VerifyPortalChain(h.DB.WithContext(c.Request().Context()), portal.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to verify intervention chain")
+	}
+
+	return templates.AdminPortalChain(portal, report, c).Render(c.Request().Context(), c.Response().Writer)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetInterventionReportPDF serves a stored revision of the intervention PDF
+// report, the latest one unless a revision is requested. The first revision
+// is generated on demand.
+func (h *Handlers) GetInterventionReportPDF(c echo.Context) error {
+	interventionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid intervention ID")
+	}
+
+	ctx := c.Request().Context()
+	service := h.reportService()
+
+	var report *models.InterventionReport
+	if revisionParam := c.QueryParam("revision"); revisionParam != "" {
+		revision, err := strconv.Atoi(revisionParam)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid revision")
+		}
+		report, err = service.Revision(ctx, uint(interventionID), revision)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "Report revision not found")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+		}
+	} else {
+		report, err = service.Latest(ctx, uint(interventionID))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+			}
+			if errors.Is(err, interventions.ErrNotValidated) {
+				return echo.NewHTTPError(http.StatusConflict, "Intervention is not validated yet")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate report")
+		}
+	}
+
+	reader, err := service.Open(ctx, report)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open report")
+	}
+	defer reader.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="`+report.FileName()+`"`)
+	c.Response().Header().Set("X-Content-SHA256", report.SHA256)
+	return c.Stream(http.StatusOK, "application/pdf", reader)
+}
+
+// RegenerateInterventionReport renders the current data of the intervention
+// into a new report revision, keeping the previous ones
+func (h *Handlers) RegenerateInterventionReport(c echo.Context) error {
+	interventionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid intervention ID")
+	}
+
+	var intervention models.Intervention
+	if result := h.DB.First(&intervention, interventionID); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	if _, err := h.reportService().Regenerate(c.Request().Context(), intervention.ID); err != nil {
+		if errors.Is(err, interventions.ErrNotValidated) {
+			return echo.NewHTTPError(http.StatusConflict, "Intervention is not validated yet")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate report")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/portals/"+strconv.Itoa(int(intervention.PortalID)))
+}
+
+// GetAdminInterventions lists the latest validated interventions, filtered by
+// report number when a query is given
+func (h *Handlers) GetAdminInterventions(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+
+	db := h.DB.Preload("Portal").Where("status = ?", models.InterventionStatusValidated).Order("date desc, id desc").Limit(50)
+	if query != "" {
+		db = db.Where(`report_number ILIKE ? ESCAPE '\'`, "%"+escapeLike(query)+"%")
+	}
+
+	var interventions []models.Intervention
+	if result := db.Find(&interventions); result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch interventions")
+	}
+
+	return templates.AdminInterventions(interventions, query, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, with backslash as the
+// escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes a user query match literally inside a LIKE pattern
+func escapeLike(query string) string {
+	return likeEscaper.Replace(query)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/middleware"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetAdminReviews lists the interventions waiting for a supervisor, the
+// oldest submission first
+func (h *Handlers) GetAdminReviews(c echo.Context) error {
+	var submitted []models.Intervention
+	result := h.DB.Preload("Portal").Preload("Controls").Preload("ChecklistVersion.Items").Preload("Signatures").Preload("Reviews").
+		Where("status = ?", models.InterventionStatusSubmitted).
+		Order("submitted_at, id").
+		Find(&submitted)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch interventions")
+	}
+
+	return templates.AdminReviews(submitted, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// PostValidateIntervention validates a submitted intervention, which numbers
+// and seals it, queues its report to be emailed, and queues the email to the
+// portal contact when its status changed
+func (h *Handlers) PostValidateIntervention(c echo.Context) error {
+	err := h.reviewIntervention(c, func(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User) error {
+		if err := interventions.ValidateIntervention(tx, intervention, reviewer, time.Now()); err != nil {
+			return err
+		}
+		return interventions.QueueInterventionReport(c.Request().Context(), tx, intervention.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/reviews")
+}
+
+// PostRejectIntervention sends a submitted intervention back to its
+// technician with the comment of the supervisor
+func (h *Handlers) PostRejectIntervention(c echo.Context) error {
+	comment := c.FormValue("comment")
+	err := h.reviewIntervention(c, func(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User) error {
+		return interventions.RejectIntervention(tx, intervention, reviewer, comment, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/reviews")
+}
+
+// reviewIntervention applies the decision of the current user to the
+// intervention of the route, in a transaction holding the intervention lock
+func (h *Handlers) reviewIntervention(c echo.Context, decide func(*gorm.DB, *models.Intervention, *models.User) error) error {
+	reviewer, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
+	interventionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid intervention ID")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		intervention, err := interventions.LockIntervention(tx, uint(interventionID))
+		if err != nil {
+			return err
+		}
+		return decide(tx, intervention, reviewer)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Intervention not found")
+	case errors.Is(err, interventions.ErrNotSupervisor):
+		return echo.NewHTTPError(http.StatusForbidden, "Supervisor role required")
+	case errors.Is(err, interventions.ErrInvalidTransition):
+		return echo.NewHTTPError(http.StatusConflict, "Intervention is not waiting for validation")
+	case errors.Is(err, interventions.ErrRejectionCommentRequired):
+		return echo.NewHTTPError(http.StatusBadRequest, "A comment is required to reject an intervention")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to review intervention")
+	}
+}
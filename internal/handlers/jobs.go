@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+// RegisterJobs sets the handlers of the background jobs on the worker
+func (h *Handlers) RegisterJobs(worker *jobs.Worker) {
+	notificationService := interventions.NewNotificationService(h.DB, h.reportService())
+	jobs.Register(worker, interventions.SendReportJob, 2*time.Minute, interventions.SendReport(notificationService))
+	jobs.Register(worker, outbox.DeliverJob, time.Minute, outbox.Deliver(h.DB, h.EmailNotificationService, h.Storage))
+}
+
+// GetAdminJobs lists the latest background jobs of a status, the failed ones
+// by default
+func (h *Handlers) GetAdminJobs(c echo.Context) error {
+	status := models.JobStatus(c.QueryParam("status"))
+	if !status.IsValid() {
+		status = models.JobStatusDead
+	}
+
+	var counts []struct {
+		Status models.JobStatus
+		Count  int
+	}
+	if err := h.DB.Model(&models.Job{}).Select("status, COUNT(*) AS count").Group("status").Scan(&counts).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count jobs")
+	}
+	countByStatus := make(map[models.JobStatus]int, len(counts))
+	for _, count := range counts {
+		countByStatus[count.Status] = count.Count
+	}
+
+	var list []models.Job
+	if err := h.DB.Where("status = ?", status).Order("updated_at DESC").Limit(100).Find(&list).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch jobs")
+	}
+
+	return templates.AdminJobs(list, status, countByStatus, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// PostRetryJob queues a dead job again
+func (h *Handlers) PostRetryJob(c echo.Context) error {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid job ID")
+	}
+
+	if err := jobs.Retry(h.DB, uint(jobID)); err != nil {
+		if errors.Is(err, jobs.ErrNotRetryable) {
+			return echo.NewHTTPError(http.StatusConflict, "Only failed jobs can be retried")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retry job")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/jobs?status="+string(models.JobStatusDead))
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateNonConformity changes the assignee and the due date of an open
+// non-conformity
+func (h *Handlers) UpdateNonConformity(c echo.Context) error {
+	var nonConformity models.NonConformity
+	if result := h.DB.First(&nonConformity, c.Param("id")); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Non-conformity not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if !nonConformity.IsOpen() {
+		return echo.NewHTTPError(http.StatusConflict, "Non-conformity is already closed")
+	}
+
+	dueDate, err := time.Parse("2006-01-02", c.FormValue("due_date"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid due date")
+	}
+
+	var assigneeID *uint
+	if value := c.FormValue("assignee_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid assignee")
+		}
+		var assignee models.User
+		if result := h.DB.Where("is_active = ?", true).First(&assignee, id); result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return echo.NewHTTPError(http.StatusBadRequest, "Invalid assignee")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+		}
+		assigneeID = &assignee.ID
+	}
+
+	result := h.DB.Model(&nonConformity).Updates(map[string]any{
+		"due_date":    dueDate,
+		"assignee_id": assigneeID,
+	})
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update non-conformity")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/portals/"+strconv.Itoa(int(nonConformity.PortalID)))
+}
+
+// saveCorrectiveActions stores the corrective actions described on the
+// intervention form for the open non-conformities of the portal. Clearing a
+// description removes the corrective action.
+func saveCorrectiveActions(tx *gorm.DB, c echo.Context, intervention *models.Intervention) error {
+	var open []models.NonConformity
+	if err := tx.Where("portal_id = ? AND status = ?", intervention.PortalID, models.NonConformityStatusOpen).Find(&open).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch non-conformities")
+	}
+
+	for _, nonConformity := range open {
+		description := strings.TrimSpace(c.FormValue("corrective_action_" + strconv.Itoa(int(nonConformity.ID))))
+		existing := intervention.CorrectiveAction(nonConformity.ID)
+
+		var err error
+		switch {
+		case existing != nil && description == "":
+			err = tx.Delete(existing).Error
+		case existing != nil:
+			err = tx.Model(existing).Update("description", description).Error
+		case description != "":
+			err = tx.Omit(clause.Associations).Create(&models.CorrectiveAction{
+				NonConformityID: nonConformity.ID,
+				InterventionID:  intervention.ID,
+				Description:     description,
+			}).Error
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save corrective action")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostPortalRecipient adds a notification recipient to a portal
+func (h *Handlers) PostPortalRecipient(c echo.Context) error {
+	var portal models.Portal
+	if err := h.DB.First(&portal, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	recipient := models.NotificationRecipient{PortalID: &portal.ID}
+	return h.createNotificationRecipient(c, &recipient)
+}
+
+// PostContractRecipient adds a notification recipient to a contract, who is
+// notified of the events of every portal of the contract
+func (h *Handlers) PostContractRecipient(c echo.Context) error {
+	contract, err := h.findContract(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	recipient := models.NotificationRecipient{ContractID: &contract.ID}
+	return h.createNotificationRecipient(c, &recipient)
+}
+
+func (h *Handlers) createNotificationRecipient(c echo.Context, recipient *models.NotificationRecipient) error {
+	if err := notificationRecipientFromForm(c, recipient); err != nil {
+		return err
+	}
+
+	if err := h.DB.Omit(clause.Associations).Create(recipient).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create notification recipient")
+	}
+
+	return c.Redirect(http.StatusSeeOther, notificationRecipientReturnPath(recipient))
+}
+
+// UpdateNotificationRecipient changes the address, field and events of a
+// recipient. An unsubscribed recipient stays unsubscribed.
+func (h *Handlers) UpdateNotificationRecipient(c echo.Context) error {
+	recipient, err := h.findNotificationRecipient(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	if err := notificationRecipientFromForm(c, recipient); err != nil {
+		return err
+	}
+
+	if err := h.DB.Omit(clause.Associations).Save(recipient).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update notification recipient")
+	}
+
+	return c.Redirect(http.StatusSeeOther, notificationRecipientReturnPath(recipient))
+}
+
+func (h *Handlers) DeleteNotificationRecipient(c echo.Context) error {
+	recipient, err := h.findNotificationRecipient(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	if err := h.DB.Delete(recipient).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete notification recipient")
+	}
+
+	return c.Redirect(http.StatusSeeOther, notificationRecipientReturnPath(recipient))
+}
+
+func (h *Handlers) findNotificationRecipient(id string) (*models.NotificationRecipient, error) {
+	var recipient models.NotificationRecipient
+	if err := h.DB.First(&recipient, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Notification recipient not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return &recipient, nil
+}
+
+// notificationRecipientFromForm validates the recipient form and applies it
+func notificationRecipientFromForm(c echo.Context, recipient *models.NotificationRecipient) error {
+	address, err := mail.ParseAddress(strings.TrimSpace(c.FormValue("email")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid email")
+	}
+
+	field := models.RecipientField(c.FormValue("field"))
+	if !field.IsValid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recipient field")
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid form")
+	}
+	events := []models.NotificationEvent{}
+	for _, value := range form["events"] {
+		event := models.NotificationEvent(value)
+		if !event.IsValid() {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid notification event")
+		}
+		events = append(events, event)
+	}
+
+	recipient.Name = strings.TrimSpace(c.FormValue("name"))
+	recipient.Email = address.Address
+	recipient.Field = field
+	recipient.Events = events
+	return nil
+}
+
+// notificationRecipientReturnPath returns the admin page listing a recipient
+func notificationRecipientReturnPath(recipient *models.NotificationRecipient) string {
+	if recipient.ContractID != nil {
+		return "/admin/contracts/" + strconv.Itoa(int(*recipient.ContractID))
+	}
+	return "/admin/portals/" + strconv.Itoa(int(*recipient.PortalID)) + "/edit"
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/middleware"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/portals"
+	"gorm.io/gorm"
+)
+
+// UpdatePortalStatus changes the operational status of a portal by hand, for
+// instance to put it back in service or to decommission it
+func (h *Handlers) UpdatePortalStatus(c echo.Context) error {
+	user, err := middleware.GetCurrentUser(c, h.DB)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user")
+	}
+
+	portalID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid portal ID")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := portals.ChangeStatus(tx, uint(portalID), models.PortalStatus(c.FormValue("status")), models.PortalStatusChange{
+			Reason:   c.FormValue("reason"),
+			UserID:   &user.ID,
+			UserName: user.FullName(),
+		})
+		return err
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
+	case errors.Is(err, portals.ErrInvalidStatus):
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid portal status")
+	case errors.Is(err, portals.ErrStatusReasonRequired):
+		return echo.NewHTTPError(http.StatusBadRequest, "A reason is required to change the portal status")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update portal status")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/portals/"+strconv.Itoa(int(portalID)))
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetAdminReminders lists the reminder rules and the latest reminders sent,
+// those of one portal when portal_id is given
+func (h *Handlers) GetAdminReminders(c echo.Context) error {
+	var rules []models.ReminderRule
+	if err := h.DB.Order("offset_days, id").Find(&rules).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch reminder rules")
+	}
+
+	query := h.DB.Preload("Portal").Preload("Rule").Preload("Email").Order("created_at DESC").Limit(200)
+	var portal *models.Portal
+	if portalID := c.QueryParam("portal_id"); portalID != "" {
+		portal = &models.Portal{}
+		if err := h.DB.First(portal, portalID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "Portal not found")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+		}
+		query = query.Where("portal_id = ?", portal.ID)
+	}
+
+	var reminders []models.MaintenanceReminder
+	if err := query.Find(&reminders).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch reminders")
+	}
+
+	return templates.AdminReminders(rules, reminders, portal, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) PostReminderRule(c echo.Context) error {
+	var rule models.ReminderRule
+	if err := reminderRuleFromForm(c, &rule); err != nil {
+		return err
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create reminder rule")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/reminders")
+}
+
+func (h *Handlers) UpdateReminderRule(c echo.Context) error {
+	var rule models.ReminderRule
+	if result := h.DB.First(&rule, c.Param("id")); result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "Reminder rule not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	if err := reminderRuleFromForm(c, &rule); err != nil {
+		return err
+	}
+
+	if err := h.DB.Save(&rule).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update reminder rule")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/reminders")
+}
+
+// reminderRuleFromForm validates the reminder rule form and copies its values
+// to the rule. The offset is entered as a number of days and whether it is
+// before or after the due date.
+func reminderRuleFromForm(c echo.Context, rule *models.ReminderRule) error {
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	days, err := strconv.Atoi(c.FormValue("days"))
+	if err != nil || days < 0 || days > 365 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Days must be between 0 and 365")
+	}
+	switch c.FormValue("when") {
+	case "before":
+		days = -days
+	case "after":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid reminder timing")
+	}
+
+	repeatEveryDays := 0
+	if value := c.FormValue("repeat_every_days"); value != "" {
+		repeatEveryDays, err = strconv.Atoi(value)
+		if err != nil || repeatEveryDays < 0 || repeatEveryDays > 365 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Repeat interval must be between 0 and 365 days")
+		}
+	}
+
+	rule.Name = name
+	rule.OffsetDays = days
+	rule.RepeatEveryDays = repeatEveryDays
+	rule.Active = c.FormValue("active") == "true"
+	return nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/portals"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/scheduler"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+// RegisterTasks adds the recurring tasks to the scheduler. Cleaning up
+// expired tokens and rolling up QR analytics are left out until the
+// application stores expiring tokens or records QR scans, it does neither
+// yet: sessions live in cookies and download URLs are signed.
+func (h *Handlers) RegisterTasks(s *scheduler.Scheduler) error {
+	tasks := []scheduler.Task{
+		{
+			// Temporary PDFs are written on the host generating them
+			Name:        "cleanup_temp_files",
+			Description: "Supprime les PDF temporaires laissés par les générations et envois de rapports interrompus",
+			Schedule:    "15 * * * *",
+			Timeout:     5 * time.Minute,
+			Local:       true,
+			Run: func(ctx context.Context) error {
+				removed, err := interventions.RemoveStaleTempFiles(os.TempDir(), time.Hour, time.Now())
+				if removed > 0 {
+					log.Printf("Removed %d stale temp files", removed)
+				}
+				return err
+			},
+		},
+		{
+			Name:        "purge_history",
+			Description: "Supprime les tâches réussies de plus de 30 jours et l'historique des exécutions de plus de 90 jours",
+			Schedule:    "30 3 * * *",
+			Timeout:     10 * time.Minute,
+			Run: func(ctx context.Context) error {
+				db := h.DB.WithContext(ctx)
+				if _, err := jobs.PurgeSucceeded(db, time.Now().AddDate(0, 0, -30)); err != nil {
+					return err
+				}
+				_, err := scheduler.PurgeRuns(db, time.Now().AddDate(0, 0, -90))
+				return err
+			},
+		},
+		{
+			Name:        "maintenance_reminders",
+			Description: "Relance par email les contacts, prestataires et techniciens des portails selon les règles de relance",
+			Schedule:    "0 8 * * *",
+			Timeout:     15 * time.Minute,
+			Run: func(ctx context.Context) error {
+				sent, err := portals.SendMaintenanceReminders(ctx, h.DB, time.Now())
+				if sent > 0 {
+					log.Printf("Queued %d maintenance reminders", sent)
+				}
+				return err
+			},
+		},
+		{
+			Name:        "weekly_digest",
+			Description: "Envoie aux superviseurs le point hebdomadaire des visites en retard et des non-conformités",
+			Schedule:    "0 7 * * 1",
+			Timeout:     5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				return portals.SendWeeklyDigest(ctx, h.DB, time.Now())
+			},
+		},
+	}
+	for _, task := range tasks {
+		if err := s.Register(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAdminTasks lists the scheduled tasks and their latest runs
+func (h *Handlers) GetAdminTasks(c echo.Context) error {
+	var tasks []models.ScheduledTask
+	if err := h.DB.Order("name").Find(&tasks).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch tasks")
+	}
+
+	var runs []models.TaskRun
+	if err := h.DB.Preload("Task").Order("started_at DESC").Limit(100).Find(&runs).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch task runs")
+	}
+
+	return templates.AdminTasks(tasks, runs, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// PostRunTask asks the scheduler to run a task now
+func (h *Handlers) PostRunTask(c echo.Context) error {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	if err := scheduler.RequestRun(h.DB, uint(taskID)); err != nil {
+		if errors.Is(err, scheduler.ErrUnknownTask) {
+			return echo.NewHTTPError(http.StatusNotFound, "Task not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to request task run")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/tasks")
+}
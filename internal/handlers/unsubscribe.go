@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/notifications"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetUnsubscribe shows the unsubscribe page linked from a notification
+// email, where its recipient confirms
+func (h *Handlers) GetUnsubscribe(c echo.Context) error {
+	outgoing, err := h.findUnsubscribeEmail(c.Param("token"))
+	if err != nil {
+		return err
+	}
+
+	config := templates.UnsubscribeConfig{Email: *outgoing.Recipient}
+	return templates.Unsubscribe(config, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// PostUnsubscribe stops notifying the recipient of the email of the events
+// of its portal
+func (h *Handlers) PostUnsubscribe(c echo.Context) error {
+	outgoing, err := h.findUnsubscribeEmail(c.Param("token"))
+	if err != nil {
+		return err
+	}
+	if err := notifications.Unsubscribe(h.DB, c.Param("token"), time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to unsubscribe")
+	}
+
+	config := templates.UnsubscribeConfig{Email: *outgoing.Recipient, Done: true}
+	return templates.Unsubscribe(config, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// findUnsubscribeEmail returns the notification an unsubscribe token was
+// sent with
+func (h *Handlers) findUnsubscribeEmail(token string) (*models.OutboxEmail, error) {
+	var outgoing models.OutboxEmail
+	err := h.DB.Where("unsubscribe_token = ? AND recipient IS NOT NULL AND portal_id IS NOT NULL", token).First(&outgoing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Unknown unsubscribe link")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+	return &outgoing, nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/interventions"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// GetVerifyReport shows the public details of the report revision printed
+// with a verification code
+func (h *Handlers) GetVerifyReport(c echo.Context) error {
+	config, err := h.verifyReportConfig(c)
+	if err != nil {
+		return err
+	}
+
+	return templates.VerifyReport(config, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+// verifyUploadOverhead is the room left for the multipart encoding around the
+// uploaded report
+const verifyUploadOverhead = 1 << 20
+
+// PostVerifyReport compares an uploaded PDF with the stored report revision
+// printed with a verification code
+func (h *Handlers) PostVerifyReport(c echo.Context) error {
+	config, err := h.verifyReportConfig(c)
+	if err != nil {
+		return err
+	}
+
+	// The route is public, parsing the upload stops as soon as it exceeds the
+	// largest report and the multipart overhead
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, storage.Reports.MaxSize+verifyUploadOverhead)
+	fileHeader, err := c.FormFile("pdf")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "PDF file is too large")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "Missing PDF file")
+	}
+	if fileHeader.Size > storage.Reports.MaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "PDF file is too large")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read PDF file")
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.LimitReader(file, storage.Reports.MaxSize)); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read PDF file")
+	}
+
+	config.UploadedSHA256 = hex.EncodeToString(hash.Sum(nil))
+	matches := config.UploadedSHA256 == config.Report.SHA256
+	config.Matches = &matches
+
+	return templates.VerifyReport(config, c).Render(c.Request().Context(), c.Response().Writer)
+}
+
+func (h *Handlers) verifyReportConfig(c echo.Context) (templates.VerifyReportConfig, error) {
+	ctx := c.Request().Context()
+	service := h.reportService()
+
+	report, err := service.ByVerificationCode(ctx, c.Param("code"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return templates.VerifyReportConfig{}, echo.NewHTTPError(http.StatusNotFound, "Unknown verification code")
+		}
+		return templates.VerifyReportConfig{}, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	latestRevision, err := service.LatestRevision(ctx, report.InterventionID)
+	if err != nil {
+		return templates.VerifyReportConfig{}, echo.NewHTTPError(http.StatusInternalServerError, "Database error")
+	}
+
+	return templates.VerifyReportConfig{
+		Report:         report,
+		Code:           interventions.FormatVerificationCode(*report.VerificationCode),
+		LatestRevision: latestRevision,
+	}, nil
+}
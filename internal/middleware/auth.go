@@ -25,6 +25,7 @@ func RequireAuth() echo.MiddlewareFunc {
 
 			c.Set("user_id", userID)
 			c.Set("user_email", sess.Values["user_email"])
+			c.Set("user_role", sess.Values["user_role"])
 
 			return next(c)
 		}
@@ -45,3 +46,21 @@ func GetCurrentUser(c echo.Context, db *gorm.DB) (*models.User, error) {
 
 	return &user, nil
 }
+
+// RequireSupervisor only lets supervisors through. It must run after
+// RequireAuth. The role is read from the database so that a demotion applies
+// to open sessions.
+func RequireSupervisor(db *gorm.DB) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, err := GetCurrentUser(c, db)
+			if err != nil {
+				return c.Redirect(http.StatusSeeOther, "/login")
+			}
+			if !user.IsSupervisor() {
+				return echo.NewHTTPError(http.StatusForbidden, "Supervisor role required")
+			}
+			return next(c)
+		}
+	}
+}
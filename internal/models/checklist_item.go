@@ -0,0 +1,126 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ChecklistItemType string
+
+const (
+	// ChecklistItemTypeCheck items are marked compliant or non-compliant
+	ChecklistItemTypeCheck ChecklistItemType = "check"
+	// ChecklistItemTypeMeasure items record a measured value whose result is
+	// derived from the item thresholds
+	ChecklistItemTypeMeasure ChecklistItemType = "measure"
+)
+
+// ChecklistItem is one control line of a checklist version. Code is the value
+// stored in Control.Kind when the item is filled in.
+type ChecklistItem struct {
+	ID                 uint              `json:"id" gorm:"primaryKey"`
+	ChecklistVersionID uint              `json:"checklist_version_id" gorm:"not null;index"`
+	Code               string            `json:"code" gorm:"type:varchar(50);not null"`
+	Kind               ControlKind       `json:"kind" gorm:"type:varchar(20);not null"`
+	Label              string            `json:"label" gorm:"not null"`
+	Position           int               `json:"position" gorm:"not null;default:0"`
+	Type               ChecklistItemType `json:"type" gorm:"type:varchar(20);not null;default:check"`
+	Unit               string            `json:"unit" gorm:"type:varchar(20)"`
+	MinValue           *float64          `json:"min_value"`
+	MaxValue           *float64          `json:"max_value"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+func (ChecklistItem) TableName() string {
+	return "checklist_items"
+}
+
+func (item ChecklistItem) IsMeasure() bool {
+	return item.Type == ChecklistItemTypeMeasure
+}
+
+// IsWithinLimits reports whether a measured value is within the item thresholds
+func (item ChecklistItem) IsWithinLimits(value float64) bool {
+	if item.MinValue != nil && value < *item.MinValue {
+		return false
+	}
+	if item.MaxValue != nil && value > *item.MaxValue {
+		return false
+	}
+	return true
+}
+
+// BeforeSave prevents changing the items of a version already in use
+func (item *ChecklistItem) BeforeSave(tx *gorm.DB) error {
+	return item.ensureVersionUnused(tx)
+}
+
+// BeforeDelete prevents removing the items of a version already in use
+func (item *ChecklistItem) BeforeDelete(tx *gorm.DB) error {
+	return item.ensureVersionUnused(tx)
+}
+
+func (item *ChecklistItem) ensureVersionUnused(tx *gorm.DB) error {
+	if item.ChecklistVersionID == 0 {
+		return nil
+	}
+	version := ChecklistVersion{ID: item.ChecklistVersionID}
+	used, err := version.IsUsed(tx.Session(&gorm.Session{NewDB: true}))
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrChecklistVersionLocked
+	}
+	return nil
+}
+
+type ChecklistItems []ChecklistItem
+
+// ByKind returns the items of the given kind, ordered by position
+func (items ChecklistItems) ByKind(kind ControlKind) ChecklistItems {
+	var filtered ChecklistItems
+	for _, item := range items {
+		if item.Kind == kind {
+			filtered = append(filtered, item)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Position < filtered[j].Position
+	})
+	return filtered
+}
+
+// Ordered returns the items grouped by kind in display order, each group
+// ordered by position
+func (items ChecklistItems) Ordered() ChecklistItems {
+	var ordered ChecklistItems
+	for _, kind := range ControlKinds {
+		ordered = append(ordered, items.ByKind(kind)...)
+	}
+	return ordered
+}
+
+// Find returns the item with the given code, or nil when the checklist does
+// not define it
+func (items ChecklistItems) Find(code string) *ChecklistItem {
+	for i := range items {
+		if items[i].Code == code {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// Label returns the label of the item with the given code, or the code itself
+// when the checklist does not define it
+func (items ChecklistItems) Label(code string) string {
+	if item := items.Find(code); item != nil {
+		return item.Label
+	}
+	return code
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecklistItems_ByKind(t *testing.T) {
+	items := ChecklistItems{
+		{Code: "drive_system", Kind: ControlKindOther, Position: 2},
+		{Code: "safety_cells", Kind: ControlKindSecurity, Position: 1},
+		{Code: "warning_lights", Kind: ControlKindSecurity, Position: 0},
+	}
+
+	security := items.ByKind(ControlKindSecurity)
+	assert.Len(t, security, 2)
+	assert.Equal(t, "warning_lights", security[0].Code)
+	assert.Equal(t, "safety_cells", security[1].Code)
+
+	other := items.ByKind(ControlKindOther)
+	assert.Len(t, other, 1)
+	assert.Equal(t, "drive_system", other[0].Code)
+}
+
+func TestChecklistItems_Label(t *testing.T) {
+	items := ChecklistItems{
+		{Code: "warning_lights", Label: "Feux d'avertissement"},
+	}
+
+	assert.Equal(t, "Feux d'avertissement", items.Label("warning_lights"))
+	assert.Equal(t, "unknown_code", items.Label("unknown_code"))
+}
+
+func TestControlKind_IsValid(t *testing.T) {
+	assert.True(t, ControlKindSecurity.IsValid())
+	assert.True(t, ControlKindOther.IsValid())
+	assert.False(t, ControlKind("electrical").IsValid())
+}
+
+func TestChecklistItems_Ordered(t *testing.T) {
+	items := ChecklistItems{
+		{Code: "drive_system", Kind: ControlKindOther, Position: 0},
+		{Code: "safety_cells", Kind: ControlKindSecurity, Position: 1},
+		{Code: "warning_lights", Kind: ControlKindSecurity, Position: 0},
+	}
+
+	ordered := items.Ordered()
+	assert.Equal(t, []string{"warning_lights", "safety_cells", "drive_system"}, []string{ordered[0].Code, ordered[1].Code, ordered[2].Code})
+}
+
+func TestEquipmentType_CurrentChecklist(t *testing.T) {
+	equipmentType := EquipmentType{}
+	assert.Nil(t, equipmentType.CurrentChecklist())
+
+	equipmentType.ChecklistVersions = []ChecklistVersion{{ID: 1, Version: 1}, {ID: 3, Version: 3}, {ID: 2, Version: 2}}
+	assert.Equal(t, uint(3), equipmentType.CurrentChecklist().ID)
+}
+
+func TestChecklistItem_IsWithinLimits(t *testing.T) {
+	minValue, maxValue := 10.0, 400.0
+	item := ChecklistItem{Type: ChecklistItemTypeMeasure, MinValue: &minValue, MaxValue: &maxValue}
+
+	assert.True(t, item.IsWithinLimits(10))
+	assert.True(t, item.IsWithinLimits(400))
+	assert.False(t, item.IsWithinLimits(9.9))
+	assert.False(t, item.IsWithinLimits(400.5))
+
+	unbounded := ChecklistItem{Type: ChecklistItemTypeMeasure}
+	assert.True(t, unbounded.IsWithinLimits(-1000))
+}
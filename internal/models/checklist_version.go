@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrChecklistVersionLocked is returned when modifying the items of a
+// checklist version that interventions were already recorded against
+var ErrChecklistVersionLocked = errors.New("checklist version is used by interventions and cannot be modified")
+
+// ChecklistVersion is an immutable snapshot of an equipment type checklist.
+// Once an intervention references a version, its items can no longer change;
+// edits go to a new version instead.
+type ChecklistVersion struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	EquipmentTypeID uint           `json:"equipment_type_id" gorm:"not null;uniqueIndex:idx_checklist_versions_type_version"`
+	Version         int            `json:"version" gorm:"not null;uniqueIndex:idx_checklist_versions_type_version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	EquipmentType EquipmentType  `json:"equipment_type,omitempty" gorm:"foreignKey:EquipmentTypeID"`
+	Items         ChecklistItems `json:"items,omitempty" gorm:"foreignKey:ChecklistVersionID"`
+}
+
+func (ChecklistVersion) TableName() string {
+	return "checklist_versions"
+}
+
+// IsUsed reports whether interventions were recorded against the version
+func (v *ChecklistVersion) IsUsed(tx *gorm.DB) (bool, error) {
+	var count int64
+	if err := tx.Model(&Intervention{}).Where("checklist_version_id = ?", v.ID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MinVisitsPerYear is the number of preventive visits French regulations
+// require each year for automatic gates
+const MinVisitsPerYear = 2
+
+// ContractExpiryNotice is the number of days before its end date a contract
+// is listed as about to expire
+const ContractExpiryNotice = 60
+
+// Contract is the maintenance contract covering one or more portals
+type Contract struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Reference        string         `json:"reference" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Contractor       string         `json:"contractor" gorm:"not null"`
+	ContractorEmail  string         `json:"contractor_email"`
+	StartDate        time.Time      `json:"start_date" gorm:"type:date;not null"`
+	EndDate          time.Time      `json:"end_date" gorm:"type:date;not null;index"`
+	VisitsPerYear    int            `json:"visits_per_year" gorm:"not null;default:2"`
+	CallOutHours     int            `json:"call_out_hours" gorm:"not null"`
+	AnnualPriceCents int64          `json:"annual_price_cents" gorm:"not null"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Portals                []Portal                `json:"portals,omitempty" gorm:"foreignKey:ContractID"`
+	NotificationRecipients []NotificationRecipient `json:"notification_recipients,omitempty" gorm:"foreignKey:ContractID"`
+}
+
+func (Contract) TableName() string {
+	return "contracts"
+}
+
+// IsActive reports whether the contract covers the given day
+func (c *Contract) IsActive(now time.Time) bool {
+	today := startOfDay(now)
+	return !today.Before(startOfDay(c.StartDate)) && !today.After(startOfDay(c.EndDate))
+}
+
+// IsExpired reports whether the end date of the contract has passed
+func (c *Contract) IsExpired(now time.Time) bool {
+	return startOfDay(now).After(startOfDay(c.EndDate))
+}
+
+// IsExpiring reports whether the contract ends within ContractExpiryNotice
+// days and has not expired yet
+func (c *Contract) IsExpiring(now time.Time) bool {
+	return !c.IsExpired(now) && c.DaysUntilExpiry(now) <= ContractExpiryNotice
+}
+
+// DaysUntilExpiry returns the number of days left before the end date of the
+// contract, negative once it has expired
+func (c *Contract) DaysUntilExpiry(now time.Time) int {
+	return int(startOfDay(c.EndDate).Sub(startOfDay(now)).Hours() / 24)
+}
+
+// VisitIntervalMonths returns the number of months between two preventive
+// visits, rounded down so the contract frequency is always met
+func (c *Contract) VisitIntervalMonths() int {
+	if c.VisitsPerYear <= 0 {
+		return 12 / MinVisitsPerYear
+	}
+	if months := 12 / c.VisitsPerYear; months > 0 {
+		return months
+	}
+	return 1
+}
+
+// NextVisitDue returns the date of the next preventive visit, one interval
+// after the last one, or the start of the contract when no visit was made yet
+func (c *Contract) NextVisitDue(lastVisit *time.Time) time.Time {
+	if lastVisit == nil {
+		return startOfDay(c.StartDate)
+	}
+	return startOfDay(*lastVisit).AddDate(0, c.VisitIntervalMonths(), 0)
+}
+
+// NextVisitDue returns the date of the next preventive visit of the portal
+// given its last one, nil when it has no loaded contract or is decommissioned
+func (p *Portal) NextVisitDue(lastVisit *time.Time) *time.Time {
+	if p.Contract == nil || p.OperationalStatus() == PortalStatusDecommissioned {
+		return nil
+	}
+	due := p.Contract.NextVisitDue(lastVisit)
+	return &due
+}
+
+// startOfDay truncates a time to its calendar day, in UTC like date columns
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testContract() *Contract {
+	return &Contract{
+		StartDate:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:       time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		VisitsPerYear: 2,
+	}
+}
+
+func TestContract_Expiry(t *testing.T) {
+	contract := testContract()
+
+	before := time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC)
+	assert.False(t, contract.IsActive(before))
+	assert.False(t, contract.IsExpiring(before))
+
+	midYear := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	assert.True(t, contract.IsActive(midYear))
+	assert.False(t, contract.IsExpiring(midYear))
+
+	lastDay := time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)
+	assert.True(t, contract.IsActive(lastDay))
+	assert.False(t, contract.IsExpired(lastDay))
+	assert.True(t, contract.IsExpiring(lastDay))
+	assert.Equal(t, 0, contract.DaysUntilExpiry(lastDay))
+
+	notice := time.Date(2025, 11, 1, 8, 0, 0, 0, time.UTC)
+	assert.True(t, contract.IsExpiring(notice))
+	assert.Equal(t, 60, contract.DaysUntilExpiry(notice))
+
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, contract.IsActive(after))
+	assert.True(t, contract.IsExpired(after))
+	assert.False(t, contract.IsExpiring(after))
+	assert.Equal(t, -1, contract.DaysUntilExpiry(after))
+}
+
+func TestContract_VisitIntervalMonths(t *testing.T) {
+	assert.Equal(t, 6, (&Contract{VisitsPerYear: 2}).VisitIntervalMonths())
+	assert.Equal(t, 4, (&Contract{VisitsPerYear: 3}).VisitIntervalMonths())
+	assert.Equal(t, 2, (&Contract{VisitsPerYear: 5}).VisitIntervalMonths())
+	assert.Equal(t, 1, (&Contract{VisitsPerYear: 24}).VisitIntervalMonths())
+	assert.Equal(t, 6, (&Contract{}).VisitIntervalMonths())
+}
+
+func TestContract_NextVisitDue(t *testing.T) {
+	contract := testContract()
+
+	assert.Equal(t, contract.StartDate, contract.NextVisitDue(nil))
+
+	lastVisit := time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC), contract.NextVisitDue(&lastVisit))
+}
+
+func TestPortal_NextVisitDue(t *testing.T) {
+	lastVisit := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, (&Portal{}).NextVisitDue(&lastVisit))
+
+	portal := &Portal{Contract: testContract()}
+	due := portal.NextVisitDue(&lastVisit)
+	require.NotNil(t, due)
+	assert.Equal(t, time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC), *due)
+
+	portal.Status = PortalStatusDecommissioned
+	assert.Nil(t, portal.NextVisitDue(&lastVisit))
+}
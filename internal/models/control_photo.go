@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ControlPhoto is a picture taken as evidence while performing a control. Key
+// holds the web sized copy and ThumbnailKey its thumbnail, the original upload
+// is not kept.
+type ControlPhoto struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ControlID    uint           `json:"control_id" gorm:"not null;index"`
+	FileName     string         `json:"file_name" gorm:"not null"`
+	ContentType  string         `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size         int64          `json:"size" gorm:"not null"`
+	Key          string         `json:"-" gorm:"not null"`
+	SHA256       string         `json:"sha256" gorm:"type:char(64)"`
+	ThumbnailKey string         `json:"-"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	CapturedAt   *time.Time     `json:"captured_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Control Control `json:"control,omitempty" gorm:"foreignKey:ControlID"`
+}
+
+func (ControlPhoto) TableName() string {
+	return "control_photos"
+}
+
+// ThumbnailOrWebKey returns the key of the thumbnail, falling back to the web
+// sized copy for photos uploaded before thumbnails were generated
+func (p ControlPhoto) ThumbnailOrWebKey() string {
+	if p.ThumbnailKey != "" {
+		return p.ThumbnailKey
+	}
+	return p.Key
+}
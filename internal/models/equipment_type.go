@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EquipmentTypeCodeGate is the equipment type assigned to portals created
+// before equipment types existed (sliding or sectional gates).
+const EquipmentTypeCodeGate = "gate"
+
+// EquipmentType defines the checklist of a kind of equipment, and the
+// preventive visit frequency of its portals not covered by a contract
+type EquipmentType struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Code          string         `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name          string         `json:"name" gorm:"not null"`
+	VisitsPerYear int            `json:"visits_per_year" gorm:"not null;default:2"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	ChecklistVersions []ChecklistVersion `json:"checklist_versions,omitempty" gorm:"foreignKey:EquipmentTypeID"`
+}
+
+func (EquipmentType) TableName() string {
+	return "equipment_types"
+}
+
+// CurrentChecklist returns the latest of the loaded checklist versions
+func (e *EquipmentType) CurrentChecklist() *ChecklistVersion {
+	var current *ChecklistVersion
+	for i := range e.ChecklistVersions {
+		if current == nil || e.ChecklistVersions[i].Version > current.Version {
+			current = &e.ChecklistVersions[i]
+		}
+	}
+	return current
+}
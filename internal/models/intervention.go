@@ -1,6 +1,8 @@
 package models
 
 import (
+	"sort"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,66 +15,270 @@ const (
 	ControlKindOther    ControlKind = "other"
 )
 
-var ControlTypesByKind = map[ControlKind][]string{
-	ControlKindSecurity: {
-		"warning_lights",
-		"area_lighting",
-		"safety_cells",
-		"pressure_bar",
-		"floor_loop",
-		"force_limiter",
-		"safety_springs",
-		"floor_markings",
-	},
-	ControlKindOther: {
-		"apron_condition",
-		"horizontal_rails",
-		"vertical_rails",
-		"roller_condition",
-		"drive_system",
-		"limit_switches",
-		"control_devices",
-		"control_panel",
-		"manual_override",
-	},
-}
-
-type ControlResult *bool
+// ControlKinds lists the control kinds in display order
+var ControlKinds = []ControlKind{ControlKindSecurity, ControlKindOther}
+
+func (k ControlKind) IsValid() bool {
+	for _, kind := range ControlKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type ControlOutcome string
+
+const (
+	ControlOutcomeCompliant     ControlOutcome = "compliant"
+	ControlOutcomeNonCompliant  ControlOutcome = "non_compliant"
+	ControlOutcomeNotChecked    ControlOutcome = "not_checked"
+	ControlOutcomeNotApplicable ControlOutcome = "not_applicable"
+)
+
+// ControlOutcomes lists the control outcomes in display order
+var ControlOutcomes = []ControlOutcome{
+	ControlOutcomeCompliant,
+	ControlOutcomeNonCompliant,
+	ControlOutcomeNotChecked,
+	ControlOutcomeNotApplicable,
+}
+
+func (o ControlOutcome) IsValid() bool {
+	for _, outcome := range ControlOutcomes {
+		if o == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the outcome
+func (o ControlOutcome) Label() string {
+	switch o {
+	case ControlOutcomeCompliant:
+		return "Conforme"
+	case ControlOutcomeNonCompliant:
+		return "Non conforme"
+	case ControlOutcomeNotChecked:
+		return "Non contrôlé"
+	case ControlOutcomeNotApplicable:
+		return "Sans objet"
+	}
+	return string(o)
+}
 
 type Intervention struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Date      time.Time      `json:"date" gorm:"not null"`
-	Summary   *string        `json:"summary"`
-	UserID    uint           `json:"user_id" gorm:"not null"`
-	UserName  string         `json:"user_name" gorm:"not null"`
-	PortalID  uint           `json:"portal_id" gorm:"not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint               `json:"id" gorm:"primaryKey"`
+	Date               time.Time          `json:"date" gorm:"not null"`
+	Type               *InterventionType  `json:"type" gorm:"type:varchar(30)"`
+	Summary            *string            `json:"summary"`
+	Fault              *string            `json:"fault" gorm:"type:text"`
+	WorkDone           *string            `json:"work_done" gorm:"type:text"`
+	ArrivedAt          *time.Time         `json:"arrived_at"`
+	DepartedAt         *time.Time         `json:"departed_at"`
+	PortalStatusAfter  *PortalStatus      `json:"portal_status_after" gorm:"type:varchar(20)"`
+	Status             InterventionStatus `json:"status" gorm:"type:varchar(20);not null;default:draft;index"`
+	SubmittedAt        *time.Time         `json:"submitted_at"`
+	ValidatedAt        *time.Time         `json:"validated_at"`
+	Revision           int                `json:"revision" gorm:"not null;default:1"`
+	UserID             uint               `json:"user_id" gorm:"not null"`
+	UserName           string             `json:"user_name" gorm:"not null"`
+	PortalID           uint               `json:"portal_id" gorm:"not null;uniqueIndex:idx_interventions_portal_chain_index"`
+	ChecklistVersionID *uint              `json:"checklist_version_id" gorm:"index"`
+	OrganizationID     *uint              `json:"organization_id" gorm:"uniqueIndex:idx_interventions_organization_report_number"`
+	ReportNumber       *string            `json:"report_number" gorm:"type:varchar(20);uniqueIndex:idx_interventions_organization_report_number"`
+	ChainIndex         *int               `json:"chain_index" gorm:"uniqueIndex:idx_interventions_portal_chain_index"`
+	PreviousHash       *string            `json:"previous_hash" gorm:"type:char(64)"`
+	ChainHash          *string            `json:"chain_hash" gorm:"type:char(64)"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt     `json:"-" gorm:"index"`
 
 	// Relationships
-	Portal   Portal    `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
-	User     User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Controls []Control `json:"controls,omitempty" gorm:"foreignKey:intervention_id"`
+	Portal            Portal                  `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
+	ChecklistVersion  *ChecklistVersion       `json:"checklist_version,omitempty" gorm:"foreignKey:ChecklistVersionID"`
+	User              User                    `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Controls          []Control               `json:"controls,omitempty" gorm:"foreignKey:intervention_id"`
+	Reports           []InterventionReport    `json:"reports,omitempty" gorm:"foreignKey:InterventionID"`
+	Signatures        []InterventionSignature `json:"signatures,omitempty" gorm:"foreignKey:InterventionID"`
+	Reviews           []InterventionReview    `json:"reviews,omitempty" gorm:"foreignKey:InterventionID"`
+	Revisions         []InterventionRevision  `json:"revisions,omitempty" gorm:"foreignKey:InterventionID"`
+	CorrectiveActions []CorrectiveAction      `json:"corrective_actions,omitempty" gorm:"foreignKey:InterventionID"`
+	Emails            []OutboxEmail           `json:"emails,omitempty" gorm:"foreignKey:InterventionID"`
 }
 
 type Control struct {
 	ID             uint           `json:"id" gorm:"primaryKey"`
-	Kind           string         `json:"kind" gorm:"type:varchar(20);not null"`
-	Result         ControlResult  `json:"result"`
+	Kind           string         `json:"kind" gorm:"type:varchar(50);not null"`
+	Outcome        ControlOutcome `json:"outcome" gorm:"type:varchar(20);not null;default:not_checked"`
+	MeasuredValue  *float64       `json:"measured_value"`
+	Remark         *string        `json:"remark" gorm:"type:text"`
 	InterventionID uint           `json:"intervention_id" gorm:"not null"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	Intervention Intervention `json:"intervention,omitempty"`
+	Intervention Intervention   `json:"intervention,omitempty"`
+	Photos       []ControlPhoto `json:"photos,omitempty" gorm:"foreignKey:ControlID"`
 }
 
 func (Intervention) TableName() string {
 	return "interventions"
 }
 
+// ReportReference returns the report number of the intervention, or its
+// database ID for interventions recorded before numbering was introduced
+func (i *Intervention) ReportReference() string {
+	if i.ReportNumber != nil {
+		return *i.ReportNumber
+	}
+	return "#" + strconv.Itoa(int(i.ID))
+}
+
+// IsSealed reports whether the intervention was added to the hash chain of
+// its portal. ChainIndex, PreviousHash and ChainHash are nil until then.
+func (i *Intervention) IsSealed() bool {
+	return i.ChainHash != nil
+}
+
+// InterventionType returns the type of the intervention. Interventions
+// recorded before types were introduced were all preventive visits.
+func (i *Intervention) InterventionType() InterventionType {
+	if i.Type == nil {
+		return InterventionTypePreventive
+	}
+	return *i.Type
+}
+
+// Sections returns the sections of the form and report that apply to the
+// intervention
+func (i *Intervention) Sections() InterventionSections {
+	return i.InterventionType().Sections()
+}
+
+// Duration returns the time spent on site, when both the arrival and the
+// departure were recorded
+func (i *Intervention) Duration() (time.Duration, bool) {
+	if i.ArrivedAt == nil || i.DepartedAt == nil {
+		return 0, false
+	}
+	return i.DepartedAt.Sub(*i.ArrivedAt), true
+}
+
+// Checklist returns the items of the checklist version the intervention was
+// filled in against
+func (i *Intervention) Checklist() ChecklistItems {
+	if i.ChecklistVersion == nil {
+		return nil
+	}
+	return i.ChecklistVersion.Items
+}
+
 func (Control) TableName() string {
 	return "controls"
 }
+
+// MeasurementPoint is a value measured for a checklist item during an intervention
+type MeasurementPoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// Control returns the control recorded for the checklist item code
+func (i *Intervention) Control(code string) *Control {
+	for j := range i.Controls {
+		if i.Controls[j].Kind == code {
+			return &i.Controls[j]
+		}
+	}
+	return nil
+}
+
+// PreviousMeasurements returns the values measured for a checklist item during
+// the earlier interventions loaded in Portal.Interventions, most recent first
+func (i *Intervention) PreviousMeasurements(code string) []MeasurementPoint {
+	previous := make([]Intervention, 0, len(i.Portal.Interventions))
+	for _, other := range i.Portal.Interventions {
+		if other.ID == i.ID {
+			continue
+		}
+		if other.Date.Before(i.Date) || (other.Date.Equal(i.Date) && other.ID < i.ID) {
+			previous = append(previous, other)
+		}
+	}
+	sort.SliceStable(previous, func(a, b int) bool {
+		if previous[a].Date.Equal(previous[b].Date) {
+			return previous[a].ID > previous[b].ID
+		}
+		return previous[a].Date.After(previous[b].Date)
+	})
+
+	var points []MeasurementPoint
+	for _, other := range previous {
+		if control := other.Control(code); control != nil && control.MeasuredValue != nil {
+			points = append(points, MeasurementPoint{Date: other.Date, Value: *control.MeasuredValue})
+		}
+	}
+	return points
+}
+
+// ControlStats counts the controls of an intervention by outcome
+type ControlStats struct {
+	Compliant     int
+	NonCompliant  int
+	NotChecked    int
+	NotApplicable int
+}
+
+// Applicable returns the number of controls that apply to the equipment
+func (s ControlStats) Applicable() int {
+	return s.Compliant + s.NonCompliant + s.NotChecked
+}
+
+// ComplianceRate returns the share of checked controls that are compliant, in
+// percent. Not applicable and not checked controls are left out.
+func (s ControlStats) ComplianceRate() int {
+	checked := s.Compliant + s.NonCompliant
+	if checked == 0 {
+		return 0
+	}
+	return s.Compliant * 100 / checked
+}
+
+// ControlStats counts the controls of the intervention by outcome. Checklist
+// items without a recorded control count as not checked.
+func (i *Intervention) ControlStats() ControlStats {
+	var stats ControlStats
+	counted := make(map[string]bool, len(i.Controls))
+	for _, control := range i.Controls {
+		counted[control.Kind] = true
+		switch control.Outcome {
+		case ControlOutcomeCompliant:
+			stats.Compliant++
+		case ControlOutcomeNonCompliant:
+			stats.NonCompliant++
+		case ControlOutcomeNotApplicable:
+			stats.NotApplicable++
+		default:
+			stats.NotChecked++
+		}
+	}
+	for _, item := range i.Checklist() {
+		if !counted[item.Code] {
+			stats.NotChecked++
+		}
+	}
+	return stats
+}
+
+// PhotosCount returns the number of photos attached to the controls of the
+// intervention
+func (i *Intervention) PhotosCount() int {
+	count := 0
+	for _, control := range i.Controls {
+		count += len(control.Photos)
+	}
+	return count
+}
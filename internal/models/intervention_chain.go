@@ -0,0 +1,62 @@
+package models
+
+// ChainBreakReason tells why an intervention breaks the hash chain of its
+// portal
+type ChainBreakReason string
+
+const (
+	// ChainBreakGap means the chain index does not follow the previous one,
+	// a sealed intervention was removed
+	ChainBreakGap ChainBreakReason = "gap"
+	// ChainBreakLink means the previous hash does not match the hash of the
+	// previous intervention
+	ChainBreakLink ChainBreakReason = "link"
+	// ChainBreakContent means the intervention or its controls were edited
+	// after being sealed
+	ChainBreakContent ChainBreakReason = "content"
+	// ChainBreakDeleted means the intervention was deleted after being sealed
+	ChainBreakDeleted ChainBreakReason = "deleted"
+	// ChainBreakAmendment means a recorded amendment of the intervention was
+	// altered or removed
+	ChainBreakAmendment ChainBreakReason = "amendment"
+)
+
+// Description returns a human readable description of the reason
+func (r ChainBreakReason) Description() string {
+	switch r {
+	case ChainBreakGap:
+		return "an intervention is missing before this one"
+	case ChainBreakLink:
+		return "previous hash does not match the previous intervention"
+	case ChainBreakContent:
+		return "content was modified after sealing"
+	case ChainBreakDeleted:
+		return "intervention was deleted"
+	case ChainBreakAmendment:
+		return "amendment history was altered"
+	}
+	return string(r)
+}
+
+// ChainBreak is an inconsistency found while walking a portal chain
+type ChainBreak struct {
+	InterventionID uint
+	ChainIndex     int
+	Reason         ChainBreakReason
+}
+
+// ChainReport is the result of the verification of a portal chain
+type ChainReport struct {
+	PortalID uint
+	// Length is the number of sealed interventions, deleted ones included
+	Length int
+	// Unsealed is the number of validated interventions outside of the chain,
+	// recorded before the chain was introduced
+	Unsealed int
+	Breaks   []ChainBreak
+}
+
+// Valid reports whether the chain has no break
+func (r *ChainReport) Valid() bool {
+	return len(r.Breaks) == 0
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInterventionReportImmutable is returned when updating or deleting a
+// stored report revision
+var ErrInterventionReportImmutable = errors.New("intervention reports are immutable, generate a new revision instead")
+
+// InterventionReport is a generated PDF report of an intervention. Each
+// generation creates a new revision; stored revisions are never modified so
+// that a report sent to a customer can always be retrieved as sent.
+type InterventionReport struct {
+	ID             uint `json:"id" gorm:"primaryKey"`
+	InterventionID uint `json:"intervention_id" gorm:"not null;uniqueIndex:idx_intervention_reports_intervention_revision"`
+	Revision       int  `json:"revision" gorm:"not null;uniqueIndex:idx_intervention_reports_intervention_revision"`
+	// InterventionRevision is the revision of the intervention content the
+	// report was generated from, printed on amended reports
+	InterventionRevision int    `json:"intervention_revision" gorm:"not null;default:1"`
+	Key                  string `json:"-" gorm:"not null"`
+	SHA256               string `json:"sha256" gorm:"type:char(64);not null"`
+	Size                 int64  `json:"size" gorm:"not null"`
+	// VerificationCode is printed on the PDF and identifies the revision on
+	// the public verification page. Revisions generated before verification
+	// was introduced have none.
+	VerificationCode *string   `json:"-" gorm:"size:10;uniqueIndex"`
+	CreatedAt        time.Time `json:"created_at"`
+
+	// Relationships
+	Intervention Intervention `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+}
+
+func (InterventionReport) TableName() string {
+	return "intervention_reports"
+}
+
+// BeforeUpdate prevents modifying a stored revision
+func (r *InterventionReport) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInterventionReportImmutable
+}
+
+// BeforeDelete prevents removing a stored revision
+func (r *InterventionReport) BeforeDelete(tx *gorm.DB) error {
+	return ErrInterventionReportImmutable
+}
+
+// FileName returns the name the report is downloaded as, based on the report
+// number of the loaded Intervention
+func (r *InterventionReport) FileName() string {
+	reference := strconv.Itoa(int(r.InterventionID))
+	if r.Intervention.ReportNumber != nil {
+		reference = *r.Intervention.ReportNumber
+	}
+	return "rapport_intervention_" + reference + "_r" + strconv.Itoa(r.Revision) + ".pdf"
+}
+
+// LatestReport returns the most recent revision among the loaded reports of
+// the intervention, or nil when none was generated yet
+func (i *Intervention) LatestReport() *InterventionReport {
+	var latest *InterventionReport
+	for index := range i.Reports {
+		if latest == nil || i.Reports[index].Revision > latest.Revision {
+			latest = &i.Reports[index]
+		}
+	}
+	return latest
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervention_LatestReport(t *testing.T) {
+	intervention := Intervention{}
+	assert.Nil(t, intervention.LatestReport())
+
+	intervention.Reports = []InterventionReport{
+		{InterventionID: 4, Revision: 1},
+		{InterventionID: 4, Revision: 3},
+		{InterventionID: 4, Revision: 2},
+	}
+	latest := intervention.LatestReport()
+	assert.Equal(t, 3, latest.Revision)
+	assert.Equal(t, "rapport_intervention_4_r3.pdf", latest.FileName())
+}
+
+func TestInterventionReport_Immutable(t *testing.T) {
+	report := &InterventionReport{ID: 1}
+	assert.ErrorIs(t, report.BeforeUpdate(nil), ErrInterventionReportImmutable)
+	assert.ErrorIs(t, report.BeforeDelete(nil), ErrInterventionReportImmutable)
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterventionStatus is the step of the validation workflow an intervention
+// is at. Interventions are only final, numbered and sent to the customer
+// once validated by a supervisor.
+type InterventionStatus string
+
+const (
+	InterventionStatusDraft     InterventionStatus = "draft"
+	InterventionStatusSubmitted InterventionStatus = "submitted"
+	InterventionStatusValidated InterventionStatus = "validated"
+	InterventionStatusRejected  InterventionStatus = "rejected"
+)
+
+// InterventionStatuses lists the intervention statuses in workflow order
+var InterventionStatuses = []InterventionStatus{
+	InterventionStatusDraft,
+	InterventionStatusSubmitted,
+	InterventionStatusValidated,
+	InterventionStatusRejected,
+}
+
+// interventionTransitions lists the statuses each status can move to
+var interventionTransitions = map[InterventionStatus][]InterventionStatus{
+	InterventionStatusDraft:     {InterventionStatusSubmitted},
+	InterventionStatusRejected:  {InterventionStatusSubmitted},
+	InterventionStatusSubmitted: {InterventionStatusValidated, InterventionStatusRejected},
+}
+
+func (s InterventionStatus) IsValid() bool {
+	for _, status := range InterventionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the status
+func (s InterventionStatus) Label() string {
+	switch s {
+	case InterventionStatusDraft:
+		return "Brouillon"
+	case InterventionStatusSubmitted:
+		return "En attente de validation"
+	case InterventionStatusValidated:
+		return "Validée"
+	case InterventionStatusRejected:
+		return "Refusée"
+	}
+	return string(s)
+}
+
+// IsEditable reports whether the technician can still change the
+// intervention
+func (s InterventionStatus) IsEditable() bool {
+	return s == InterventionStatusDraft || s == InterventionStatusRejected
+}
+
+// CanTransitionTo reports whether the workflow allows moving from the status
+// to the next one
+func (s InterventionStatus) CanTransitionTo(next InterventionStatus) bool {
+	for _, status := range interventionTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// InterventionReview is the decision of a supervisor on a submitted
+// intervention. Reviews are never updated, every submission gets its own.
+type InterventionReview struct {
+	ID             uint               `json:"id" gorm:"primaryKey"`
+	InterventionID uint               `json:"intervention_id" gorm:"not null;index"`
+	Decision       InterventionStatus `json:"decision" gorm:"type:varchar(20);not null"`
+	Comment        *string            `json:"comment" gorm:"type:text"`
+	ReviewerID     uint               `json:"reviewer_id" gorm:"not null"`
+	ReviewerName   string             `json:"reviewer_name" gorm:"not null"`
+	CreatedAt      time.Time          `json:"created_at"`
+
+	// Relationships
+	Intervention Intervention `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+	Reviewer     User         `json:"reviewer,omitempty" gorm:"foreignKey:ReviewerID"`
+}
+
+func (InterventionReview) TableName() string {
+	return "intervention_reviews"
+}
+
+// ErrInterventionReviewImmutable is returned when updating or deleting a
+// recorded review
+var ErrInterventionReviewImmutable = errors.New("intervention reviews are immutable")
+
+// BeforeUpdate prevents modifying a review once recorded
+func (r *InterventionReview) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInterventionReviewImmutable
+}
+
+// BeforeDelete prevents removing a review once recorded
+func (r *InterventionReview) BeforeDelete(tx *gorm.DB) error {
+	return ErrInterventionReviewImmutable
+}
+
+// LatestReview returns the most recent of the loaded reviews, or nil when the
+// intervention was never reviewed
+func (i *Intervention) LatestReview() *InterventionReview {
+	var latest *InterventionReview
+	for index := range i.Reviews {
+		review := &i.Reviews[index]
+		if latest == nil || review.CreatedAt.After(latest.CreatedAt) || (review.CreatedAt.Equal(latest.CreatedAt) && review.ID > latest.ID) {
+			latest = review
+		}
+	}
+	return latest
+}
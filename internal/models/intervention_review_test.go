@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterventionStatus_Transitions(t *testing.T) {
+	assert.True(t, InterventionStatusDraft.CanTransitionTo(InterventionStatusSubmitted))
+	assert.True(t, InterventionStatusRejected.CanTransitionTo(InterventionStatusSubmitted))
+	assert.True(t, InterventionStatusSubmitted.CanTransitionTo(InterventionStatusValidated))
+	assert.True(t, InterventionStatusSubmitted.CanTransitionTo(InterventionStatusRejected))
+
+	assert.False(t, InterventionStatusDraft.CanTransitionTo(InterventionStatusValidated))
+	assert.False(t, InterventionStatusValidated.CanTransitionTo(InterventionStatusRejected))
+	assert.False(t, InterventionStatusValidated.CanTransitionTo(InterventionStatusSubmitted))
+
+	assert.True(t, InterventionStatusDraft.IsEditable())
+	assert.True(t, InterventionStatusRejected.IsEditable())
+	assert.False(t, InterventionStatusSubmitted.IsEditable())
+	assert.False(t, InterventionStatusValidated.IsEditable())
+
+	for _, status := range InterventionStatuses {
+		assert.True(t, status.IsValid())
+		assert.NotEqual(t, string(status), status.Label())
+	}
+	assert.False(t, InterventionStatus("archived").IsValid())
+}
+
+func TestIntervention_LatestReview(t *testing.T) {
+	assert.Nil(t, (&Intervention{}).LatestReview())
+
+	day := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+	intervention := Intervention{Reviews: []InterventionReview{
+		{ID: 3, Decision: InterventionStatusValidated, CreatedAt: day.Add(time.Hour)},
+		{ID: 1, Decision: InterventionStatusRejected, CreatedAt: day},
+		{ID: 2, Decision: InterventionStatusRejected, CreatedAt: day.Add(time.Hour)},
+	}}
+	assert.Equal(t, uint(3), intervention.LatestReview().ID)
+}
+
+func TestUser_IsSupervisor(t *testing.T) {
+	assert.True(t, (&User{Role: UserRoleSupervisor}).IsSupervisor())
+	assert.False(t, (&User{Role: UserRoleTechnician}).IsSupervisor())
+	assert.False(t, (&User{}).IsSupervisor())
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InterventionSnapshot holds the content of an intervention that can be
+// amended once validated. Times are stored as RFC 3339 UTC strings so that
+// snapshots compare and hash the same whatever time zone they are loaded in.
+type InterventionSnapshot struct {
+	Date              string            `json:"date"`
+	ArrivedAt         string            `json:"arrived_at,omitempty"`
+	DepartedAt        string            `json:"departed_at,omitempty"`
+	Summary           *string           `json:"summary,omitempty"`
+	Fault             *string           `json:"fault,omitempty"`
+	WorkDone          *string           `json:"work_done,omitempty"`
+	PortalStatusAfter *PortalStatus     `json:"portal_status_after,omitempty"`
+	Controls          []ControlSnapshot `json:"controls"`
+}
+
+// ControlSnapshot is the amendable content of a control
+type ControlSnapshot struct {
+	Kind          string         `json:"kind"`
+	Outcome       ControlOutcome `json:"outcome"`
+	MeasuredValue *float64       `json:"measured_value,omitempty"`
+	Remark        *string        `json:"remark,omitempty"`
+}
+
+// Snapshot captures the amendable content of the intervention, with its
+// controls ordered by checklist item code
+func (i *Intervention) Snapshot() InterventionSnapshot {
+	snapshot := InterventionSnapshot{
+		Date:              i.Date.UTC().Format("2006-01-02"),
+		ArrivedAt:         snapshotTime(i.ArrivedAt),
+		DepartedAt:        snapshotTime(i.DepartedAt),
+		Summary:           i.Summary,
+		Fault:             i.Fault,
+		WorkDone:          i.WorkDone,
+		PortalStatusAfter: i.PortalStatusAfter,
+		Controls:          []ControlSnapshot{},
+	}
+	for _, control := range i.Controls {
+		snapshot.Controls = append(snapshot.Controls, ControlSnapshot{
+			Kind:          control.Kind,
+			Outcome:       control.Outcome,
+			MeasuredValue: control.MeasuredValue,
+			Remark:        control.Remark,
+		})
+	}
+	sort.Slice(snapshot.Controls, func(a, b int) bool {
+		return snapshot.Controls[a].Kind < snapshot.Controls[b].Kind
+	})
+	return snapshot
+}
+
+// ApplySnapshot overwrites the amendable content of the intervention and of
+// its loaded controls with the snapshot. Controls missing from the snapshot
+// are left untouched.
+func (i *Intervention) ApplySnapshot(snapshot InterventionSnapshot) error {
+	date, err := time.Parse("2006-01-02", snapshot.Date)
+	if err != nil {
+		return err
+	}
+	arrivedAt, err := parseSnapshotTime(snapshot.ArrivedAt)
+	if err != nil {
+		return err
+	}
+	departedAt, err := parseSnapshotTime(snapshot.DepartedAt)
+	if err != nil {
+		return err
+	}
+
+	i.Date = date
+	i.ArrivedAt = arrivedAt
+	i.DepartedAt = departedAt
+	i.Summary = snapshot.Summary
+	i.Fault = snapshot.Fault
+	i.WorkDone = snapshot.WorkDone
+	i.PortalStatusAfter = snapshot.PortalStatusAfter
+	for _, controlSnapshot := range snapshot.Controls {
+		if control := i.Control(controlSnapshot.Kind); control != nil {
+			control.Outcome = controlSnapshot.Outcome
+			control.MeasuredValue = controlSnapshot.MeasuredValue
+			control.Remark = controlSnapshot.Remark
+		}
+	}
+	return nil
+}
+
+// Equal reports whether both snapshots hold the same content
+func (s InterventionSnapshot) Equal(other InterventionSnapshot) bool {
+	a, errA := json.Marshal(s)
+	b, errB := json.Marshal(other)
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+// SnapshotChange is a value changed between two snapshots. Code is the
+// checklist item code for changes to a control.
+type SnapshotChange struct {
+	Field  string
+	Code   string
+	Before string
+	After  string
+}
+
+// Fields of the intervention reported by DiffSnapshots
+const (
+	SnapshotFieldDate              = "date"
+	SnapshotFieldArrivedAt         = "arrived_at"
+	SnapshotFieldDepartedAt        = "departed_at"
+	SnapshotFieldSummary           = "summary"
+	SnapshotFieldFault             = "fault"
+	SnapshotFieldWorkDone          = "work_done"
+	SnapshotFieldPortalStatusAfter = "portal_status_after"
+	SnapshotFieldOutcome           = "outcome"
+	SnapshotFieldMeasuredValue     = "measured_value"
+	SnapshotFieldRemark            = "remark"
+)
+
+// DiffSnapshots lists the values changed from one snapshot to the next, the
+// intervention fields first then the controls by checklist item code
+func DiffSnapshots(before, after InterventionSnapshot) []SnapshotChange {
+	var changes []SnapshotChange
+	add := func(field, code, previous, next string) {
+		if previous != next {
+			changes = append(changes, SnapshotChange{Field: field, Code: code, Before: previous, After: next})
+		}
+	}
+
+	add(SnapshotFieldDate, "", before.Date, after.Date)
+	add(SnapshotFieldArrivedAt, "", before.ArrivedAt, after.ArrivedAt)
+	add(SnapshotFieldDepartedAt, "", before.DepartedAt, after.DepartedAt)
+	add(SnapshotFieldSummary, "", optionalString(before.Summary), optionalString(after.Summary))
+	add(SnapshotFieldFault, "", optionalString(before.Fault), optionalString(after.Fault))
+	add(SnapshotFieldWorkDone, "", optionalString(before.WorkDone), optionalString(after.WorkDone))
+	add(SnapshotFieldPortalStatusAfter, "", optionalPortalStatus(before.PortalStatusAfter), optionalPortalStatus(after.PortalStatusAfter))
+
+	previousControls := make(map[string]ControlSnapshot, len(before.Controls))
+	for _, control := range before.Controls {
+		previousControls[control.Kind] = control
+	}
+	for _, control := range after.Controls {
+		previous := previousControls[control.Kind]
+		add(SnapshotFieldOutcome, control.Kind, string(previous.Outcome), string(control.Outcome))
+		add(SnapshotFieldMeasuredValue, control.Kind, optionalFloat(previous.MeasuredValue), optionalFloat(control.MeasuredValue))
+		add(SnapshotFieldRemark, control.Kind, optionalString(previous.Remark), optionalString(control.Remark))
+	}
+	return changes
+}
+
+// InterventionRevision is an amendment of a validated intervention. The
+// original content is revision 1, each amendment creates the next revision
+// with the content before and after the change. Revisions are linked by
+// hashes starting from the chain hash of the intervention, so that the
+// content sealed at validation can be restored and verified.
+type InterventionRevision struct {
+	ID             uint                 `json:"id" gorm:"primaryKey"`
+	InterventionID uint                 `json:"intervention_id" gorm:"not null;uniqueIndex:idx_intervention_revisions_intervention_number"`
+	Number         int                  `json:"number" gorm:"not null;uniqueIndex:idx_intervention_revisions_intervention_number"`
+	Reason         string               `json:"reason" gorm:"type:text;not null"`
+	AuthorID       uint                 `json:"author_id" gorm:"not null"`
+	AuthorName     string               `json:"author_name" gorm:"not null"`
+	Before         InterventionSnapshot `json:"before" gorm:"type:jsonb;serializer:json;not null"`
+	After          InterventionSnapshot `json:"after" gorm:"type:jsonb;serializer:json;not null"`
+	PreviousHash   *string              `json:"previous_hash" gorm:"type:char(64)"`
+	Hash           string               `json:"hash" gorm:"type:char(64);not null"`
+	CreatedAt      time.Time            `json:"created_at"`
+
+	// Relationships
+	Intervention Intervention `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+	Author       User         `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+func (InterventionRevision) TableName() string {
+	return "intervention_revisions"
+}
+
+// ErrInterventionRevisionImmutable is returned when updating or deleting a
+// recorded revision
+var ErrInterventionRevisionImmutable = errors.New("intervention revisions are immutable")
+
+// BeforeUpdate prevents modifying a revision once recorded
+func (r *InterventionRevision) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInterventionRevisionImmutable
+}
+
+// BeforeDelete prevents removing a revision once recorded
+func (r *InterventionRevision) BeforeDelete(tx *gorm.DB) error {
+	return ErrInterventionRevisionImmutable
+}
+
+// Changes lists the values changed by the amendment
+func (r *InterventionRevision) Changes() []SnapshotChange {
+	return DiffSnapshots(r.Before, r.After)
+}
+
+// CurrentRevision returns the revision number of the content of the
+// intervention, 1 until it is amended
+func (i *Intervention) CurrentRevision() int {
+	if i.Revision < 1 {
+		return 1
+	}
+	return i.Revision
+}
+
+// OrderedRevisions returns the loaded revisions of the intervention, oldest
+// first
+func (i *Intervention) OrderedRevisions() []InterventionRevision {
+	revisions := append([]InterventionRevision(nil), i.Revisions...)
+	sort.Slice(revisions, func(a, b int) bool {
+		return revisions[a].Number < revisions[b].Number
+	})
+	return revisions
+}
+
+func snapshotTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseSnapshotTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
+
+func optionalPortalStatus(value *PortalStatus) string {
+	if value == nil {
+		return ""
+	}
+	return string(*value)
+}
+
+func optionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
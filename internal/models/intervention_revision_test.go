@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervention_Snapshot(t *testing.T) {
+	arrivedAt := time.Date(2025, 3, 10, 9, 30, 0, 0, time.FixedZone("UTC+1", 60*60))
+	intervention := Intervention{
+		Date:      time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		ArrivedAt: &arrivedAt,
+		Controls: []Control{
+			{Kind: "safety_cells", Outcome: ControlOutcomeCompliant},
+			{Kind: "emergency_stop", Outcome: ControlOutcomeNonCompliant},
+		},
+	}
+
+	snapshot := intervention.Snapshot()
+	assert.Equal(t, "2025-03-10", snapshot.Date)
+	assert.Equal(t, "2025-03-10T08:30:00Z", snapshot.ArrivedAt)
+	assert.Empty(t, snapshot.DepartedAt)
+	require.Len(t, snapshot.Controls, 2)
+	assert.Equal(t, "emergency_stop", snapshot.Controls[0].Kind)
+
+	// Loading order and time zone do not change the snapshot
+	reloaded := intervention
+	localArrival := arrivedAt.UTC()
+	reloaded.ArrivedAt = &localArrival
+	reloaded.Controls = []Control{intervention.Controls[1], intervention.Controls[0]}
+	assert.True(t, snapshot.Equal(reloaded.Snapshot()))
+}
+
+func TestIntervention_ApplySnapshot(t *testing.T) {
+	value := 150.0
+	intervention := Intervention{
+		Date:     time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		Controls: []Control{{Kind: "force_limiter", Outcome: ControlOutcomeNonCompliant}},
+	}
+	original := intervention.Snapshot()
+
+	summary := "Réglage du limiteur"
+	amended := original
+	amended.Date = "2025-03-11"
+	amended.Summary = &summary
+	amended.Controls = []ControlSnapshot{
+		{Kind: "force_limiter", Outcome: ControlOutcomeCompliant, MeasuredValue: &value},
+		{Kind: "unknown", Outcome: ControlOutcomeCompliant},
+	}
+
+	require.NoError(t, intervention.ApplySnapshot(amended))
+	assert.Equal(t, time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), intervention.Date)
+	assert.Equal(t, &summary, intervention.Summary)
+	assert.Equal(t, ControlOutcomeCompliant, intervention.Controls[0].Outcome)
+	assert.Equal(t, &value, intervention.Controls[0].MeasuredValue)
+	assert.Len(t, intervention.Controls, 1)
+
+	require.NoError(t, intervention.ApplySnapshot(original))
+	assert.True(t, original.Equal(intervention.Snapshot()))
+
+	assert.Error(t, intervention.ApplySnapshot(InterventionSnapshot{Date: "10/03/2025"}))
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	value := 150.0
+	remark := "Cellule encrassée"
+	status := PortalStatusInService
+	before := InterventionSnapshot{
+		Date: "2025-03-10",
+		Controls: []ControlSnapshot{
+			{Kind: "force_limiter", Outcome: ControlOutcomeNotChecked},
+			{Kind: "safety_cells", Outcome: ControlOutcomeCompliant},
+		},
+	}
+	after := InterventionSnapshot{
+		Date:              "2025-03-10",
+		PortalStatusAfter: &status,
+		Controls: []ControlSnapshot{
+			{Kind: "force_limiter", Outcome: ControlOutcomeCompliant, MeasuredValue: &value},
+			{Kind: "safety_cells", Outcome: ControlOutcomeCompliant, Remark: &remark},
+		},
+	}
+
+	assert.Equal(t, []SnapshotChange{
+		{Field: SnapshotFieldPortalStatusAfter, Before: "", After: "in_service"},
+		{Field: SnapshotFieldOutcome, Code: "force_limiter", Before: "not_checked", After: "compliant"},
+		{Field: SnapshotFieldMeasuredValue, Code: "force_limiter", Before: "", After: "150"},
+		{Field: SnapshotFieldRemark, Code: "safety_cells", Before: "", After: remark},
+	}, DiffSnapshots(before, after))
+	assert.Empty(t, DiffSnapshots(after, after))
+}
+
+func TestIntervention_CurrentRevision(t *testing.T) {
+	assert.Equal(t, 1, (&Intervention{}).CurrentRevision())
+
+	intervention := Intervention{Revision: 3, Revisions: []InterventionRevision{{Number: 3}, {Number: 2}}}
+	assert.Equal(t, 3, intervention.CurrentRevision())
+	revisions := intervention.OrderedRevisions()
+	assert.Equal(t, 2, revisions[0].Number)
+	assert.Equal(t, 3, revisions[1].Number)
+	assert.Equal(t, 3, intervention.Revisions[0].Number)
+}
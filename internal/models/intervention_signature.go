@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInterventionSignatureImmutable is returned when updating or deleting a
+// signature
+var ErrInterventionSignatureImmutable = errors.New("intervention signatures are immutable")
+
+// SignatureParty is the side of the intervention a signature was given by
+type SignatureParty string
+
+const (
+	// SignaturePartyCustomer is the on-site contact countersigning the visit
+	SignaturePartyCustomer SignatureParty = "customer"
+	// SignaturePartyTechnician is the technician who performed the visit
+	SignaturePartyTechnician SignatureParty = "technician"
+)
+
+// SignatureParties lists the signature parties in display order
+var SignatureParties = []SignatureParty{SignaturePartyCustomer, SignaturePartyTechnician}
+
+func (p SignatureParty) IsValid() bool {
+	for _, party := range SignatureParties {
+		if p == party {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the party
+func (p SignatureParty) Label() string {
+	switch p {
+	case SignaturePartyCustomer:
+		return "Client"
+	case SignaturePartyTechnician:
+		return "Technicien"
+	}
+	return string(p)
+}
+
+// InterventionSignature is a handwritten signature captured on site at the
+// end of an intervention. The image is kept in the storage under Key.
+type InterventionSignature struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	InterventionID uint           `json:"intervention_id" gorm:"not null;uniqueIndex:idx_intervention_signatures_intervention_party"`
+	Party          SignatureParty `json:"party" gorm:"type:varchar(20);not null;uniqueIndex:idx_intervention_signatures_intervention_party"`
+	SignerName     string         `json:"signer_name" gorm:"not null"`
+	SignerRole     string         `json:"signer_role"`
+	ContentType    string         `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size           int64          `json:"size" gorm:"not null"`
+	Key            string         `json:"-" gorm:"not null"`
+	SHA256         string         `json:"sha256" gorm:"type:char(64);not null"`
+	SignedAt       time.Time      `json:"signed_at" gorm:"not null"`
+	CreatedAt      time.Time      `json:"created_at"`
+
+	// Relationships
+	Intervention Intervention `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+}
+
+func (InterventionSignature) TableName() string {
+	return "intervention_signatures"
+}
+
+// BeforeUpdate prevents modifying a signature once captured
+func (s *InterventionSignature) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInterventionSignatureImmutable
+}
+
+// BeforeDelete prevents removing a signature once captured
+func (s *InterventionSignature) BeforeDelete(tx *gorm.DB) error {
+	return ErrInterventionSignatureImmutable
+}
+
+// Signature returns the loaded signature given by the party, or nil when the
+// party did not sign
+func (i *Intervention) Signature(party SignatureParty) *InterventionSignature {
+	for index := range i.Signatures {
+		if i.Signatures[index].Party == party {
+			return &i.Signatures[index]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervention_PreviousMeasurements(t *testing.T) {
+	value := func(v float64) *float64 { return &v }
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	current := Intervention{ID: 3, Date: day(20)}
+	current.Portal.Interventions = []Intervention{
+		{ID: 1, Date: day(1), Controls: []Control{{Kind: "force_limiter", MeasuredValue: value(310)}}},
+		{ID: 2, Date: day(10), Controls: []Control{{Kind: "force_limiter", MeasuredValue: value(320)}}},
+		{ID: 3, Date: day(20), Controls: []Control{{Kind: "force_limiter", MeasuredValue: value(330)}}},
+		{ID: 4, Date: day(25), Controls: []Control{{Kind: "force_limiter", MeasuredValue: value(340)}}},
+		{ID: 5, Date: day(5), Controls: []Control{{Kind: "safety_cells"}}},
+	}
+
+	points := current.PreviousMeasurements("force_limiter")
+	assert.Equal(t, []MeasurementPoint{
+		{Date: day(10), Value: 320},
+		{Date: day(1), Value: 310},
+	}, points)
+}
+
+func TestIntervention_ControlStats(t *testing.T) {
+	intervention := Intervention{
+		ChecklistVersion: &ChecklistVersion{Items: ChecklistItems{
+			{Code: "a"}, {Code: "b"}, {Code: "c"}, {Code: "d"}, {Code: "e"}, {Code: "f"},
+		}},
+		Controls: []Control{
+			{Kind: "a", Outcome: ControlOutcomeCompliant},
+			{Kind: "b", Outcome: ControlOutcomeCompliant},
+			{Kind: "c", Outcome: ControlOutcomeCompliant},
+			{Kind: "d", Outcome: ControlOutcomeNonCompliant},
+			{Kind: "e", Outcome: ControlOutcomeNotApplicable},
+		},
+	}
+
+	stats := intervention.ControlStats()
+	assert.Equal(t, ControlStats{Compliant: 3, NonCompliant: 1, NotChecked: 1, NotApplicable: 1}, stats)
+	assert.Equal(t, 5, stats.Applicable())
+	assert.Equal(t, 75, stats.ComplianceRate())
+	assert.Equal(t, 0, ControlStats{NotApplicable: 2}.ComplianceRate())
+}
+
+func TestIntervention_ReportReference(t *testing.T) {
+	number := "2025-000042"
+
+	assert.Equal(t, "2025-000042", (&Intervention{ID: 7, ReportNumber: &number}).ReportReference())
+	assert.Equal(t, "#7", (&Intervention{ID: 7}).ReportReference())
+}
+
+func TestIntervention_InterventionType(t *testing.T) {
+	legacy := Intervention{}
+	assert.Equal(t, InterventionTypePreventive, legacy.InterventionType())
+	assert.True(t, legacy.Sections().Checklist)
+
+	emergency := InterventionTypeEmergency
+	intervention := Intervention{Type: &emergency}
+	assert.Equal(t, InterventionSections{Fault: true, Work: true}, intervention.Sections())
+
+	for _, interventionType := range InterventionTypes {
+		assert.True(t, interventionType.IsValid())
+		assert.NotEqual(t, string(interventionType), interventionType.Label())
+	}
+	assert.False(t, InterventionType("inspection").IsValid())
+}
+
+func TestIntervention_Duration(t *testing.T) {
+	arrivedAt := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	departedAt := arrivedAt.Add(95 * time.Minute)
+
+	_, ok := (&Intervention{ArrivedAt: &arrivedAt}).Duration()
+	assert.False(t, ok)
+
+	duration, ok := (&Intervention{ArrivedAt: &arrivedAt, DepartedAt: &departedAt}).Duration()
+	assert.True(t, ok)
+	assert.Equal(t, 95*time.Minute, duration)
+}
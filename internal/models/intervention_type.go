@@ -0,0 +1,74 @@
+package models
+
+type InterventionType string
+
+const (
+	InterventionTypePreventive      InterventionType = "preventive"
+	InterventionTypeCorrective      InterventionType = "corrective"
+	InterventionTypeEmergency       InterventionType = "emergency"
+	InterventionTypeInstallation    InterventionType = "installation"
+	InterventionTypeDecommissioning InterventionType = "decommissioning"
+)
+
+// InterventionTypes lists the intervention types in display order
+var InterventionTypes = []InterventionType{
+	InterventionTypePreventive,
+	InterventionTypeCorrective,
+	InterventionTypeEmergency,
+	InterventionTypeInstallation,
+	InterventionTypeDecommissioning,
+}
+
+func (t InterventionType) IsValid() bool {
+	for _, interventionType := range InterventionTypes {
+		if t == interventionType {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the intervention type
+func (t InterventionType) Label() string {
+	switch t {
+	case InterventionTypePreventive:
+		return "Visite préventive"
+	case InterventionTypeCorrective:
+		return "Réparation"
+	case InterventionTypeEmergency:
+		return "Dépannage d'urgence"
+	case InterventionTypeInstallation:
+		return "Installation"
+	case InterventionTypeDecommissioning:
+		return "Mise hors service définitive"
+	}
+	return string(t)
+}
+
+// InterventionSections tells which parts of the intervention form and report
+// apply to an intervention type
+type InterventionSections struct {
+	// Checklist is the controls table and measures of the equipment type
+	Checklist bool
+	// Fault is the description of the fault reported or found on arrival
+	Fault bool
+	// Work is the description of the work carried out
+	Work bool
+}
+
+// Sections returns the sections that apply to the intervention type
+func (t InterventionType) Sections() InterventionSections {
+	switch t {
+	case InterventionTypePreventive:
+		return InterventionSections{Checklist: true}
+	case InterventionTypeCorrective:
+		return InterventionSections{Checklist: true, Fault: true, Work: true}
+	case InterventionTypeEmergency:
+		return InterventionSections{Fault: true, Work: true}
+	case InterventionTypeInstallation:
+		return InterventionSections{Checklist: true, Work: true}
+	case InterventionTypeDecommissioning:
+		return InterventionSections{Work: true}
+	}
+	return InterventionSections{Checklist: true}
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	// JobStatusPending jobs wait for their run time, including failed jobs
+	// scheduled for a retry
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	// JobStatusDead jobs failed their last attempt and are only retried by
+	// hand
+	JobStatusDead JobStatus = "dead"
+)
+
+// JobStatuses lists the job statuses in display order
+var JobStatuses = []JobStatus{JobStatusPending, JobStatusRunning, JobStatusSucceeded, JobStatusDead}
+
+func (s JobStatus) IsValid() bool {
+	for _, status := range JobStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the job status
+func (s JobStatus) Label() string {
+	switch s {
+	case JobStatusPending:
+		return "En attente"
+	case JobStatusRunning:
+		return "En cours"
+	case JobStatusSucceeded:
+		return "Terminé"
+	case JobStatusDead:
+		return "En échec"
+	}
+	return string(s)
+}
+
+// Job is a unit of background work stored in the database so it survives
+// restarts. Workers claim pending jobs whose run time has come.
+type Job struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Type        string     `json:"type" gorm:"type:varchar(50);not null;index"`
+	Payload     string     `json:"payload" gorm:"type:jsonb;not null"`
+	Status      JobStatus  `json:"status" gorm:"type:varchar(20);not null;default:pending;index:idx_jobs_ready,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_jobs_ready,priority:2"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null"`
+	LastError   *string    `json:"last_error" gorm:"type:text"`
+	LockedAt    *time.Time `json:"locked_at"`
+	LockedBy    *string    `json:"locked_by"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// NonConformitySeverity ranks a non-conformity. Failed security controls are
+// major and must be resolved sooner.
+type NonConformitySeverity string
+
+const (
+	NonConformitySeverityMajor NonConformitySeverity = "major"
+	NonConformitySeverityMinor NonConformitySeverity = "minor"
+)
+
+// NonConformitySeverities lists the severities, the most serious first
+var NonConformitySeverities = []NonConformitySeverity{NonConformitySeverityMajor, NonConformitySeverityMinor}
+
+func (s NonConformitySeverity) IsValid() bool {
+	for _, severity := range NonConformitySeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the severity
+func (s NonConformitySeverity) Label() string {
+	switch s {
+	case NonConformitySeverityMajor:
+		return "Majeure"
+	case NonConformitySeverityMinor:
+		return "Mineure"
+	}
+	return string(s)
+}
+
+// ResolutionDelay returns the time given to resolve a non-conformity of the
+// severity, from the date of the intervention that found it
+func (s NonConformitySeverity) ResolutionDelay() time.Duration {
+	if s == NonConformitySeverityMajor {
+		return 15 * 24 * time.Hour
+	}
+	return 90 * 24 * time.Hour
+}
+
+// SeverityForKind returns the severity of a failed control of the given kind
+func SeverityForKind(kind ControlKind) NonConformitySeverity {
+	if kind == ControlKindSecurity {
+		return NonConformitySeverityMajor
+	}
+	return NonConformitySeverityMinor
+}
+
+type NonConformityStatus string
+
+const (
+	NonConformityStatusOpen   NonConformityStatus = "open"
+	NonConformityStatusClosed NonConformityStatus = "closed"
+)
+
+// Label returns the French label of the status
+func (s NonConformityStatus) Label() string {
+	switch s {
+	case NonConformityStatusOpen:
+		return "Ouverte"
+	case NonConformityStatusClosed:
+		return "Levée"
+	}
+	return string(s)
+}
+
+// NonConformityResolution tells how a later intervention closed a
+// non-conformity
+type NonConformityResolution string
+
+const (
+	// NonConformityResolutionCompliant non-conformities were closed by a
+	// later control of the same item found compliant
+	NonConformityResolutionCompliant NonConformityResolution = "compliant"
+	// NonConformityResolutionCorrectiveAction non-conformities were closed by
+	// a corrective action recorded during a later intervention
+	NonConformityResolutionCorrectiveAction NonConformityResolution = "corrective_action"
+)
+
+// Label returns the French label of the resolution
+func (r NonConformityResolution) Label() string {
+	switch r {
+	case NonConformityResolutionCompliant:
+		return "Contrôle conforme"
+	case NonConformityResolutionCorrectiveAction:
+		return "Action corrective"
+	}
+	return string(r)
+}
+
+// NonConformity tracks a control found non-compliant on a portal until a
+// later intervention resolves it. A portal has at most one open
+// non-conformity per checklist item.
+type NonConformity struct {
+	ID                   uint                     `json:"id" gorm:"primaryKey"`
+	PortalID             uint                     `json:"portal_id" gorm:"not null;index;uniqueIndex:idx_non_conformities_open,where:status = 'open'"`
+	Code                 string                   `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_non_conformities_open,where:status = 'open'"`
+	Label                string                   `json:"label" gorm:"not null"`
+	Severity             NonConformitySeverity    `json:"severity" gorm:"type:varchar(20);not null"`
+	Status               NonConformityStatus      `json:"status" gorm:"type:varchar(20);not null;default:open;index"`
+	DueDate              time.Time                `json:"due_date" gorm:"type:date;not null"`
+	AssigneeID           *uint                    `json:"assignee_id" gorm:"index"`
+	OpenedInterventionID uint                     `json:"opened_intervention_id" gorm:"not null;index"`
+	ClosedInterventionID *uint                    `json:"closed_intervention_id" gorm:"index"`
+	Resolution           *NonConformityResolution `json:"resolution" gorm:"type:varchar(30)"`
+	ClosedAt             *time.Time               `json:"closed_at"`
+	CreatedAt            time.Time                `json:"created_at"`
+	UpdatedAt            time.Time                `json:"updated_at"`
+
+	// Relationships
+	Portal             Portal             `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
+	Assignee           *User              `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID"`
+	OpenedIntervention Intervention       `json:"opened_intervention,omitempty" gorm:"foreignKey:OpenedInterventionID"`
+	ClosedIntervention *Intervention      `json:"closed_intervention,omitempty" gorm:"foreignKey:ClosedInterventionID"`
+	CorrectiveActions  []CorrectiveAction `json:"corrective_actions,omitempty" gorm:"foreignKey:NonConformityID"`
+}
+
+func (NonConformity) TableName() string {
+	return "non_conformities"
+}
+
+func (n *NonConformity) IsOpen() bool {
+	return n.Status == NonConformityStatusOpen
+}
+
+// IsOverdue reports whether the non-conformity is still open after its due
+// date
+func (n *NonConformity) IsOverdue(now time.Time) bool {
+	if !n.IsOpen() {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	due := time.Date(n.DueDate.Year(), n.DueDate.Month(), n.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+	return today.After(due)
+}
+
+// CorrectiveAction describes the work done on a non-conformity during an
+// intervention. It closes the non-conformity once the intervention is
+// validated.
+type CorrectiveAction struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	NonConformityID uint      `json:"non_conformity_id" gorm:"not null;uniqueIndex:idx_corrective_actions_non_conformity_intervention"`
+	InterventionID  uint      `json:"intervention_id" gorm:"not null;index;uniqueIndex:idx_corrective_actions_non_conformity_intervention"`
+	Description     string    `json:"description" gorm:"type:text;not null"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Relationships
+	NonConformity NonConformity `json:"non_conformity,omitempty" gorm:"foreignKey:NonConformityID"`
+	Intervention  Intervention  `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+}
+
+func (CorrectiveAction) TableName() string {
+	return "corrective_actions"
+}
+
+// OpenNonConformities returns the loaded non-conformities of the portal that
+// are still open, the most serious first then by due date
+func (p *Portal) OpenNonConformities() []NonConformity {
+	var open []NonConformity
+	for _, nonConformity := range p.NonConformities {
+		if nonConformity.IsOpen() {
+			open = append(open, nonConformity)
+		}
+	}
+	sort.SliceStable(open, func(a, b int) bool {
+		if open[a].Severity != open[b].Severity {
+			return open[a].Severity == NonConformitySeverityMajor
+		}
+		return open[a].DueDate.Before(open[b].DueDate)
+	})
+	return open
+}
+
+// CorrectiveAction returns the corrective action recorded on the
+// non-conformity during the intervention, nil when there is none
+func (i *Intervention) CorrectiveAction(nonConformityID uint) *CorrectiveAction {
+	for j := range i.CorrectiveActions {
+		if i.CorrectiveActions[j].NonConformityID == nonConformityID {
+			return &i.CorrectiveActions[j]
+		}
+	}
+	return nil
+}
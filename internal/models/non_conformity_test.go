@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityForKind(t *testing.T) {
+	assert.Equal(t, NonConformitySeverityMajor, SeverityForKind(ControlKindSecurity))
+	assert.Equal(t, NonConformitySeverityMinor, SeverityForKind(ControlKindOther))
+	assert.Less(t, NonConformitySeverityMajor.ResolutionDelay(), NonConformitySeverityMinor.ResolutionDelay())
+
+	for _, severity := range NonConformitySeverities {
+		assert.True(t, severity.IsValid())
+		assert.NotEqual(t, string(severity), severity.Label())
+	}
+	assert.False(t, NonConformitySeverity("critical").IsValid())
+}
+
+func TestNonConformity_IsOverdue(t *testing.T) {
+	nonConformity := NonConformity{Status: NonConformityStatusOpen, DueDate: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)}
+
+	assert.False(t, nonConformity.IsOverdue(time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, nonConformity.IsOverdue(time.Date(2025, 3, 11, 8, 0, 0, 0, time.UTC)))
+
+	nonConformity.Status = NonConformityStatusClosed
+	assert.False(t, nonConformity.IsOverdue(time.Date(2025, 3, 11, 8, 0, 0, 0, time.UTC)))
+}
+
+func TestPortal_OpenNonConformities(t *testing.T) {
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	portal := Portal{NonConformities: []NonConformity{
+		{ID: 1, Status: NonConformityStatusOpen, Severity: NonConformitySeverityMinor, DueDate: day},
+		{ID: 2, Status: NonConformityStatusClosed, Severity: NonConformitySeverityMajor, DueDate: day},
+		{ID: 3, Status: NonConformityStatusOpen, Severity: NonConformitySeverityMajor, DueDate: day.AddDate(0, 1, 0)},
+		{ID: 4, Status: NonConformityStatusOpen, Severity: NonConformitySeverityMajor, DueDate: day},
+	}}
+
+	var ids []uint
+	for _, nonConformity := range portal.OpenNonConformities() {
+		ids = append(ids, nonConformity.ID)
+	}
+	assert.Equal(t, []uint{4, 3, 1}, ids)
+	assert.Empty(t, (&Portal{}).OpenNonConformities())
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// NotificationEvent is an event notified by email to the recipients of a
+// portal
+type NotificationEvent string
+
+const (
+	NotificationEventInterventionValidated NotificationEvent = "intervention_validated"
+	NotificationEventNonConformityOpened   NotificationEvent = "non_conformity_opened"
+	NotificationEventPortalOutOfService    NotificationEvent = "portal_out_of_service"
+)
+
+// NotificationEvents lists the events recipients can subscribe to, in the
+// order they are shown
+var NotificationEvents = []NotificationEvent{
+	NotificationEventInterventionValidated,
+	NotificationEventNonConformityOpened,
+	NotificationEventPortalOutOfService,
+}
+
+// IsValid reports whether the event is a known one
+func (e NotificationEvent) IsValid() bool {
+	for _, event := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the event
+func (e NotificationEvent) Label() string {
+	switch e {
+	case NotificationEventInterventionValidated:
+		return "Intervention validée"
+	case NotificationEventNonConformityOpened:
+		return "Non-conformité ouverte"
+	case NotificationEventPortalOutOfService:
+		return "Portail hors service"
+	}
+	return string(e)
+}
+
+// RecipientField is the header listing a recipient in the emails
+type RecipientField string
+
+const (
+	RecipientFieldTo  RecipientField = "to"
+	RecipientFieldCc  RecipientField = "cc"
+	RecipientFieldBcc RecipientField = "bcc"
+)
+
+// RecipientFields lists the recipient fields, from the most to the least
+// visible
+var RecipientFields = []RecipientField{RecipientFieldTo, RecipientFieldCc, RecipientFieldBcc}
+
+// IsValid reports whether the field is a known one
+func (f RecipientField) IsValid() bool {
+	return f == RecipientFieldTo || f == RecipientFieldCc || f == RecipientFieldBcc
+}
+
+// Label returns the French label of the field
+func (f RecipientField) Label() string {
+	switch f {
+	case RecipientFieldTo:
+		return "Destinataire"
+	case RecipientFieldCc:
+		return "Copie"
+	case RecipientFieldBcc:
+		return "Copie cachée"
+	}
+	return string(f)
+}
+
+// NotificationRecipient is an email address notified of the events of a
+// portal, or of every portal of a contract when set on the contract of the
+// contractor. Exactly one of PortalID and ContractID is set.
+type NotificationRecipient struct {
+	ID         uint                `json:"id" gorm:"primaryKey"`
+	PortalID   *uint               `json:"portal_id" gorm:"index"`
+	ContractID *uint               `json:"contract_id" gorm:"index"`
+	Name       string              `json:"name"`
+	Email      string              `json:"email" gorm:"not null"`
+	Field      RecipientField      `json:"field" gorm:"type:varchar(3);not null;default:to"`
+	Events     []NotificationEvent `json:"events" gorm:"type:jsonb;serializer:json;not null"`
+	// UnsubscribedAt is set when the recipient unsubscribed from the link of
+	// an email, it is then no longer notified
+	UnsubscribedAt *time.Time `json:"unsubscribed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Portal   *Portal   `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
+	Contract *Contract `json:"contract,omitempty" gorm:"foreignKey:ContractID"`
+}
+
+func (NotificationRecipient) TableName() string {
+	return "notification_recipients"
+}
+
+// HasEvent reports whether the event is one the recipient is set to be
+// notified of, even if it unsubscribed since
+func (r *NotificationRecipient) HasEvent(event NotificationEvent) bool {
+	for _, candidate := range r.Events {
+		if candidate == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribed reports whether the recipient is notified of the event
+func (r *NotificationRecipient) Subscribed(event NotificationEvent) bool {
+	return r.UnsubscribedAt == nil && r.HasEvent(event)
+}
+
+// NotificationRouting holds the addresses an event is emailed to
+type NotificationRouting struct {
+	To  []string
+	Cc  []string
+	Bcc []string
+}
+
+// IsEmpty reports whether nobody is to be notified
+func (r NotificationRouting) IsEmpty() bool {
+	return len(r.To) == 0 && len(r.Cc) == 0 && len(r.Bcc) == 0
+}
+
+// RouteNotification returns who is emailed an event of a portal: the
+// recipients subscribed to it, each address once in its most visible field,
+// and the contact email of the portal in To. The contact is notified of
+// every event unless a recipient with the same address says otherwise, and
+// is the only one notified when event is empty.
+func RouteNotification(event NotificationEvent, contactEmail string, recipients []NotificationRecipient) NotificationRouting {
+	fields := make(map[string]RecipientField)
+	var order []string
+	add := func(address string, field RecipientField) {
+		key := strings.ToLower(strings.TrimSpace(address))
+		if key == "" {
+			return
+		}
+		current, seen := fields[key]
+		if !seen {
+			order = append(order, strings.TrimSpace(address))
+		}
+		if !seen || fieldRank(field) < fieldRank(current) {
+			fields[key] = field
+		}
+	}
+
+	listed := make(map[string]bool, len(recipients))
+	for i := range recipients {
+		recipient := &recipients[i]
+		listed[strings.ToLower(strings.TrimSpace(recipient.Email))] = true
+		if event != "" && recipient.Subscribed(event) {
+			add(recipient.Email, recipient.Field)
+		}
+	}
+	if contact := strings.TrimSpace(contactEmail); contact != "" && !listed[strings.ToLower(contact)] {
+		add(contact, RecipientFieldTo)
+	}
+
+	var routing NotificationRouting
+	for _, address := range order {
+		switch fields[strings.ToLower(address)] {
+		case RecipientFieldTo:
+			routing.To = append(routing.To, address)
+		case RecipientFieldCc:
+			routing.Cc = append(routing.Cc, address)
+		default:
+			routing.Bcc = append(routing.Bcc, address)
+		}
+	}
+	return routing
+}
+
+func fieldRank(field RecipientField) int {
+	for rank, candidate := range RecipientFields {
+		if field == candidate {
+			return rank
+		}
+	}
+	return len(RecipientFields)
+}
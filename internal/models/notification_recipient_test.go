@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteNotification(t *testing.T) {
+	unsubscribedAt := time.Now()
+	recipients := []NotificationRecipient{
+		{Email: "syndic@example.com", Field: RecipientFieldTo, Events: []NotificationEvent{NotificationEventInterventionValidated, NotificationEventPortalOutOfService}},
+		{Email: "gardien@example.com", Field: RecipientFieldCc, Events: []NotificationEvent{NotificationEventPortalOutOfService}},
+		{Email: "archives@example.com", Field: RecipientFieldBcc, Events: []NotificationEvent{NotificationEventInterventionValidated}},
+		{Email: "Syndic@example.com", Field: RecipientFieldBcc, Events: []NotificationEvent{NotificationEventInterventionValidated}},
+		{Email: "ancien@example.com", Field: RecipientFieldTo, Events: []NotificationEvent{NotificationEventInterventionValidated}, UnsubscribedAt: &unsubscribedAt},
+	}
+
+	routing := RouteNotification(NotificationEventInterventionValidated, "contact@example.com", recipients)
+	assert.Equal(t, []string{"syndic@example.com", "contact@example.com"}, routing.To, "an address is listed once, in its most visible field")
+	assert.Empty(t, routing.Cc)
+	assert.Equal(t, []string{"archives@example.com"}, routing.Bcc)
+
+	routing = RouteNotification(NotificationEventPortalOutOfService, "", recipients)
+	assert.Equal(t, []string{"syndic@example.com"}, routing.To)
+	assert.Equal(t, []string{"gardien@example.com"}, routing.Cc)
+
+	routing = RouteNotification(NotificationEventNonConformityOpened, "", recipients)
+	assert.True(t, routing.IsEmpty())
+}
+
+func TestRouteNotification_Contact(t *testing.T) {
+	routing := RouteNotification("", "contact@example.com", []NotificationRecipient{
+		{Email: "syndic@example.com", Field: RecipientFieldTo, Events: NotificationEvents},
+	})
+	assert.Equal(t, []string{"contact@example.com"}, routing.To, "only the contact is told of events nobody subscribes to")
+
+	unsubscribedAt := time.Now()
+	routing = RouteNotification(NotificationEventInterventionValidated, "contact@example.com", []NotificationRecipient{
+		{Email: "CONTACT@example.com", Field: RecipientFieldTo, Events: NotificationEvents, UnsubscribedAt: &unsubscribedAt},
+	})
+	assert.True(t, routing.IsEmpty(), "a listed contact follows its own subscription")
+}
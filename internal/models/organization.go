@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Organization is the company maintaining portals and issuing their reports.
+// Report numbers are sequential per organization and year.
+type Organization struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"not null;unique"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Portals []Portal `json:"portals,omitempty" gorm:"foreignKey:OrganizationID"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
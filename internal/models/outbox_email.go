@@ -0,0 +1,143 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OutboxEmailStatus is the delivery state of an outgoing email
+type OutboxEmailStatus string
+
+const (
+	// OutboxEmailStatusWaiting emails wait for an attachment generated after
+	// the change they report, such as the report PDF, to be delivered
+	OutboxEmailStatusWaiting OutboxEmailStatus = "waiting"
+	// OutboxEmailStatusPending emails wait for their first or next delivery
+	// attempt
+	OutboxEmailStatusPending OutboxEmailStatus = "pending"
+	OutboxEmailStatusSent    OutboxEmailStatus = "sent"
+	// OutboxEmailStatusFailed emails were rejected or ran out of attempts,
+	// they are only sent again by hand
+	OutboxEmailStatusFailed OutboxEmailStatus = "failed"
+)
+
+// Label returns the French label of the delivery status
+func (s OutboxEmailStatus) Label() string {
+	switch s {
+	case OutboxEmailStatusWaiting:
+		return "En attente de la pièce jointe"
+	case OutboxEmailStatusPending:
+		return "En attente d'envoi"
+	case OutboxEmailStatusSent:
+		return "Envoyé"
+	case OutboxEmailStatusFailed:
+		return "En échec"
+	}
+	return string(s)
+}
+
+// OutboxEmailKind tells what an outgoing email is about
+type OutboxEmailKind string
+
+const (
+	OutboxEmailKindInterventionReport  OutboxEmailKind = "intervention_report"
+	OutboxEmailKindPortalStatus        OutboxEmailKind = "portal_status"
+	OutboxEmailKindNonConformity       OutboxEmailKind = "non_conformity"
+	OutboxEmailKindMaintenanceReminder OutboxEmailKind = "maintenance_reminder"
+	OutboxEmailKindWeeklyDigest        OutboxEmailKind = "weekly_digest"
+)
+
+// OutboxAttachment is a stored file attached to an outgoing email
+type OutboxAttachment struct {
+	Key      string `json:"key"`
+	FileName string `json:"file_name"`
+}
+
+// OutboxEmail is an outgoing email. It is written in the transaction of the
+// change it reports, so it is sent if and only if the change is committed,
+// then delivered in the background.
+type OutboxEmail struct {
+	ID   uint            `json:"id" gorm:"primaryKey"`
+	Kind OutboxEmailKind `json:"kind" gorm:"type:varchar(50);not null;index"`
+	// DedupKey, when set, keeps the same email from being queued twice
+	DedupKey *string  `json:"dedup_key" gorm:"type:varchar(255);uniqueIndex"`
+	To       []string `json:"to" gorm:"type:jsonb;serializer:json;not null"`
+	Cc       []string `json:"cc" gorm:"type:jsonb;serializer:json"`
+	Bcc      []string `json:"bcc" gorm:"type:jsonb;serializer:json"`
+	// Recipient, when set, is the only address the email is delivered to,
+	// the others being listed in its headers only. Notifications are sent as
+	// one copy per recipient, each with its own unsubscribe link.
+	Recipient *string `json:"recipient" gorm:"type:varchar(255)"`
+	Subject   string  `json:"subject" gorm:"not null"`
+	Body      string  `json:"body" gorm:"type:text;not null"`
+	// HTMLBody is the HTML version of Body, sent along with it when set
+	HTMLBody       string             `json:"html_body" gorm:"type:text"`
+	Attachments    []OutboxAttachment `json:"attachments" gorm:"type:jsonb;serializer:json"`
+	InterventionID *uint              `json:"intervention_id" gorm:"index"`
+	PortalID       *uint              `json:"portal_id" gorm:"index"`
+	Status         OutboxEmailStatus  `json:"status" gorm:"type:varchar(20);not null;default:pending;index"`
+	Attempts       int                `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts    int                `json:"max_attempts" gorm:"not null"`
+	// MessageID is set on the first attempt and reused by the next ones
+	MessageID    *string    `json:"message_id" gorm:"type:varchar(255)"`
+	SMTPResponse *string    `json:"smtp_response" gorm:"column:smtp_response;type:text"`
+	LastError    *string    `json:"last_error" gorm:"type:text"`
+	SentAt       *time.Time `json:"sent_at"`
+	// UnsubscribeToken identifies the copy of a notification, and so its
+	// Recipient, on the unsubscribe page linked from its body
+	UnsubscribeToken *string   `json:"-" gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	DeliveryAttempts []EmailDeliveryAttempt `json:"delivery_attempts,omitempty" gorm:"foreignKey:EmailID"`
+}
+
+func (OutboxEmail) TableName() string {
+	return "email_outbox"
+}
+
+// Recipients returns the addresses the email is delivered to as a comma
+// separated list
+func (e *OutboxEmail) Recipients() string {
+	if e.Recipient != nil {
+		return *e.Recipient
+	}
+	recipients := append(append(append([]string{}, e.To...), e.Cc...), e.Bcc...)
+	return strings.Join(recipients, ", ")
+}
+
+// Routing returns the recipients of the email by field
+func (e *OutboxEmail) Routing() NotificationRouting {
+	return NotificationRouting{To: e.To, Cc: e.Cc, Bcc: e.Bcc}
+}
+
+// EmailDeliveryAttempt records one attempt to hand an outgoing email to the
+// SMTP server
+type EmailDeliveryAttempt struct {
+	ID      uint `json:"id" gorm:"primaryKey"`
+	EmailID uint `json:"email_id" gorm:"not null;index"`
+	Attempt int  `json:"attempt" gorm:"not null"`
+	Success bool `json:"success" gorm:"not null"`
+	// SMTPResponse is the reply of the server, or its error reply
+	SMTPResponse *string   `json:"smtp_response" gorm:"column:smtp_response;type:text"`
+	Error        *string   `json:"error" gorm:"type:text"`
+	StartedAt    time.Time `json:"started_at" gorm:"not null"`
+	FinishedAt   time.Time `json:"finished_at" gorm:"not null"`
+}
+
+func (EmailDeliveryAttempt) TableName() string {
+	return "email_delivery_attempts"
+}
+
+// ReportEmails returns the emails of the intervention reports, based on the
+// loaded Emails
+func (i *Intervention) ReportEmails() []OutboxEmail {
+	var emails []OutboxEmail
+	for _, email := range i.Emails {
+		if email.Kind == OutboxEmailKindInterventionReport {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
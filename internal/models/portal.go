@@ -18,15 +18,52 @@ type Portal struct {
 	ContactPhone      string         `json:"contact_phone" gorm:"size:20;not null"`
 	ContactEmail      string         `json:"contact_email"`
 	InstallationDate  time.Time      `json:"installation_date" gorm:"not null"`
+	EquipmentTypeID   *uint          `json:"equipment_type_id" gorm:"index"`
+	OrganizationID    *uint          `json:"organization_id" gorm:"index"`
+	ContractID        *uint          `json:"contract_id" gorm:"index"`
+	TechnicianID      *uint          `json:"technician_id" gorm:"index"`
+	RemindersOptOut   bool           `json:"reminders_opt_out" gorm:"not null;default:false"`
+	Status            PortalStatus   `json:"status" gorm:"type:varchar(20);not null;default:in_service;index"`
+	StatusChangedAt   *time.Time     `json:"status_changed_at"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	QRCodes       []QRCode       `json:"qr_codes,omitempty" gorm:"foreignKey:PortalID"`
-	Interventions []Intervention `json:"interventions,omitempty" gorm:"foreignKey:PortalID"`
+	EquipmentType      *EquipmentType            `json:"equipment_type,omitempty" gorm:"foreignKey:EquipmentTypeID"`
+	Organization       *Organization             `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+	Contract           *Contract                 `json:"contract,omitempty" gorm:"foreignKey:ContractID"`
+	Technician         *User                     `json:"technician,omitempty" gorm:"foreignKey:TechnicianID"`
+	QRCodes            []QRCode                  `json:"qr_codes,omitempty" gorm:"foreignKey:PortalID"`
+	Interventions      []Intervention            `json:"interventions,omitempty" gorm:"foreignKey:PortalID"`
+	NotApplicableItems []PortalNotApplicableItem `json:"not_applicable_items,omitempty" gorm:"foreignKey:PortalID"`
+	NonConformities    []NonConformity           `json:"non_conformities,omitempty" gorm:"foreignKey:PortalID"`
+	StatusChanges      []PortalStatusChange      `json:"status_changes,omitempty" gorm:"foreignKey:PortalID"`
+	// NotificationRecipients are the recipients of the portal itself, those
+	// of its contract are on the contract
+	NotificationRecipients []NotificationRecipient `json:"notification_recipients,omitempty" gorm:"foreignKey:PortalID"`
 }
 
 func (Portal) TableName() string {
 	return "portals"
 }
+
+// OperationalStatus returns the status of the portal, in service for portals
+// loaded without it
+func (p *Portal) OperationalStatus() PortalStatus {
+	if p.Status == "" {
+		return PortalStatusInService
+	}
+	return p.Status
+}
+
+// IsNotApplicable reports whether the checklist item was marked as not
+// applicable to the portal, based on the loaded NotApplicableItems
+func (p *Portal) IsNotApplicable(code string) bool {
+	for _, item := range p.NotApplicableItems {
+		if item.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// MaintenanceUrgency groups portals on the maintenance dashboard by how soon
+// their next preventive visit is due
+type MaintenanceUrgency string
+
+const (
+	MaintenanceUrgencyOverdue      MaintenanceUrgency = "overdue"
+	MaintenanceUrgencyDueThisMonth MaintenanceUrgency = "due_this_month"
+	MaintenanceUrgencyUpToDate     MaintenanceUrgency = "up_to_date"
+	// MaintenanceUrgencyUntracked portals are decommissioned and no longer
+	// visited
+	MaintenanceUrgencyUntracked MaintenanceUrgency = "untracked"
+)
+
+// MaintenanceUrgencies lists the urgencies, the most urgent first
+var MaintenanceUrgencies = []MaintenanceUrgency{
+	MaintenanceUrgencyOverdue,
+	MaintenanceUrgencyDueThisMonth,
+	MaintenanceUrgencyUpToDate,
+	MaintenanceUrgencyUntracked,
+}
+
+// Label returns the French label of the urgency
+func (u MaintenanceUrgency) Label() string {
+	switch u {
+	case MaintenanceUrgencyOverdue:
+		return "En retard"
+	case MaintenanceUrgencyDueThisMonth:
+		return "À prévoir ce mois-ci"
+	case MaintenanceUrgencyUpToDate:
+		return "À jour"
+	case MaintenanceUrgencyUntracked:
+		return "Hors suivi"
+	}
+	return string(u)
+}
+
+// PortalMaintenance is the maintenance schedule of a portal as computed by
+// the dashboard query. It is not stored.
+type PortalMaintenance struct {
+	PortalID          uint
+	Name              string
+	AddressStreet     string
+	AddressZipcode    string
+	AddressCity       string
+	ContractorCompany string
+	Status            PortalStatus
+	ContractReference *string
+	VisitsPerYear     int
+	LastVisit         *time.Time
+	DueDate           *time.Time
+	DaysOverdue       *int
+}
+
+// Urgency returns how soon the next visit of the portal is due, relative to
+// the calendar month of now
+func (m *PortalMaintenance) Urgency(now time.Time) MaintenanceUrgency {
+	switch {
+	case m.DueDate == nil:
+		return MaintenanceUrgencyUntracked
+	case m.DaysOverdue != nil && *m.DaysOverdue > 0:
+		return MaintenanceUrgencyOverdue
+	}
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	if startOfDay(*m.DueDate).Before(nextMonth) {
+		return MaintenanceUrgencyDueThisMonth
+	}
+	return MaintenanceUrgencyUpToDate
+}
+
+// MaintenanceDashboard holds the portals grouped by urgency, in the order
+// they were given
+type MaintenanceDashboard map[MaintenanceUrgency][]PortalMaintenance
+
+// NewMaintenanceDashboard groups the portal schedules by urgency
+func NewMaintenanceDashboard(schedules []PortalMaintenance, now time.Time) MaintenanceDashboard {
+	dashboard := make(MaintenanceDashboard, len(MaintenanceUrgencies))
+	for _, schedule := range schedules {
+		urgency := schedule.Urgency(now)
+		dashboard[urgency] = append(dashboard[urgency], schedule)
+	}
+	return dashboard
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleDue(due time.Time, now time.Time) PortalMaintenance {
+	daysOverdue := int(startOfDay(now).Sub(startOfDay(due)).Hours() / 24)
+	return PortalMaintenance{DueDate: &due, DaysOverdue: &daysOverdue}
+}
+
+func TestPortalMaintenance_Urgency(t *testing.T) {
+	now := time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	overdue := scheduleDue(time.Date(2025, 6, 9, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyOverdue, overdue.Urgency(now))
+
+	today := scheduleDue(time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyDueThisMonth, today.Urgency(now))
+
+	endOfMonth := scheduleDue(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyDueThisMonth, endOfMonth.Urgency(now))
+
+	nextMonth := scheduleDue(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyUpToDate, nextMonth.Urgency(now))
+
+	decommissioned := PortalMaintenance{Status: PortalStatusDecommissioned}
+	assert.Equal(t, MaintenanceUrgencyUntracked, decommissioned.Urgency(now))
+}
+
+func TestPortalMaintenance_UrgencyInDecember(t *testing.T) {
+	now := time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)
+
+	endOfYear := scheduleDue(time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyDueThisMonth, endOfYear.Urgency(now))
+
+	nextYear := scheduleDue(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), now)
+	assert.Equal(t, MaintenanceUrgencyUpToDate, nextYear.Urgency(now))
+}
+
+func TestNewMaintenanceDashboard(t *testing.T) {
+	now := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	first := scheduleDue(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), now)
+	first.Name = "A"
+	second := scheduleDue(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), now)
+	second.Name = "B"
+
+	dashboard := NewMaintenanceDashboard([]PortalMaintenance{
+		first,
+		scheduleDue(time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC), now),
+		second,
+		scheduleDue(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), now),
+		{Name: "Désaffecté"},
+	}, now)
+
+	require.Len(t, dashboard[MaintenanceUrgencyOverdue], 2)
+	assert.Equal(t, "A", dashboard[MaintenanceUrgencyOverdue][0].Name)
+	assert.Equal(t, "B", dashboard[MaintenanceUrgencyOverdue][1].Name)
+	assert.Len(t, dashboard[MaintenanceUrgencyDueThisMonth], 1)
+	assert.Len(t, dashboard[MaintenanceUrgencyUpToDate], 1)
+	assert.Len(t, dashboard[MaintenanceUrgencyUntracked], 1)
+}
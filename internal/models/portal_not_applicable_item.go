@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PortalNotApplicableItem remembers that a checklist item does not apply to a
+// portal (e.g. no floor loop) so that later visits default to not applicable
+type PortalNotApplicableItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PortalID  uint      `json:"portal_id" gorm:"not null;uniqueIndex:idx_portal_not_applicable_items_portal_code"`
+	Code      string    `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_portal_not_applicable_items_portal_code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PortalNotApplicableItem) TableName() string {
+	return "portal_not_applicable_items"
+}
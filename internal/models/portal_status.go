@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PortalStatus is the operational status of a portal. Interventions record
+// the status they leave the portal in, which becomes the status of the portal
+// once they are validated.
+type PortalStatus string
+
+const (
+	PortalStatusInService      PortalStatus = "in_service"
+	PortalStatusDegraded       PortalStatus = "degraded"
+	PortalStatusOutOfService   PortalStatus = "out_of_service"
+	PortalStatusDecommissioned PortalStatus = "decommissioned"
+)
+
+// PortalStatuses lists the portal statuses in display order
+var PortalStatuses = []PortalStatus{
+	PortalStatusInService,
+	PortalStatusDegraded,
+	PortalStatusOutOfService,
+	PortalStatusDecommissioned,
+}
+
+// InterventionPortalStatuses lists the statuses a technician can leave the
+// portal in, portals are decommissioned by a decommissioning intervention
+var InterventionPortalStatuses = []PortalStatus{
+	PortalStatusInService,
+	PortalStatusDegraded,
+	PortalStatusOutOfService,
+}
+
+func (s PortalStatus) IsValid() bool {
+	for _, status := range PortalStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the portal status
+func (s PortalStatus) Label() string {
+	switch s {
+	case PortalStatusInService:
+		return "En service"
+	case PortalStatusDegraded:
+		return "Fonctionnement dégradé"
+	case PortalStatusOutOfService:
+		return "Hors service"
+	case PortalStatusDecommissioned:
+		return "Désaffecté"
+	}
+	return string(s)
+}
+
+// IsUnsafe reports whether users must be warned not to use the portal
+func (s PortalStatus) IsUnsafe() bool {
+	return s == PortalStatusOutOfService
+}
+
+// PortalStatusChange records a change of the operational status of a portal,
+// made by a validated intervention or by hand
+type PortalStatusChange struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	PortalID       uint         `json:"portal_id" gorm:"not null;index"`
+	FromStatus     PortalStatus `json:"from_status" gorm:"type:varchar(20);not null"`
+	ToStatus       PortalStatus `json:"to_status" gorm:"type:varchar(20);not null"`
+	Reason         string       `json:"reason" gorm:"type:text;not null"`
+	InterventionID *uint        `json:"intervention_id" gorm:"index"`
+	UserID         *uint        `json:"user_id"`
+	UserName       string       `json:"user_name"`
+	CreatedAt      time.Time    `json:"created_at"`
+
+	// Relationships
+	Portal       Portal        `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
+	Intervention *Intervention `json:"intervention,omitempty" gorm:"foreignKey:InterventionID"`
+	User         *User         `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+func (PortalStatusChange) TableName() string {
+	return "portal_status_changes"
+}
+
+// ErrPortalStatusChangeImmutable is returned when updating or deleting a
+// logged status change
+var ErrPortalStatusChangeImmutable = errors.New("portal status changes are immutable")
+
+// BeforeUpdate prevents modifying a status change once logged
+func (c *PortalStatusChange) BeforeUpdate(tx *gorm.DB) error {
+	return ErrPortalStatusChangeImmutable
+}
+
+// BeforeDelete prevents removing a status change once logged
+func (c *PortalStatusChange) BeforeDelete(tx *gorm.DB) error {
+	return ErrPortalStatusChangeImmutable
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortalStatus_IsValid(t *testing.T) {
+	for _, status := range PortalStatuses {
+		assert.True(t, status.IsValid(), status)
+	}
+	assert.False(t, PortalStatus("").IsValid())
+	assert.False(t, PortalStatus("broken").IsValid())
+}
+
+func TestPortalStatus_Label(t *testing.T) {
+	assert.Equal(t, "En service", PortalStatusInService.Label())
+	assert.Equal(t, "Fonctionnement dégradé", PortalStatusDegraded.Label())
+	assert.Equal(t, "Hors service", PortalStatusOutOfService.Label())
+	assert.Equal(t, "Désaffecté", PortalStatusDecommissioned.Label())
+	assert.Equal(t, "broken", PortalStatus("broken").Label())
+}
+
+func TestPortalStatus_IsUnsafe(t *testing.T) {
+	assert.True(t, PortalStatusOutOfService.IsUnsafe())
+	assert.False(t, PortalStatusInService.IsUnsafe())
+	assert.False(t, PortalStatusDegraded.IsUnsafe())
+	assert.False(t, PortalStatusDecommissioned.IsUnsafe())
+}
+
+func TestInterventionPortalStatuses_ExcludeDecommissioned(t *testing.T) {
+	assert.NotContains(t, InterventionPortalStatuses, PortalStatusDecommissioned)
+}
+
+func TestPortal_OperationalStatus(t *testing.T) {
+	assert.Equal(t, PortalStatusInService, (&Portal{}).OperationalStatus())
+	assert.Equal(t, PortalStatusDegraded, (&Portal{Status: PortalStatusDegraded}).OperationalStatus())
+}
+
+func TestPortalStatusChange_Immutable(t *testing.T) {
+	change := &PortalStatusChange{}
+	assert.ErrorIs(t, change.BeforeUpdate(nil), ErrPortalStatusChangeImmutable)
+	assert.ErrorIs(t, change.BeforeDelete(nil), ErrPortalStatusChangeImmutable)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReminderCatchUpDays is how many days a one-off reminder can still be sent
+// after its day, when the reminders did not run that day
+const ReminderCatchUpDays = 7
+
+// ReminderRule says when the people in charge of a portal are reminded of
+// its next preventive visit, relative to its due date
+type ReminderRule struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"not null"`
+	// OffsetDays is the number of days from the due date the reminder is
+	// sent, negative before it
+	OffsetDays int `json:"offset_days" gorm:"not null"`
+	// RepeatEveryDays repeats the reminder while the visit is not done, zero
+	// sends it once
+	RepeatEveryDays int       `json:"repeat_every_days" gorm:"not null;default:0"`
+	Active          bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (ReminderRule) TableName() string {
+	return "reminder_rules"
+}
+
+// Occurrence tells whether the rule applies to a visit due daysFromDue days
+// ago, negative when it is due later, and which occurrence of the reminder
+// is due. One-off reminders always have occurrence 0.
+func (r *ReminderRule) Occurrence(daysFromDue int) (int, bool) {
+	if daysFromDue < r.OffsetDays {
+		return 0, false
+	}
+	if r.RepeatEveryDays <= 0 {
+		return 0, daysFromDue < r.OffsetDays+ReminderCatchUpDays
+	}
+	return (daysFromDue - r.OffsetDays) / r.RepeatEveryDays, true
+}
+
+// Describe returns a French description of when the rule sends reminders
+func (r *ReminderRule) Describe() string {
+	var when string
+	switch {
+	case r.OffsetDays < 0:
+		when = pluralDays(-r.OffsetDays) + " avant l'échéance"
+	case r.OffsetDays == 0:
+		when = "le jour de l'échéance"
+	default:
+		when = pluralDays(r.OffsetDays) + " après l'échéance"
+	}
+	if r.RepeatEveryDays > 0 {
+		when += ", puis tous les " + pluralDays(r.RepeatEveryDays) + " tant que la visite n'est pas faite"
+	}
+	return when
+}
+
+func pluralDays(days int) string {
+	if days == 1 {
+		return "1 jour"
+	}
+	return strconv.Itoa(days) + " jours"
+}
+
+// DueReminderRule returns the active rule to apply to a visit due
+// daysFromDue days ago and its occurrence. When several rules apply, the
+// latest one wins so that an overdue portal is not sent an early notice.
+func DueReminderRule(rules []ReminderRule, daysFromDue int) (*ReminderRule, int) {
+	var due *ReminderRule
+	var occurrence int
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Active {
+			continue
+		}
+		n, ok := rule.Occurrence(daysFromDue)
+		if ok && (due == nil || rule.OffsetDays > due.OffsetDays) {
+			due, occurrence = rule, n
+		}
+	}
+	return due, occurrence
+}
+
+// ReminderRecipientRole tells why a recipient is sent the reminders of a
+// portal
+type ReminderRecipientRole string
+
+const (
+	ReminderRecipientContact    ReminderRecipientRole = "contact"
+	ReminderRecipientContractor ReminderRecipientRole = "contractor"
+	ReminderRecipientTechnician ReminderRecipientRole = "technician"
+)
+
+// Label returns the French label of the recipient role
+func (r ReminderRecipientRole) Label() string {
+	switch r {
+	case ReminderRecipientContact:
+		return "Contact du portail"
+	case ReminderRecipientContractor:
+		return "Prestataire"
+	case ReminderRecipientTechnician:
+		return "Technicien"
+	}
+	return string(r)
+}
+
+// ReminderRecipient is an address the reminders of a portal are sent to
+type ReminderRecipient struct {
+	Email string
+	Role  ReminderRecipientRole
+}
+
+// ReminderRecipients returns who receives the maintenance reminders of the
+// portal: its contact, the contractor of its contract and its technician,
+// each address once. Contract and Technician must be loaded. Portals that
+// opted out have none.
+func (p *Portal) ReminderRecipients() []ReminderRecipient {
+	if p.RemindersOptOut {
+		return nil
+	}
+
+	var recipients []ReminderRecipient
+	seen := make(map[string]bool)
+	add := func(email string, role ReminderRecipientRole) {
+		email = strings.TrimSpace(email)
+		key := strings.ToLower(email)
+		if email == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		recipients = append(recipients, ReminderRecipient{Email: email, Role: role})
+	}
+
+	add(p.ContactEmail, ReminderRecipientContact)
+	if p.Contract != nil {
+		add(p.Contract.ContractorEmail, ReminderRecipientContractor)
+	}
+	if p.Technician != nil && p.Technician.IsActive {
+		add(p.Technician.Email, ReminderRecipientTechnician)
+	}
+	return recipients
+}
+
+// MaintenanceReminder records a reminder queued for one recipient, along
+// with the outbox email carrying it. The unique index makes a reminder go
+// out once per due date, rule occurrence and recipient.
+type MaintenanceReminder struct {
+	ID            uint                  `json:"id" gorm:"primaryKey"`
+	PortalID      uint                  `json:"portal_id" gorm:"not null;index;uniqueIndex:idx_maintenance_reminders_dedup"`
+	RuleID        uint                  `json:"rule_id" gorm:"not null;uniqueIndex:idx_maintenance_reminders_dedup"`
+	DueDate       time.Time             `json:"due_date" gorm:"type:date;not null;uniqueIndex:idx_maintenance_reminders_dedup"`
+	Occurrence    int                   `json:"occurrence" gorm:"not null;uniqueIndex:idx_maintenance_reminders_dedup"`
+	Recipient     string                `json:"recipient" gorm:"not null;uniqueIndex:idx_maintenance_reminders_dedup"`
+	RecipientRole ReminderRecipientRole `json:"recipient_role" gorm:"type:varchar(20);not null"`
+	EmailID       *uint                 `json:"email_id" gorm:"index"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+
+	// Relationships
+	Portal Portal       `json:"portal,omitempty" gorm:"foreignKey:PortalID"`
+	Rule   ReminderRule `json:"rule,omitempty" gorm:"foreignKey:RuleID"`
+	Email  *OutboxEmail `json:"email,omitempty" gorm:"foreignKey:EmailID"`
+}
+
+func (MaintenanceReminder) TableName() string {
+	return "maintenance_reminders"
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderRule_Occurrence(t *testing.T) {
+	before := ReminderRule{OffsetDays: -30}
+	_, ok := before.Occurrence(-31)
+	assert.False(t, ok)
+	n, ok := before.Occurrence(-30)
+	assert.True(t, ok)
+	assert.Equal(t, 0, n)
+	_, ok = before.Occurrence(-30 + ReminderCatchUpDays - 1)
+	assert.True(t, ok)
+	_, ok = before.Occurrence(-30 + ReminderCatchUpDays)
+	assert.False(t, ok, "one-off reminders are not caught up forever")
+
+	weekly := ReminderRule{OffsetDays: 7, RepeatEveryDays: 7}
+	_, ok = weekly.Occurrence(6)
+	assert.False(t, ok)
+	n, ok = weekly.Occurrence(7)
+	assert.True(t, ok)
+	assert.Equal(t, 0, n)
+	n, _ = weekly.Occurrence(13)
+	assert.Equal(t, 0, n)
+	n, _ = weekly.Occurrence(14)
+	assert.Equal(t, 1, n)
+	n, _ = weekly.Occurrence(100)
+	assert.Equal(t, 13, n)
+}
+
+func TestReminderRule_Describe(t *testing.T) {
+	assert.Equal(t, "30 jours avant l'échéance", (&ReminderRule{OffsetDays: -30}).Describe())
+	assert.Equal(t, "le jour de l'échéance", (&ReminderRule{}).Describe())
+	assert.Equal(t, "1 jour après l'échéance, puis tous les 7 jours tant que la visite n'est pas faite",
+		(&ReminderRule{OffsetDays: 1, RepeatEveryDays: 7}).Describe())
+}
+
+func TestDueReminderRule(t *testing.T) {
+	rules := []ReminderRule{
+		{ID: 1, OffsetDays: -30, Active: true},
+		{ID: 2, OffsetDays: 0, Active: true},
+		{ID: 3, OffsetDays: 7, RepeatEveryDays: 7, Active: true},
+		{ID: 4, OffsetDays: -10, Active: false},
+	}
+
+	rule, _ := DueReminderRule(rules, -40)
+	assert.Nil(t, rule)
+
+	rule, _ = DueReminderRule(rules, -28)
+	assert.Equal(t, uint(1), rule.ID)
+
+	rule, _ = DueReminderRule(rules, -10)
+	assert.Nil(t, rule, "inactive rules are ignored")
+
+	rule, _ = DueReminderRule(rules, 2)
+	assert.Equal(t, uint(2), rule.ID)
+
+	rule, occurrence := DueReminderRule(rules, 21)
+	assert.Equal(t, uint(3), rule.ID)
+	assert.Equal(t, 2, occurrence)
+}
+
+func TestPortal_ReminderRecipients(t *testing.T) {
+	portal := Portal{
+		ContactEmail: "syndic@example.com",
+		Contract:     &Contract{ContractorEmail: "SYNDIC@example.com"},
+		Technician:   &User{Email: "tech@example.com", IsActive: true},
+	}
+	assert.Equal(t, []ReminderRecipient{
+		{Email: "syndic@example.com", Role: ReminderRecipientContact},
+		{Email: "tech@example.com", Role: ReminderRecipientTechnician},
+	}, portal.ReminderRecipients())
+
+	portal.Technician.IsActive = false
+	portal.ContactEmail = ""
+	assert.Equal(t, []ReminderRecipient{
+		{Email: "SYNDIC@example.com", Role: ReminderRecipientContractor},
+	}, portal.ReminderRecipients())
+
+	portal.RemindersOptOut = true
+	assert.Empty(t, portal.ReminderRecipients())
+}
@@ -0,0 +1,15 @@
+package models
+
+// ReportSequence holds the last report number issued by an organization for a
+// year. The row is incremented inside the transaction that finalizes an
+// intervention so that numbers are gap-free: a rolled back intervention
+// releases its number.
+type ReportSequence struct {
+	OrganizationID uint `json:"organization_id" gorm:"primaryKey;autoIncrement:false"`
+	Year           int  `json:"year" gorm:"primaryKey;autoIncrement:false"`
+	LastNumber     int  `json:"last_number" gorm:"not null"`
+}
+
+func (ReportSequence) TableName() string {
+	return "report_sequences"
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// ScheduledTask is a recurring task run by the scheduler. The rows are kept
+// in sync with the tasks registered at startup and shared by the replicas,
+// which agree through them on the next run.
+type ScheduledTask struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Name           string     `json:"name" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Description    string     `json:"description" gorm:"not null"`
+	Schedule       string     `json:"schedule" gorm:"type:varchar(100);not null"`
+	NextRunAt      time.Time  `json:"next_run_at" gorm:"not null"`
+	RunRequestedAt *time.Time `json:"run_requested_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Runs []TaskRun `json:"runs,omitempty" gorm:"foreignKey:TaskID"`
+}
+
+func (ScheduledTask) TableName() string {
+	return "scheduled_tasks"
+}
+
+// TaskRunTrigger tells what started a task run
+type TaskRunTrigger string
+
+const (
+	TaskRunTriggerSchedule TaskRunTrigger = "schedule"
+	TaskRunTriggerManual   TaskRunTrigger = "manual"
+)
+
+// Label returns the French label of the trigger
+func (t TaskRunTrigger) Label() string {
+	switch t {
+	case TaskRunTriggerSchedule:
+		return "Planifiée"
+	case TaskRunTriggerManual:
+		return "Manuelle"
+	}
+	return string(t)
+}
+
+type TaskRunStatus string
+
+const (
+	TaskRunStatusRunning   TaskRunStatus = "running"
+	TaskRunStatusSucceeded TaskRunStatus = "succeeded"
+	TaskRunStatusFailed    TaskRunStatus = "failed"
+)
+
+// Label returns the French label of the run status
+func (s TaskRunStatus) Label() string {
+	switch s {
+	case TaskRunStatusRunning:
+		return "En cours"
+	case TaskRunStatusSucceeded:
+		return "Réussie"
+	case TaskRunStatusFailed:
+		return "En échec"
+	}
+	return string(s)
+}
+
+// TaskRun records an execution of a scheduled task
+type TaskRun struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	TaskID     uint           `json:"task_id" gorm:"not null;index"`
+	Trigger    TaskRunTrigger `json:"trigger" gorm:"type:varchar(20);not null"`
+	Status     TaskRunStatus  `json:"status" gorm:"type:varchar(20);not null"`
+	Instance   string         `json:"instance" gorm:"not null"`
+	Error      *string        `json:"error" gorm:"type:text"`
+	StartedAt  time.Time      `json:"started_at" gorm:"not null;index"`
+	FinishedAt *time.Time     `json:"finished_at"`
+
+	// Relationships
+	Task ScheduledTask `json:"task,omitempty" gorm:"foreignKey:TaskID"`
+}
+
+func (TaskRun) TableName() string {
+	return "task_runs"
+}
+
+// Duration returns how long the run took, zero while it is running
+func (r *TaskRun) Duration() time.Duration {
+	if r.FinishedAt == nil {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
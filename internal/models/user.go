@@ -7,12 +7,45 @@ import (
 	"gorm.io/gorm"
 )
 
+// UserRole tells what a user may do. Supervisors validate the interventions
+// submitted by technicians.
+type UserRole string
+
+const (
+	UserRoleTechnician UserRole = "technician"
+	UserRoleSupervisor UserRole = "supervisor"
+)
+
+// UserRoles lists the user roles
+var UserRoles = []UserRole{UserRoleTechnician, UserRoleSupervisor}
+
+func (r UserRole) IsValid() bool {
+	for _, role := range UserRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Label returns the French label of the role
+func (r UserRole) Label() string {
+	switch r {
+	case UserRoleTechnician:
+		return "Technicien"
+	case UserRoleSupervisor:
+		return "Superviseur"
+	}
+	return string(r)
+}
+
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
 	Password  string         `json:"-" gorm:"not null"`
 	FirstName string         `json:"first_name" gorm:"not null"`
 	LastName  string         `json:"last_name" gorm:"not null"`
+	Role      UserRole       `json:"role" gorm:"type:varchar(20);not null;default:technician"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -40,3 +73,8 @@ func (u *User) CheckPassword(password string) bool {
 func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
+
+// IsSupervisor reports whether the user may validate interventions
+func (u *User) IsSupervisor() bool {
+	return u.Role == UserRoleSupervisor
+}
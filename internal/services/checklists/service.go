@@ -0,0 +1,120 @@
+package checklists
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrNoChecklist is returned when an equipment type has no checklist version
+var ErrNoChecklist = errors.New("equipment type has no checklist")
+
+// Service handles the versioned checklists of equipment types
+type Service struct {
+	db *gorm.DB
+}
+
+// NewService creates a new checklist service
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// CurrentVersion returns the latest checklist version of an equipment type
+// with its items
+func (s *Service) CurrentVersion(equipmentTypeID uint) (*models.ChecklistVersion, error) {
+	return currentVersion(s.db, equipmentTypeID)
+}
+
+// Version returns a checklist version with its items
+func (s *Service) Version(versionID uint) (*models.ChecklistVersion, error) {
+	var version models.ChecklistVersion
+	if err := s.db.Preload("Items").First(&version, versionID).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Edit applies fn to the checklist of an equipment type. When the current
+// version is already used by interventions, fn is applied to a new version
+// copied from it so that historical interventions keep their checklist.
+func (s *Service) Edit(equipmentTypeID uint, fn func(tx *gorm.DB, version *models.ChecklistVersion) error) (*models.ChecklistVersion, error) {
+	var edited *models.ChecklistVersion
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		version, err := editableVersion(tx, equipmentTypeID)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx, version); err != nil {
+			return err
+		}
+		edited = version
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+// CreateEquipmentType creates an equipment type with an empty first version
+func (s *Service) CreateEquipmentType(equipmentType *models.EquipmentType) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(equipmentType).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.ChecklistVersion{EquipmentTypeID: equipmentType.ID, Version: 1}).Error
+	})
+}
+
+func currentVersion(tx *gorm.DB, equipmentTypeID uint) (*models.ChecklistVersion, error) {
+	var version models.ChecklistVersion
+	result := tx.Preload("Items").Where("equipment_type_id = ?", equipmentTypeID).Order("version DESC").First(&version)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrNoChecklist
+		}
+		return nil, result.Error
+	}
+	return &version, nil
+}
+
+// editableVersion returns the current version if no intervention uses it yet,
+// otherwise a new version holding a copy of its items
+func editableVersion(tx *gorm.DB, equipmentTypeID uint) (*models.ChecklistVersion, error) {
+	current, err := currentVersion(tx, equipmentTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := current.IsUsed(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check checklist version usage: %w", err)
+	}
+	if !used {
+		return current, nil
+	}
+
+	next := models.ChecklistVersion{
+		EquipmentTypeID: equipmentTypeID,
+		Version:         current.Version + 1,
+	}
+	for _, item := range current.Items {
+		next.Items = append(next.Items, models.ChecklistItem{
+			Code:     item.Code,
+			Kind:     item.Kind,
+			Label:    item.Label,
+			Position: item.Position,
+			Type:     item.Type,
+			Unit:     item.Unit,
+			MinValue: item.MinValue,
+			MaxValue: item.MaxValue,
+		})
+	}
+
+	if err := tx.Create(&next).Error; err != nil {
+		return nil, fmt.Errorf("failed to create checklist version: %w", err)
+	}
+	return &next, nil
+}
@@ -1,15 +1,31 @@
 package email
 
+import (
+	"context"
+	"errors"
+	"net/textproto"
+)
+
+// ErrInvalidMessage is returned when a message is rejected before being
+// sent, e.g. for an invalid recipient
+var ErrInvalidMessage = errors.New("message validation failed")
+
 // EmailService defines the interface for sending emails with optional attachments.
 // This interface abstracts email sending operations to allow for different implementations
 // (Gmail, SMTP, etc.) while maintaining a consistent API.
 type EmailService interface {
-	// Send sends an email to the specified recipient with the given subject and body.
-	// Parameters:
-	//   - to: recipient email address
-	//   - subject: email subject line
-	//   - body: email content (can be plain text or HTML)
-	//   - attachment: file path to attachment; if empty, sends email without attachment
-	// Returns an error if the email fails to send.
-	Send(to []string, subject string, body string, attachments []string) error
+	// Deliver sends the message and returns its message ID and the reply of
+	// the server that accepted it. Use IsPermanent to tell whether a failed
+	// delivery is worth retrying.
+	Deliver(ctx context.Context, msg *EmailMessage) (*Delivery, error)
+}
+
+// IsPermanent reports whether a delivery error will happen again on retry:
+// an invalid message or a 5xx reply of the SMTP server
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrInvalidMessage) {
+		return true
+	}
+	var protocolErr *textproto.Error
+	return errors.As(err, &protocolErr) && protocolErr.Code >= 500
 }
@@ -0,0 +1,16 @@
+package email
+
+import "github.com/troptropcontent/qr_code_maintenance/internal/utils"
+
+// EMAIL_LOGO_PATH_ENV_VAR holds the path of the company logo shown at the
+// top of the HTML emails, no logo is shown when unset
+const EMAIL_LOGO_PATH_ENV_VAR string = "EMAIL_LOGO_PATH"
+
+// LogoContentID is the content ID of the logo sent inline with the HTML
+// emails, referenced from them as cid:logo
+const LogoContentID = "logo"
+
+// LogoPath returns the path of the company logo, empty when not configured
+func LogoPath() string {
+	return utils.GetEnv(EMAIL_LOGO_PATH_ENV_VAR, "")
+}
@@ -2,17 +2,24 @@ package email
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/mail"
 	"net/smtp"
 	"net/textproto"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
 )
@@ -33,16 +40,55 @@ type Attachment struct {
 	FilePath    string
 	FileName    string // Override default filename
 	ContentType string // Override auto-detected content type
+	Content     []byte // Used instead of FilePath when set, FileName is then required
+	// ContentID identifies an inline attachment referenced from the HTML
+	// body as cid:<ContentID>
+	ContentID string
 }
 
 // EmailMessage represents a structured email message
 type EmailMessage struct {
-	To          []string
-	Subject     string
-	Body        string
+	// MessageID is the Message-ID header without angle brackets, generated
+	// when empty. Reusing it across retries lets servers detect duplicates.
+	MessageID string
+	To        []string
+	Cc        []string
+	// Bcc recipients receive the message without being listed in it
+	Bcc []string
+	// DeliverTo, when set, are the only addresses the message is delivered
+	// to, To and Cc being then listed in its headers only
+	DeliverTo []string
+	Subject   string
+	Body      string
+	// HTMLBody is sent along with Body, as its multipart/alternative HTML
+	// version, when set
+	HTMLBody string
+	// Inline holds the images referenced from HTMLBody, sent with it in a
+	// multipart/related part. Each must have a ContentID.
+	Inline      []Attachment
 	Attachments []Attachment
 }
 
+// Envelope returns the addresses the message is delivered to: DeliverTo
+// when set, else every recipient, Bcc included
+func (m *EmailMessage) Envelope() []string {
+	if len(m.DeliverTo) > 0 {
+		return m.DeliverTo
+	}
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	return append(recipients, m.Bcc...)
+}
+
+// Delivery describes how the SMTP server accepted a message
+type Delivery struct {
+	MessageID string
+	// Response is the reply of the server to the message data, e.g.
+	// "250 2.0.0 OK queued as 4F2A1"
+	Response string
+}
+
 // SMTPService implements EmailService using SMTP
 type SMTPService struct {
 	host     string
@@ -145,6 +191,12 @@ func (s *SMTPService) validateEmail(email string) error {
 
 // validateAttachment checks if an attachment is accessible and secure
 func (s *SMTPService) validateAttachment(attachment Attachment) error {
+	if attachment.Content != nil {
+		if attachment.FileName == "" {
+			return fmt.Errorf("attachment file name is required")
+		}
+		return nil
+	}
 	if attachment.FilePath == "" {
 		return nil // Empty attachment is valid
 	}
@@ -170,11 +222,11 @@ func (s *SMTPService) validateAttachment(attachment Attachment) error {
 
 // validateMessage validates the email message structure
 func (s *SMTPService) validateMessage(msg *EmailMessage) error {
-	if len(msg.To) == 0 {
+	if len(msg.Envelope()) == 0 {
 		return fmt.Errorf("at least one recipient is required")
 	}
 
-	for _, recipient := range msg.To {
+	for _, recipient := range append(append(append(append([]string{}, msg.To...), msg.Cc...), msg.Bcc...), msg.DeliverTo...) {
 		if err := s.validateEmail(recipient); err != nil {
 			return fmt.Errorf("invalid recipient %s: %v", recipient, err)
 		}
@@ -190,11 +242,21 @@ func (s *SMTPService) validateMessage(msg *EmailMessage) error {
 		}
 	}
 
+	for i, image := range msg.Inline {
+		if image.ContentID == "" {
+			return fmt.Errorf("inline attachment %d has no content ID", i)
+		}
+		if err := s.validateAttachment(image); err != nil {
+			return fmt.Errorf("invalid inline attachment %d: %v", i, err)
+		}
+	}
+
 	return nil
 }
 
-// createConnection establishes and authenticates SMTP connection
-func (s *SMTPService) createConnection() (*smtp.Client, error) {
+// createConnection establishes and authenticates SMTP connection. The
+// connection is closed when ctx expires.
+func (s *SMTPService) createConnection(ctx context.Context) (*smtp.Client, error) {
 	// Create TLS config
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: false,
@@ -202,10 +264,18 @@ func (s *SMTPService) createConnection() (*smtp.Client, error) {
 	}
 
 	// Connect to server with plain connection
-	conn, err := smtp.Dial(s.host + ":" + s.port)
+	netConn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(s.host, s.port))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to SMTP server: %v", err)
 	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+	conn, err := smtp.NewClient(netConn, s.host)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
 
 	// Start TLS
 	if err = conn.StartTLS(tlsConfig); err != nil {
@@ -223,42 +293,91 @@ func (s *SMTPService) createConnection() (*smtp.Client, error) {
 	return conn, nil
 }
 
-// sendMessage sends the email message through the SMTP connection
-func (s *SMTPService) sendMessage(conn *smtp.Client, msg *EmailMessage) error {
+// sendMessage sends the email message through the SMTP connection and
+// returns the reply of the server once it accepted the data
+func (s *SMTPService) sendMessage(conn *smtp.Client, msg *EmailMessage) (string, error) {
 	// Set sender
 	if err := conn.Mail(s.from); err != nil {
-		return fmt.Errorf("failed to set sender: %v", err)
+		return "", fmt.Errorf("failed to set sender: %w", err)
 	}
 
 	// Set recipients
-	for _, recipient := range msg.To {
+	for _, recipient := range msg.Envelope() {
 		if err := conn.Rcpt(recipient); err != nil {
-			return fmt.Errorf("failed to set recipient %s: %v", recipient, err)
+			return "", fmt.Errorf("failed to set recipient %s: %w", recipient, err)
 		}
 	}
 
-	// Get data writer
-	writer, err := conn.Data()
+	// Build message
+	message, err := s.buildMessageFromStruct(msg)
 	if err != nil {
-		return fmt.Errorf("failed to get data writer: %v", err)
+		return "", fmt.Errorf("failed to build message: %v", err)
 	}
-	defer writer.Close()
 
-	// Build and send message
-	message, err := s.buildMessageFromStruct(msg)
+	// smtp.Client.Data discards the final reply of the server, which holds
+	// the queue ID, so the DATA command is run on the text connection
+	id, err := conn.Text.Cmd("DATA")
+	if err != nil {
+		return "", fmt.Errorf("failed to start data: %w", err)
+	}
+	conn.Text.StartResponse(id)
+	_, _, err = conn.Text.ReadResponse(354)
+	conn.Text.EndResponse(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to start data: %w", err)
+	}
+
+	writer := conn.Text.DotWriter()
+	if _, err := writer.Write([]byte(message)); err != nil {
+		writer.Close()
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to write message: %w", err)
+	}
+	code, reply, err := conn.Text.ReadResponse(250)
 	if err != nil {
-		return fmt.Errorf("failed to build message: %v", err)
+		return "", fmt.Errorf("message rejected: %w", err)
 	}
+	return fmt.Sprintf("%d %s", code, reply), nil
+}
 
-	_, err = writer.Write([]byte(message))
+// Deliver implements the EmailService interface
+func (s *SMTPService) Deliver(ctx context.Context, msg *EmailMessage) (*Delivery, error) {
+	if msg.MessageID == "" {
+		msg.MessageID = s.newMessageID()
+	}
+	if err := s.validateMessage(msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	conn, err := s.createConnection(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to write message: %v", err)
+		return nil, err
 	}
+	defer conn.Quit()
 
-	return nil
+	response, err := s.sendMessage(conn, msg)
+	if err != nil {
+		return nil, err
+	}
+	return &Delivery{MessageID: msg.MessageID, Response: response}, nil
+}
+
+// newMessageID returns a unique message ID in the domain of the sender
+func (s *SMTPService) newMessageID() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	domain := "localhost"
+	if address, err := mail.ParseAddress(s.from); err == nil {
+		if at := strings.LastIndex(address.Address, "@"); at >= 0 {
+			domain = address.Address[at+1:]
+		}
+	}
+	return hex.EncodeToString(random) + "@" + domain
 }
 
-// Send implements the EmailService interface (backward compatibility)
+// Send sends a message with file attachments (backward compatibility)
 func (s *SMTPService) Send(to []string, subject string, body string, attachments []string) error {
 	// Convert legacy parameters to new structure
 	msg := &EmailMessage{
@@ -271,63 +390,72 @@ func (s *SMTPService) Send(to []string, subject string, body string, attachments
 		msg.Attachments = append(msg.Attachments, Attachment{FilePath: attachment})
 	}
 
-	if err := s.validateMessage(msg); err != nil {
-		return fmt.Errorf("message validation failed: %v", err)
-	}
-
-	conn, err := s.createConnection()
-	if err != nil {
-		return err
-	}
-	defer conn.Quit()
-
-	// Use new SendMessage method
-	return s.sendMessage(conn, msg)
+	_, err := s.Deliver(context.Background(), msg)
+	return err
 }
 
 // buildMessageFromStruct creates an email message from EmailMessage struct
 func (s *SMTPService) buildMessageFromStruct(msg *EmailMessage) (string, error) {
-	if len(msg.Attachments) == 0 {
+	if len(msg.Attachments) == 0 && msg.HTMLBody == "" {
 		return s.buildSimpleMessage(msg), nil
 	}
 	return s.buildMultipartMessage(msg)
 }
 
+// writeHeaders writes the headers common to every message
+func (s *SMTPService) writeHeaders(buf io.Writer, msg *EmailMessage) {
+	fmt.Fprintf(buf, "From: %s\r\n", s.from)
+	if len(msg.To) > 0 {
+		fmt.Fprintf(buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	} else {
+		buf.Write([]byte("To: undisclosed-recipients:;\r\n"))
+	}
+	if len(msg.Cc) > 0 {
+		fmt.Fprintf(buf, "Cc: %s\r\n", strings.Join(msg.Cc, ", "))
+	}
+	// Subjects are often French, non-ASCII characters must be encoded
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	if msg.MessageID != "" {
+		fmt.Fprintf(buf, "Message-ID: <%s>\r\n", msg.MessageID)
+	}
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.Write([]byte("MIME-Version: 1.0\r\n"))
+}
+
 // buildSimpleMessage creates a simple email without attachments
 func (s *SMTPService) buildSimpleMessage(msg *EmailMessage) string {
 	var buf strings.Builder
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", s.from))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(msg.To, ", ")))
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", msg.Subject))
-	buf.WriteString("MIME-Version: 1.0\r\n")
+	s.writeHeaders(&buf, msg)
 	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
 	buf.WriteString(msg.Body)
 	return buf.String()
 }
 
-// buildMultipartMessage creates an email with attachments
+// buildMultipartMessage creates an email with attachments or an HTML body.
+// The body is a multipart/alternative part when it has an HTML version,
+// within a multipart/mixed message when there are attachments.
 func (s *SMTPService) buildMultipartMessage(msg *EmailMessage) (string, error) {
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+	body, err := s.buildBody(msg)
+	if err != nil {
+		return "", err
+	}
 
-	// Write email headers
-	headers := fmt.Sprintf("From: %s\r\n", s.from)
-	headers += fmt.Sprintf("To: %s\r\n", strings.Join(msg.To, ", "))
-	headers += fmt.Sprintf("Subject: %s\r\n", msg.Subject)
-	headers += "MIME-Version: 1.0\r\n"
-	headers += fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	var buf bytes.Buffer
+	s.writeHeaders(&buf, msg)
+	if len(msg.Attachments) == 0 {
+		writeEntity(&buf, body)
+		return buf.String(), nil
+	}
 
-	buf.WriteString(headers)
+	writer := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
 
-	// Text body part
-	textPart, err := writer.CreatePart(textproto.MIMEHeader{
-		"Content-Type": []string{"text/plain; charset=utf-8"},
-	})
+	bodyPart, err := writer.CreatePart(body.header)
 	if err != nil {
-		return "", fmt.Errorf("failed to create text part: %v", err)
+		return "", fmt.Errorf("failed to create body part: %v", err)
 	}
-	if _, err := textPart.Write([]byte(msg.Body)); err != nil {
-		return "", fmt.Errorf("failed to write text body: %v", err)
+	if _, err := bodyPart.Write(body.content); err != nil {
+		return "", fmt.Errorf("failed to write body: %v", err)
 	}
 
 	// Process attachments
@@ -344,17 +472,111 @@ func (s *SMTPService) buildMultipartMessage(msg *EmailMessage) (string, error) {
 	return buf.String(), nil
 }
 
+// mimeEntity is a MIME part before it is written, as a message body or as a
+// part of a multipart one
+type mimeEntity struct {
+	header  textproto.MIMEHeader
+	content []byte
+}
+
+// writeEntity writes the headers and the content of an entity
+func writeEntity(buf *bytes.Buffer, entity mimeEntity) {
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := entity.header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(entity.content)
+}
+
+// buildBody returns the body of the message: the plain text alone, or the
+// plain text and HTML versions in a multipart/alternative entity. The HTML
+// version is wrapped with its inline images in a multipart/related entity.
+func (s *SMTPService) buildBody(msg *EmailMessage) (mimeEntity, error) {
+	text := mimeEntity{
+		header:  textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}},
+		content: []byte(msg.Body),
+	}
+	if msg.HTMLBody == "" {
+		return text, nil
+	}
+
+	// HTML lines can exceed the 998 characters SMTP allows
+	var encoded bytes.Buffer
+	qp := quotedprintable.NewWriter(&encoded)
+	if _, err := qp.Write([]byte(msg.HTMLBody)); err != nil {
+		return mimeEntity{}, fmt.Errorf("failed to encode HTML body: %v", err)
+	}
+	if err := qp.Close(); err != nil {
+		return mimeEntity{}, fmt.Errorf("failed to encode HTML body: %v", err)
+	}
+	html := mimeEntity{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {"text/html; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		content: encoded.Bytes(),
+	}
+
+	if len(msg.Inline) > 0 {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, err := writer.CreatePart(html.header)
+		if err != nil {
+			return mimeEntity{}, fmt.Errorf("failed to create HTML part: %v", err)
+		}
+		if _, err := part.Write(html.content); err != nil {
+			return mimeEntity{}, fmt.Errorf("failed to write HTML body: %v", err)
+		}
+		for i, image := range msg.Inline {
+			if err := s.addAttachment(writer, image); err != nil {
+				return mimeEntity{}, fmt.Errorf("failed to add inline attachment %d: %v", i, err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return mimeEntity{}, fmt.Errorf("failed to close multipart writer: %v", err)
+		}
+		html = mimeEntity{
+			header:  textproto.MIMEHeader{"Content-Type": {fmt.Sprintf("multipart/related; type=\"text/html\"; boundary=%s", writer.Boundary())}},
+			content: buf.Bytes(),
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for _, entity := range []mimeEntity{text, html} {
+		part, err := writer.CreatePart(entity.header)
+		if err != nil {
+			return mimeEntity{}, fmt.Errorf("failed to create body part: %v", err)
+		}
+		if _, err := part.Write(entity.content); err != nil {
+			return mimeEntity{}, fmt.Errorf("failed to write body part: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return mimeEntity{}, fmt.Errorf("failed to close multipart writer: %v", err)
+	}
+	return mimeEntity{
+		header:  textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + writer.Boundary()}},
+		content: buf.Bytes(),
+	}, nil
+}
+
 // addAttachment adds a single attachment to the multipart writer
 func (s *SMTPService) addAttachment(writer *multipart.Writer, attachment Attachment) error {
-	file, err := os.Open(attachment.FilePath)
-	if err != nil {
-		return fmt.Errorf("failed to open attachment file: %v", err)
-	}
-	defer file.Close()
+	fileContent := attachment.Content
+	if fileContent == nil {
+		file, err := os.Open(attachment.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to open attachment file: %v", err)
+		}
+		defer file.Close()
 
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf("failed to read attachment file: %v", err)
+		fileContent, err = io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment file: %v", err)
+		}
 	}
 
 	// Determine filename
@@ -370,11 +592,16 @@ func (s *SMTPService) addAttachment(writer *multipart.Writer, attachment Attachm
 	}
 
 	// Create attachment part
-	attachPart, err := writer.CreatePart(textproto.MIMEHeader{
+	header := textproto.MIMEHeader{
 		"Content-Type":              []string{contentType},
 		"Content-Disposition":       []string{fmt.Sprintf("attachment; filename=\"%s\"", filename)},
 		"Content-Transfer-Encoding": []string{"base64"},
-	})
+	}
+	if attachment.ContentID != "" {
+		header.Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+		header.Set("Content-ID", "<"+attachment.ContentID+">")
+	}
+	attachPart, err := writer.CreatePart(header)
 	if err != nil {
 		return fmt.Errorf("failed to create attachment part: %v", err)
 	}
@@ -1,8 +1,18 @@
 package email
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -236,3 +246,154 @@ func BenchmarkSend_Validation(b *testing.B) {
 		service.Send([]string{"recipient@example.com"}, "Test Subject", "Test body content", []string{})
 	}
 }
+
+func TestSMTPService_BuildMessage(t *testing.T) {
+	service := NewSMTPService(SMTPConfig{From: "Maintenance <noreply@example.com>"})
+
+	msg := &EmailMessage{
+		MessageID: "abc@example.com",
+		To:        []string{"a@example.com", "b@example.com"},
+		Subject:   "Rapport",
+		Body:      "Bonjour",
+		Attachments: []Attachment{
+			{FileName: "rapport.pdf", Content: []byte("%PDF-1.4")},
+		},
+	}
+	require.NoError(t, service.validateMessage(msg))
+
+	message, err := service.buildMessageFromStruct(msg)
+	require.NoError(t, err)
+	assert.Contains(t, message, "To: a@example.com, b@example.com\r\n")
+	assert.Contains(t, message, "Subject: Rapport\r\n")
+	assert.Contains(t, message, "Message-ID: <abc@example.com>\r\n")
+	assert.Contains(t, message, "Content-Type: application/pdf")
+	assert.Contains(t, message, "filename=\"rapport.pdf\"")
+	assert.Contains(t, message, "JVBERi0xLjQ=")
+
+	assert.Error(t, service.validateAttachment(Attachment{Content: []byte("x")}), "in-memory attachments need a name")
+}
+
+func TestSMTPService_EncodedSubject(t *testing.T) {
+	service := NewSMTPService(SMTPConfig{From: "noreply@example.com"})
+
+	msg := &EmailMessage{
+		To:      []string{"a@example.com"},
+		Subject: "Rapport d'intervention – Portail Entrée",
+		Body:    "Bonjour",
+	}
+	message, err := service.buildMessageFromStruct(msg)
+	require.NoError(t, err)
+	assert.Contains(t, message, "Subject: =?utf-8?q?")
+	assert.NotContains(t, message, msg.Subject)
+
+	header, err := mail.ReadMessage(strings.NewReader(message))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+}
+
+func TestSMTPService_CopyRecipients(t *testing.T) {
+	service := NewSMTPService(SMTPConfig{From: "noreply@example.com"})
+
+	msg := &EmailMessage{
+		To:      []string{"a@example.com"},
+		Cc:      []string{"b@example.com"},
+		Bcc:     []string{"c@example.com"},
+		Subject: "Rapport",
+		Body:    "Bonjour",
+	}
+	require.NoError(t, service.validateMessage(msg))
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com"}, msg.Envelope())
+
+	message, err := service.buildMessageFromStruct(msg)
+	require.NoError(t, err)
+	assert.Contains(t, message, "Cc: b@example.com\r\n")
+	assert.NotContains(t, message, "c@example.com", "Bcc recipients are not listed")
+
+	msg.DeliverTo = []string{"b@example.com"}
+	assert.Equal(t, []string{"b@example.com"}, msg.Envelope())
+	message, err = service.buildMessageFromStruct(msg)
+	require.NoError(t, err)
+	assert.Contains(t, message, "To: a@example.com\r\n", "the other recipients stay listed")
+
+	msg.Bcc = []string{"not-an-address"}
+	assert.Error(t, service.validateMessage(msg))
+}
+
+func TestSMTPService_NewMessageID(t *testing.T) {
+	service := NewSMTPService(SMTPConfig{From: "Maintenance <noreply@example.com>"})
+
+	id := service.newMessageID()
+	assert.Regexp(t, `^[0-9a-f]{32}@example\.com$`, id)
+	assert.NotEqual(t, id, service.newMessageID())
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, IsPermanent(fmt.Errorf("%w: no recipient", ErrInvalidMessage)))
+	assert.True(t, IsPermanent(fmt.Errorf("failed to set recipient: %w", &textproto.Error{Code: 550, Msg: "mailbox unavailable"})))
+	assert.False(t, IsPermanent(fmt.Errorf("message rejected: %w", &textproto.Error{Code: 451, Msg: "try again later"})))
+	assert.False(t, IsPermanent(errors.New("failed to connect to SMTP server")))
+}
+
+func TestSMTPService_HTMLMessage(t *testing.T) {
+	service := NewSMTPService(SMTPConfig{From: "noreply@example.com"})
+
+	msg := &EmailMessage{
+		To:          []string{"a@example.com"},
+		Subject:     "Rapport",
+		Body:        "Bonjour",
+		HTMLBody:    `<p>Bonjour</p><img src="cid:logo">`,
+		Inline:      []Attachment{{FileName: "logo.png", Content: []byte("png"), ContentID: "logo"}},
+		Attachments: []Attachment{{FileName: "rapport.pdf", Content: []byte("pdf")}},
+	}
+	require.NoError(t, service.validateMessage(msg))
+
+	message, err := service.buildMessageFromStruct(msg)
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	require.NoError(t, err)
+
+	mixed := readParts(t, parsed.Header.Get("Content-Type"), parsed.Body)
+	require.Len(t, mixed, 2)
+	assert.Contains(t, mixed[1].header.Get("Content-Disposition"), "attachment")
+
+	alternative := readParts(t, mixed[0].header.Get("Content-Type"), bytes.NewReader(mixed[0].content))
+	require.Len(t, alternative, 2)
+	assert.Equal(t, "text/plain; charset=utf-8", alternative[0].header.Get("Content-Type"))
+	assert.Equal(t, "Bonjour", string(alternative[0].content))
+
+	related := readParts(t, alternative[1].header.Get("Content-Type"), bytes.NewReader(alternative[1].content))
+	require.Len(t, related, 2)
+	assert.Equal(t, "text/html; charset=utf-8", related[0].header.Get("Content-Type"))
+	assert.Equal(t, `<p>Bonjour</p><img src="cid:logo">`, string(related[0].content))
+	assert.Equal(t, "<logo>", related[1].header.Get("Content-Id"))
+	assert.Contains(t, related[1].header.Get("Content-Disposition"), "inline")
+
+	msg.Inline[0].ContentID = ""
+	assert.Error(t, service.validateMessage(msg))
+}
+
+// readParts returns the parts of a multipart entity, decoded
+func readParts(t *testing.T, contentType string, body io.Reader) []mimeEntity {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(mediaType, "multipart/"), mediaType)
+
+	var parts []mimeEntity
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			content, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(content), "\r\n", ""))
+			require.NoError(t, err)
+		}
+		parts = append(parts, mimeEntity{header: part.Header, content: content})
+	}
+}
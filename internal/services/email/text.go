@@ -0,0 +1,162 @@
+package email
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// HTMLToText returns the plain-text version of an HTML email: paragraphs,
+// headings, lists and tables are separated by blank lines, divs and table
+// rows start a new line, list items start with a dash and links are
+// followed by their URL
+func HTMLToText(document string) string {
+	var text textWriter
+	var links []string
+	hidden := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.String()
+		case html.TextToken:
+			if hidden == 0 {
+				text.WriteText(string(tokenizer.Text()))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch tag {
+			case "head", "style", "script":
+				hidden++
+			case "br":
+				text.NewLine()
+			case "li":
+				text.Break(1)
+				text.WriteText("- ")
+			case "div", "tr", "dt", "dd":
+				text.Break(1)
+			case "a":
+				links = append(links, attribute(tokenizer, "href"))
+				text.StartLink()
+			default:
+				if isBlock(tag) {
+					text.Break(2)
+				}
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch tag {
+			case "head", "style", "script":
+				hidden--
+			case "li", "div", "tr", "dt", "dd":
+				text.Break(1)
+			case "a":
+				if len(links) > 0 {
+					text.EndLink(links[len(links)-1])
+					links = links[:len(links)-1]
+				}
+			default:
+				if isBlock(tag) {
+					text.Break(2)
+				}
+			}
+		}
+	}
+}
+
+func isBlock(tag string) bool {
+	switch tag {
+	case "p", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "dl", "table", "blockquote", "hr":
+		return true
+	}
+	return false
+}
+
+func attribute(tokenizer *html.Tokenizer, name string) string {
+	for {
+		key, value, more := tokenizer.TagAttr()
+		if string(key) == name {
+			return string(value)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// textWriter collapses the white space of HTML text and the line breaks
+// asked by the elements around it
+type textWriter struct {
+	builder strings.Builder
+	// breaks is the number of line breaks to write before the next word
+	breaks int
+	// space is set when white space separates the next word from the
+	// previous one
+	space     bool
+	linkStart int
+}
+
+// Break ends the current line, and leaves lines-1 blank lines before the
+// next text
+func (w *textWriter) Break(lines int) {
+	w.breaks = max(w.breaks, lines)
+	w.space = false
+}
+
+// NewLine ends the current line, a blank line is left after two of them
+func (w *textWriter) NewLine() {
+	if w.builder.Len() > 0 {
+		w.breaks = min(w.breaks+1, 2)
+	}
+	w.space = false
+}
+
+func (w *textWriter) WriteText(text string) {
+	if text == "" {
+		return
+	}
+	if isSpace(rune(text[0])) {
+		w.space = true
+	}
+	for _, word := range strings.FieldsFunc(text, isSpace) {
+		if w.builder.Len() > 0 {
+			if w.breaks > 0 {
+				w.builder.WriteString(strings.Repeat("\n", w.breaks))
+			} else if w.space {
+				w.builder.WriteString(" ")
+			}
+		}
+		w.builder.WriteString(word)
+		w.breaks = 0
+		w.space = true
+	}
+	w.space = isSpace(rune(text[len(text)-1]))
+}
+
+func (w *textWriter) StartLink() {
+	w.linkStart = w.builder.Len()
+}
+
+// EndLink writes the URL of a link after its text, unless it is the text
+func (w *textWriter) EndLink(href string) {
+	label := strings.TrimSpace(w.builder.String()[w.linkStart:])
+	if href == "" || strings.HasPrefix(href, "#") || label == href {
+		return
+	}
+	if label != "" {
+		w.builder.WriteString(" ")
+	}
+	w.builder.WriteString("(" + href + ")")
+	w.space = false
+}
+
+func (w *textWriter) String() string {
+	return w.builder.String()
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
@@ -0,0 +1,42 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLToText(t *testing.T) {
+	document := `<!DOCTYPE html>
+<html>
+	<head><title>Rapport</title><style>p { color: red; }</style></head>
+	<body>
+		<p>Bonjour,</p>
+		<p>Le statut   du portail
+			<strong>Portail Nord</strong> a changé.</p>
+		<div><strong>Motif :</strong> panne</div>
+		<div><strong>Date :</strong> 10/03/2025</div>
+		<h2>Visites (2)</h2>
+		<ul><li>Portail Nord</li><li>Portail Sud</li></ul>
+		<p>Cordialement,<br/>L&#39;équipe</p>
+		<p><a href="https://example.com/unsubscribe">Se désabonner</a> <a href="#top">Haut</a> <a href="https://example.com">https://example.com</a></p>
+	</body>
+</html>`
+
+	assert.Equal(t, `Bonjour,
+
+Le statut du portail Portail Nord a changé.
+
+Motif : panne
+Date : 10/03/2025
+
+Visites (2)
+
+- Portail Nord
+- Portail Sud
+
+Cordialement,
+L'équipe
+
+Se désabonner (https://example.com/unsubscribe) Haut https://example.com`, HTMLToText(document))
+}
@@ -0,0 +1,180 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+const (
+	exifTagOrientation        = 0x0112
+	exifTagDateTime           = 0x0132
+	exifTagExifIFDPointer     = 0x8769
+	exifTagDateTimeOriginal   = 0x9003
+	exifTagOffsetTimeOriginal = 0x9011
+
+	exifTypeShort = 3
+)
+
+// exifMetadata holds the EXIF fields the pipeline needs, everything else is
+// discarded when the image is re-encoded
+type exifMetadata struct {
+	Orientation int
+	CapturedAt  *time.Time
+}
+
+// readJPEGExif extracts the orientation and capture time from the EXIF
+// segment of a JPEG file. Missing or malformed metadata yields zero values.
+func readJPEGExif(data []byte) exifMetadata {
+	metadata := exifMetadata{Orientation: 1}
+
+	tiff := findJPEGExif(data)
+	if tiff == nil {
+		return metadata
+	}
+	parser, ok := newTiffParser(tiff)
+	if !ok {
+		return metadata
+	}
+
+	ifd0 := parser.readIFD(parser.order.Uint32(tiff[4:8]))
+	if entry, ok := ifd0[exifTagOrientation]; ok && entry.kind == exifTypeShort {
+		if orientation := int(parser.order.Uint16(entry.value[:2])); orientation >= 1 && orientation <= 8 {
+			metadata.Orientation = orientation
+		}
+	}
+
+	var dateTime, offset string
+	if entry, ok := ifd0[exifTagExifIFDPointer]; ok {
+		exifIFD := parser.readIFD(parser.order.Uint32(entry.value))
+		dateTime = parser.readString(exifIFD[exifTagDateTimeOriginal])
+		offset = parser.readString(exifIFD[exifTagOffsetTimeOriginal])
+	}
+	if dateTime == "" {
+		dateTime = parser.readString(ifd0[exifTagDateTime])
+	}
+	metadata.CapturedAt = parseExifTime(dateTime, offset)
+
+	return metadata
+}
+
+// findJPEGExif returns the TIFF structure embedded in the APP1 segment of a
+// JPEG file
+func findJPEGExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	position := 2
+	for position+4 <= len(data) {
+		if data[position] != 0xFF {
+			return nil
+		}
+		marker := data[position+1]
+		// Start of scan: no metadata segments follow
+		if marker == 0xDA {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[position+2 : position+4]))
+		if length < 2 || position+2+length > len(data) {
+			return nil
+		}
+		segment := data[position+4 : position+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		position += 2 + length
+	}
+	return nil
+}
+
+type tiffEntry struct {
+	kind  uint16
+	count uint32
+	value []byte
+}
+
+type tiffParser struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func newTiffParser(data []byte) (*tiffParser, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, false
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return nil, false
+	}
+	return &tiffParser{data: data, order: order}, true
+}
+
+// readIFD reads the entries of the image file directory at offset
+func (p *tiffParser) readIFD(offset uint32) map[uint16]tiffEntry {
+	entries := map[uint16]tiffEntry{}
+	if int(offset)+2 > len(p.data) {
+		return entries
+	}
+	count := int(p.order.Uint16(p.data[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		if start+12 > len(p.data) {
+			break
+		}
+		entry := p.data[start : start+12]
+		entries[p.order.Uint16(entry[0:2])] = tiffEntry{
+			kind:  p.order.Uint16(entry[2:4]),
+			count: p.order.Uint32(entry[4:8]),
+			value: entry[8:12],
+		}
+	}
+	return entries
+}
+
+// readString reads an ASCII entry, stored inline when it fits in 4 bytes
+func (p *tiffParser) readString(entry tiffEntry) string {
+	if entry.count == 0 {
+		return ""
+	}
+	raw := entry.value
+	if entry.count > 4 {
+		offset := p.order.Uint32(entry.value)
+		end := uint64(offset) + uint64(entry.count)
+		if end > uint64(len(p.data)) {
+			return ""
+		}
+		raw = p.data[offset:end]
+	} else {
+		raw = raw[:entry.count]
+	}
+	return strings.TrimRight(string(raw), "\x00 ")
+}
+
+// parseExifTime parses an EXIF date ("2006:01:02 15:04:05") with its optional
+// UTC offset ("+02:00"). Dates without offset are read in the server time zone.
+func parseExifTime(dateTime string, offset string) *time.Time {
+	if dateTime == "" {
+		return nil
+	}
+	var capturedAt time.Time
+	var err error
+	if offset != "" {
+		capturedAt, err = time.Parse("2006:01:02 15:04:05-07:00", dateTime+offset)
+	} else {
+		capturedAt, err = time.ParseInLocation("2006:01:02 15:04:05", dateTime, time.Local)
+	}
+	if err != nil || capturedAt.Year() < 1990 {
+		return nil
+	}
+	return &capturedAt
+}
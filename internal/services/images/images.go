@@ -0,0 +1,161 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// WebSize is the length of the longest edge of the copy shown in pages and reports
+	WebSize = 1600
+	// ThumbnailSize is the length of the longest edge of thumbnails
+	ThumbnailSize = 320
+	// MaxPixels bounds the resolution of accepted images so that decoding a
+	// small malicious file cannot exhaust the memory
+	MaxPixels = 50_000_000
+
+	webQuality       = 82
+	thumbnailQuality = 75
+)
+
+// ErrInvalidImage is returned when an upload is not an image the pipeline accepts
+var ErrInvalidImage = errors.New("invalid image")
+
+var acceptedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// Processed is the result of running an upload through the pipeline. Both
+// copies are JPEG files without any metadata.
+type Processed struct {
+	Web        []byte
+	Thumbnail  []byte
+	Width      int
+	Height     int
+	CapturedAt *time.Time
+}
+
+// Process validates an uploaded image, orients it according to its EXIF
+// data, and re-encodes a web sized copy and a thumbnail. Re-encoding drops
+// all the metadata of the original (GPS coordinates, device details...).
+func Process(data []byte) (*Processed, error) {
+	contentType := http.DetectContentType(data)
+	if !acceptedTypes[contentType] {
+		return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidImage, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: unsupported dimensions %dx%d", ErrInvalidImage, config.Width, config.Height)
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	metadata := exifMetadata{Orientation: 1}
+	if contentType == "image/jpeg" {
+		metadata = readJPEGExif(data)
+	}
+
+	web := render(source, metadata.Orientation, WebSize)
+	thumbnail := render(source, metadata.Orientation, ThumbnailSize)
+
+	processed := &Processed{
+		Width:      web.Bounds().Dx(),
+		Height:     web.Bounds().Dy(),
+		CapturedAt: metadata.CapturedAt,
+	}
+	if processed.Web, err = encodeJPEG(web, webQuality); err != nil {
+		return nil, err
+	}
+	if processed.Thumbnail, err = encodeJPEG(thumbnail, thumbnailQuality); err != nil {
+		return nil, err
+	}
+	return processed, nil
+}
+
+// render scales the source so that the longest edge of the oriented image is
+// at most maxSize, flattens transparency on white and applies the orientation
+func render(source image.Image, orientation int, maxSize int) image.Image {
+	bounds := source.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), maxSize)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), source, bounds, draw.Over, nil)
+
+	return orient(scaled, orientation)
+}
+
+// fit returns the dimensions of an image scaled down so that its longest
+// edge is at most maxSize. Images are never upscaled.
+func fit(width, height, maxSize int) (int, int) {
+	longest := max(width, height)
+	if longest <= maxSize {
+		return width, height
+	}
+	return max(1, width*maxSize/longest), max(1, height*maxSize/longest)
+}
+
+// orient applies an EXIF orientation (1 to 8) so the image displays upright
+func orient(source *image.RGBA, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return source
+	}
+
+	width, height := source.Bounds().Dx(), source.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	oriented := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = width-1-x, y
+			case 3: // rotated 180°
+				sx, sy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, height-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90° clockwise
+				sx, sy = y, height-1-x
+			case 7: // transversed
+				sx, sy = width-1-y, height-1-x
+			case 8: // rotated 90° counter clockwise
+				sx, sy = width-1-y, x
+			}
+			oriented.SetRGBA(x, y, source.RGBAAt(sx, sy))
+		}
+	}
+	return oriented
+}
+
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// halfImage returns an image whose left half is red and right half blue
+func halfImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifSegment builds an APP1 segment holding an orientation, a capture date
+// and a capture time zone
+func exifSegment(orientation uint16, dateTime string, offset string) []byte {
+	order := binary.LittleEndian
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+
+	entry := func(tag, kind uint16, count, value uint32) []byte {
+		b := make([]byte, 12)
+		order.PutUint16(b[0:], tag)
+		order.PutUint16(b[2:], kind)
+		order.PutUint32(b[4:], count)
+		order.PutUint32(b[8:], value)
+		return b
+	}
+
+	// IFD0 at 8 (30 bytes), Exif IFD at 38 (30 bytes), strings at 68
+	tiff = append(tiff, 2, 0)
+	tiff = append(tiff, entry(exifTagOrientation, exifTypeShort, 1, uint32(orientation))...)
+	tiff = append(tiff, entry(exifTagExifIFDPointer, 4, 1, 38)...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, 2, 0)
+	tiff = append(tiff, entry(exifTagDateTimeOriginal, 2, uint32(len(dateTime)+1), 68)...)
+	tiff = append(tiff, entry(exifTagOffsetTimeOriginal, 2, uint32(len(offset)+1), uint32(68+len(dateTime)+1))...)
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, dateTime+"\x00"+offset+"\x00"...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func jpegWithExif(t *testing.T, img image.Image, segment []byte) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	encoded := buf.Bytes()
+	return append(append([]byte{0xFF, 0xD8}, segment...), encoded[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func assertColor(t *testing.T, img image.Image, x, y int, red bool) {
+	r, _, b, _ := img.At(x, y).RGBA()
+	if red {
+		assert.Greater(t, r, b, "expected red at %d,%d", x, y)
+	} else {
+		assert.Greater(t, b, r, "expected blue at %d,%d", x, y)
+	}
+}
+
+func TestProcess_OrientsAndStripsMetadata(t *testing.T) {
+	data := jpegWithExif(t, halfImage(40, 20), exifSegment(6, "2024:05:06 07:08:09", "+02:00"))
+
+	processed, err := Process(data)
+	require.NoError(t, err)
+
+	// Rotated 90° clockwise: the red left half ends up on top
+	assert.Equal(t, 20, processed.Width)
+	assert.Equal(t, 40, processed.Height)
+	web := decode(t, processed.Web)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), web.Bounds())
+	assertColor(t, web, 10, 5, true)
+	assertColor(t, web, 10, 35, false)
+
+	require.NotNil(t, processed.CapturedAt)
+	assert.True(t, processed.CapturedAt.Equal(time.Date(2024, 5, 6, 5, 8, 9, 0, time.UTC)))
+
+	assert.NotContains(t, string(processed.Web), "Exif")
+	assert.NotContains(t, string(processed.Thumbnail), "Exif")
+}
+
+func TestProcess_Resizes(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, halfImage(3200, 1600)))
+
+	processed, err := Process(buf.Bytes())
+	require.NoError(t, err)
+
+	assert.Equal(t, WebSize, processed.Width)
+	assert.Equal(t, WebSize/2, processed.Height)
+	assert.Nil(t, processed.CapturedAt)
+	assert.Equal(t, image.Rect(0, 0, ThumbnailSize, ThumbnailSize/2), decode(t, processed.Thumbnail).Bounds())
+}
+
+func TestProcess_Rejects(t *testing.T) {
+	_, err := Process([]byte("%PDF-1.4 not an image"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	_, err = Process([]byte("\xFF\xD8\xFF\xE0 truncated jpeg"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestOrient(t *testing.T) {
+	source := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	source.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+	source.SetRGBA(1, 0, color.RGBA{B: 255, A: 255})
+
+	red := color.RGBA{R: 255, A: 255}
+	tests := map[int]image.Point{
+		1: {0, 0}, 2: {1, 0}, 3: {1, 0}, 4: {0, 0},
+		5: {0, 0}, 6: {0, 0}, 7: {0, 1}, 8: {0, 1},
+	}
+	for orientation, redAt := range tests {
+		oriented := orient(source, orientation)
+		assert.Equal(t, red, oriented.At(redAt.X, redAt.Y), "orientation %d", orientation)
+	}
+}
@@ -0,0 +1,237 @@
+package interventions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotAmendable is returned when amending an intervention that was not
+	// validated, drafts are edited instead
+	ErrNotAmendable = errors.New("only validated interventions can be amended")
+	// ErrAmendmentReasonRequired is returned when an amendment does not say
+	// why the intervention is corrected
+	ErrAmendmentReasonRequired = errors.New("a reason is required to amend an intervention")
+	// ErrNoChanges is returned when an amendment leaves the intervention as is
+	ErrNoChanges = errors.New("the amendment does not change the intervention")
+)
+
+// revisionPayload is the canonical content hashed into a revision
+type revisionPayload struct {
+	InterventionID uint                        `json:"intervention_id"`
+	Number         int                         `json:"number"`
+	PreviousHash   string                      `json:"previous_hash"`
+	Reason         string                      `json:"reason"`
+	AuthorID       uint                        `json:"author_id"`
+	AuthorName     string                      `json:"author_name"`
+	Before         models.InterventionSnapshot `json:"before"`
+	After          models.InterventionSnapshot `json:"after"`
+	CreatedAt      string                      `json:"created_at"`
+}
+
+// RevisionHash computes the hash of an amendment, linked to the previous
+// revision or, for the first amendment, to the chain hash of the intervention
+func RevisionHash(revision *models.InterventionRevision) (string, error) {
+	payload := revisionPayload{
+		InterventionID: revision.InterventionID,
+		Number:         revision.Number,
+		Reason:         revision.Reason,
+		AuthorID:       revision.AuthorID,
+		AuthorName:     revision.AuthorName,
+		Before:         revision.Before,
+		After:          revision.After,
+		CreatedAt:      chainTime(&revision.CreatedAt),
+	}
+	if revision.PreviousHash != nil {
+		payload.PreviousHash = *revision.PreviousHash
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode revision payload: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AmendIntervention corrects a validated intervention and records the change
+// as its next revision. The intervention must be locked with its controls
+// loaded. Its report is regenerated with the new revision the next time it is
+// requested.
+func AmendIntervention(tx *gorm.DB, intervention *models.Intervention, author *models.User, reason string, after models.InterventionSnapshot, at time.Time) (*models.InterventionRevision, error) {
+	if !author.IsSupervisor() {
+		return nil, ErrNotSupervisor
+	}
+	if intervention.Status != models.InterventionStatusValidated {
+		return nil, ErrNotAmendable
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrAmendmentReasonRequired
+	}
+
+	before := intervention.Snapshot()
+	if before.Equal(after) {
+		return nil, ErrNoChanges
+	}
+
+	var latest models.InterventionRevision
+	if err := tx.Where("intervention_id = ?", intervention.ID).Order("number desc").Limit(1).Find(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to find previous revision: %w", err)
+	}
+	previousHash := intervention.ChainHash
+	if latest.ID != 0 {
+		previousHash = &latest.Hash
+	}
+
+	revision := &models.InterventionRevision{
+		InterventionID: intervention.ID,
+		Number:         intervention.CurrentRevision() + 1,
+		Reason:         reason,
+		AuthorID:       author.ID,
+		AuthorName:     author.FullName(),
+		Before:         before,
+		After:          after,
+		PreviousHash:   previousHash,
+		// Stored with second precision so that the hash survives the
+		// database round trip
+		CreatedAt: at.UTC().Truncate(time.Second),
+	}
+	hash, err := RevisionHash(revision)
+	if err != nil {
+		return nil, err
+	}
+	revision.Hash = hash
+
+	if err := intervention.ApplySnapshot(after); err != nil {
+		return nil, fmt.Errorf("invalid amendment: %w", err)
+	}
+	err = tx.Model(intervention).Updates(map[string]any{
+		"date":                intervention.Date,
+		"arrived_at":          intervention.ArrivedAt,
+		"departed_at":         intervention.DepartedAt,
+		"summary":             intervention.Summary,
+		"fault":               intervention.Fault,
+		"work_done":           intervention.WorkDone,
+		"portal_status_after": intervention.PortalStatusAfter,
+		"revision":            revision.Number,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to amend intervention: %w", err)
+	}
+	for index := range intervention.Controls {
+		control := &intervention.Controls[index]
+		err := tx.Model(control).Updates(map[string]any{
+			"outcome":        control.Outcome,
+			"measured_value": control.MeasuredValue,
+			"remark":         control.Remark,
+		}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to amend control: %w", err)
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Create(revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+	intervention.Revision = revision.Number
+	intervention.Revisions = append(intervention.Revisions, *revision)
+
+	// Corrected outcomes may open or close non-conformities and change the
+	// status the intervention leaves the portal in
+	if changesPortal(before, after) {
+		if err := TrackNonConformities(tx, intervention, at); err != nil {
+			return nil, err
+		}
+		if err := UpdatePortalStatus(tx, intervention, author); err != nil {
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// changesPortal reports whether an amendment changes a control outcome or the
+// status the intervention leaves the portal in. Other corrections leave the
+// non-conformities and the portal status alone, which later interventions may
+// have changed since.
+func changesPortal(before, after models.InterventionSnapshot) bool {
+	if !reflect.DeepEqual(before.PortalStatusAfter, after.PortalStatusAfter) {
+		return true
+	}
+	outcomes := make(map[string]models.ControlOutcome, len(before.Controls))
+	for _, control := range before.Controls {
+		outcomes[control.Kind] = control.Outcome
+	}
+	for _, control := range after.Controls {
+		if outcomes[control.Kind] != control.Outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// sealedContent returns the intervention as it was sealed, before its
+// amendments
+func sealedContent(intervention *models.Intervention) (*models.Intervention, error) {
+	revisions := intervention.OrderedRevisions()
+	if len(revisions) == 0 {
+		return intervention, nil
+	}
+
+	sealed := *intervention
+	sealed.Controls = append([]models.Control(nil), intervention.Controls...)
+	if err := sealed.ApplySnapshot(revisions[0].Before); err != nil {
+		return nil, err
+	}
+	return &sealed, nil
+}
+
+// amendmentBreaks checks that the amendments of a sealed intervention follow
+// one another from its chain hash, and that the last one leads to its current
+// content
+func amendmentBreaks(intervention *models.Intervention) ([]models.ChainBreakReason, error) {
+	revisions := intervention.OrderedRevisions()
+	var reasons []models.ChainBreakReason
+
+	intact := intervention.CurrentRevision() == len(revisions)+1
+	previousHash := intervention.ChainHash
+	for index, revision := range revisions {
+		hash, err := RevisionHash(&revision)
+		if err != nil {
+			return nil, err
+		}
+		if revision.Number != index+2 || hash != revision.Hash || !sameHash(revision.PreviousHash, previousHash) {
+			intact = false
+		}
+		if index > 0 && !revisions[index-1].After.Equal(revision.Before) {
+			intact = false
+		}
+		previousHash = &revisions[index].Hash
+	}
+	if !intact {
+		reasons = append(reasons, models.ChainBreakAmendment)
+	}
+
+	// Content edited outside of an amendment
+	if len(revisions) > 0 && !revisions[len(revisions)-1].After.Equal(intervention.Snapshot()) {
+		reasons = append(reasons, models.ChainBreakContent)
+	}
+	return reasons, nil
+}
+
+func sameHash(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
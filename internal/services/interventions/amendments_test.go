@@ -0,0 +1,154 @@
+package interventions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+// amended records an amendment in memory the way AmendIntervention does
+func amended(t *testing.T, intervention *models.Intervention, reason string, change func(*models.Intervention)) {
+	previousHash := intervention.ChainHash
+	if revisions := intervention.OrderedRevisions(); len(revisions) > 0 {
+		previousHash = &revisions[len(revisions)-1].Hash
+	}
+
+	after := *intervention
+	after.Controls = append([]models.Control(nil), intervention.Controls...)
+	change(&after)
+
+	revision := models.InterventionRevision{
+		InterventionID: intervention.ID,
+		Number:         intervention.CurrentRevision() + 1,
+		Reason:         reason,
+		AuthorName:     "Jane Doe",
+		Before:         intervention.Snapshot(),
+		After:          after.Snapshot(),
+		PreviousHash:   previousHash,
+		CreatedAt:      time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC),
+	}
+	hash, err := RevisionHash(&revision)
+	require.NoError(t, err)
+	revision.Hash = hash
+
+	require.NoError(t, intervention.ApplySnapshot(revision.After))
+	intervention.Revision = revision.Number
+	intervention.Revisions = append(intervention.Revisions, revision)
+}
+
+func TestAmendIntervention_Checks(t *testing.T) {
+	supervisor := &models.User{Role: models.UserRoleSupervisor}
+	validated := func() *models.Intervention {
+		return &models.Intervention{Status: models.InterventionStatusValidated, Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)}
+	}
+	changed := validated().Snapshot()
+	changed.Date = "2025-01-11"
+
+	_, err := AmendIntervention(nil, validated(), &models.User{Role: models.UserRoleTechnician}, "Erreur de date", changed, time.Now())
+	assert.ErrorIs(t, err, ErrNotSupervisor)
+
+	submitted := validated()
+	submitted.Status = models.InterventionStatusSubmitted
+	_, err = AmendIntervention(nil, submitted, supervisor, "Erreur de date", changed, time.Now())
+	assert.ErrorIs(t, err, ErrNotAmendable)
+
+	_, err = AmendIntervention(nil, validated(), supervisor, "  ", changed, time.Now())
+	assert.ErrorIs(t, err, ErrAmendmentReasonRequired)
+
+	_, err = AmendIntervention(nil, validated(), supervisor, "Erreur de date", validated().Snapshot(), time.Now())
+	assert.ErrorIs(t, err, ErrNoChanges)
+}
+
+func TestChangesPortal(t *testing.T) {
+	outOfService := models.PortalStatusOutOfService
+	before := models.InterventionSnapshot{
+		Date: "2025-01-10",
+		Controls: []models.ControlSnapshot{
+			{Kind: "safety_cells", Outcome: models.ControlOutcomeNonCompliant},
+		},
+	}
+	corrected := func(change func(*models.InterventionSnapshot)) models.InterventionSnapshot {
+		after := before
+		after.Controls = append([]models.ControlSnapshot(nil), before.Controls...)
+		change(&after)
+		return after
+	}
+
+	summary := "Visite annuelle"
+	assert.False(t, changesPortal(before, corrected(func(s *models.InterventionSnapshot) { s.Summary = &summary })))
+	assert.False(t, changesPortal(before, corrected(func(s *models.InterventionSnapshot) { s.Controls[0].Remark = &summary })))
+	assert.True(t, changesPortal(before, corrected(func(s *models.InterventionSnapshot) { s.Controls[0].Outcome = models.ControlOutcomeCompliant })))
+	assert.True(t, changesPortal(before, corrected(func(s *models.InterventionSnapshot) { s.PortalStatusAfter = &outOfService })))
+}
+
+func TestChainBreaks_Amended(t *testing.T) {
+	chain := testChain(t)
+	amended(t, &chain[1], "Mesure mal saisie", func(i *models.Intervention) {
+		value := 290.0
+		i.Controls[0].MeasuredValue = &value
+	})
+	amended(t, &chain[1], "Date corrigée", func(i *models.Intervention) {
+		i.Date = time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)
+	})
+
+	assert.Equal(t, 3, chain[1].CurrentRevision())
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Empty(t, breaks)
+}
+
+func TestChainBreaks_EditedAfterAmendment(t *testing.T) {
+	chain := testChain(t)
+	amended(t, &chain[1], "Mesure mal saisie", func(i *models.Intervention) {
+		i.Controls[0].Outcome = models.ControlOutcomeNonCompliant
+	})
+	chain[1].Controls[0].Outcome = models.ControlOutcomeCompliant
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakContent}}, breaks)
+}
+
+func TestChainBreaks_TamperedRevision(t *testing.T) {
+	chain := testChain(t)
+	amended(t, &chain[1], "Mesure mal saisie", func(i *models.Intervention) {
+		i.Controls[0].Outcome = models.ControlOutcomeNonCompliant
+	})
+	chain[1].Revisions[0].Reason = "Demande du client"
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakAmendment}}, breaks)
+
+	// Rewriting the sealed content in the revision does not go unnoticed either
+	chain = testChain(t)
+	amended(t, &chain[1], "Mesure mal saisie", func(i *models.Intervention) {
+		i.Controls[0].Outcome = models.ControlOutcomeNonCompliant
+	})
+	chain[1].Revisions[0].Before.Controls[0].Outcome = models.ControlOutcomeNotChecked
+	hash, err := RevisionHash(&chain[1].Revisions[0])
+	require.NoError(t, err)
+	chain[1].Revisions[0].Hash = hash
+
+	breaks, err = chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakContent}}, breaks)
+}
+
+func TestChainBreaks_MissingRevision(t *testing.T) {
+	chain := testChain(t)
+	amended(t, &chain[1], "Mesure mal saisie", func(i *models.Intervention) {
+		i.Controls[0].Outcome = models.ControlOutcomeNonCompliant
+	})
+	chain[1].Revisions = nil
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{
+		{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakAmendment},
+		{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakContent},
+	}, breaks)
+}
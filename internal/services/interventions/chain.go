@@ -0,0 +1,291 @@
+package interventions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// chainPayload is the canonical content hashed into the chain. Fields are
+// only ever appended, and omitted when empty, so that the hashes of
+// interventions sealed before they existed stay valid.
+type chainPayload struct {
+	PortalID           uint                     `json:"portal_id"`
+	ChainIndex         int                      `json:"chain_index"`
+	PreviousHash       string                   `json:"previous_hash"`
+	OrganizationID     *uint                    `json:"organization_id"`
+	ReportNumber       *string                  `json:"report_number"`
+	Date               string                   `json:"date"`
+	UserID             uint                     `json:"user_id"`
+	UserName           string                   `json:"user_name"`
+	Summary            *string                  `json:"summary"`
+	ChecklistVersionID *uint                    `json:"checklist_version_id"`
+	Controls           []chainControlPayload    `json:"controls"`
+	Signatures         []chainSignaturePayload  `json:"signatures,omitempty"`
+	Type               *models.InterventionType `json:"type,omitempty"`
+	Fault              *string                  `json:"fault,omitempty"`
+	WorkDone           *string                  `json:"work_done,omitempty"`
+	ArrivedAt          string                   `json:"arrived_at,omitempty"`
+	DepartedAt         string                   `json:"departed_at,omitempty"`
+	PortalStatusAfter  *models.PortalStatus     `json:"portal_status_after,omitempty"`
+}
+
+type chainControlPayload struct {
+	Kind          string                `json:"kind"`
+	Outcome       models.ControlOutcome `json:"outcome"`
+	MeasuredValue *float64              `json:"measured_value"`
+	Remark        *string               `json:"remark"`
+	Photos        []string              `json:"photos"`
+}
+
+type chainSignaturePayload struct {
+	Party      models.SignatureParty `json:"party"`
+	SignerName string                `json:"signer_name"`
+	SignerRole string                `json:"signer_role"`
+	SHA256     string                `json:"sha256"`
+	SignedAt   string                `json:"signed_at"`
+}
+
+// ChainHash computes the hash sealing an intervention at a position of its
+// portal chain. It covers the intervention, its controls with the SHA-256 of
+// their photos, its signatures, and the hash of the previous intervention of
+// the chain.
+func ChainHash(intervention *models.Intervention, chainIndex int, previousHash string) (string, error) {
+	payload := chainPayload{
+		PortalID:           intervention.PortalID,
+		ChainIndex:         chainIndex,
+		PreviousHash:       previousHash,
+		OrganizationID:     intervention.OrganizationID,
+		ReportNumber:       intervention.ReportNumber,
+		Date:               intervention.Date.UTC().Format("2006-01-02"),
+		UserID:             intervention.UserID,
+		UserName:           intervention.UserName,
+		Summary:            intervention.Summary,
+		ChecklistVersionID: intervention.ChecklistVersionID,
+		Controls:           []chainControlPayload{},
+		Type:               intervention.Type,
+		Fault:              intervention.Fault,
+		WorkDone:           intervention.WorkDone,
+		ArrivedAt:          chainTime(intervention.ArrivedAt),
+		DepartedAt:         chainTime(intervention.DepartedAt),
+		PortalStatusAfter:  intervention.PortalStatusAfter,
+	}
+
+	for _, control := range intervention.Controls {
+		photos := []string{}
+		for _, photo := range control.Photos {
+			photos = append(photos, photo.SHA256)
+		}
+		sort.Strings(photos)
+
+		payload.Controls = append(payload.Controls, chainControlPayload{
+			Kind:          control.Kind,
+			Outcome:       control.Outcome,
+			MeasuredValue: control.MeasuredValue,
+			Remark:        control.Remark,
+			Photos:        photos,
+		})
+	}
+	sort.Slice(payload.Controls, func(i, j int) bool {
+		return payload.Controls[i].Kind < payload.Controls[j].Kind
+	})
+
+	for _, signature := range intervention.Signatures {
+		payload.Signatures = append(payload.Signatures, chainSignaturePayload{
+			Party:      signature.Party,
+			SignerName: signature.SignerName,
+			SignerRole: signature.SignerRole,
+			SHA256:     signature.SHA256,
+			SignedAt:   chainTime(&signature.SignedAt),
+		})
+	}
+	sort.Slice(payload.Signatures, func(i, j int) bool {
+		return payload.Signatures[i].Party < payload.Signatures[j].Party
+	})
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chain payload: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chainTime formats an optional timestamp independently of the time zone it
+// was loaded in
+func chainTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// SealIntervention appends a validated intervention to the hash chain of its
+// portal. It must run in the transaction that validates the intervention: the
+// portal row stays locked until commit so concurrent validations are chained
+// one after the other.
+func SealIntervention(tx *gorm.DB, interventionID uint) error {
+	var intervention models.Intervention
+	if err := tx.Preload("Controls.Photos").Preload("Signatures").First(&intervention, interventionID).Error; err != nil {
+		return err
+	}
+	if intervention.IsSealed() {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Portal{}, intervention.PortalID).Error; err != nil {
+		return fmt.Errorf("failed to lock portal: %w", err)
+	}
+
+	// Deleted interventions remain part of the chain
+	var previous models.Intervention
+	err := tx.Unscoped().
+		Where("portal_id = ? AND chain_index IS NOT NULL", intervention.PortalID).
+		Order("chain_index desc").
+		Limit(1).
+		Find(&previous).Error
+	if err != nil {
+		return fmt.Errorf("failed to find previous intervention: %w", err)
+	}
+
+	chainIndex := 1
+	previousHash := ""
+	if previous.ChainIndex != nil {
+		chainIndex = *previous.ChainIndex + 1
+		previousHash = *previous.ChainHash
+	}
+
+	hash, err := ChainHash(&intervention, chainIndex, previousHash)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]any{
+		"chain_index": chainIndex,
+		"chain_hash":  hash,
+	}
+	if previousHash != "" {
+		updates["previous_hash"] = previousHash
+	}
+	if err := tx.Model(&intervention).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to seal intervention: %w", err)
+	}
+	return nil
+}
+
+// VerifyPortalChain walks the hash chain of a portal from its first
+// intervention and reports every break: edited content, altered links or
+// amendments, gaps left by removed rows and deleted interventions.
+func VerifyPortalChain(db *gorm.DB, portalID uint) (*models.ChainReport, error) {
+	var chain []models.Intervention
+	err := db.Unscoped().
+		Preload("Signatures").
+		Preload("Revisions").
+		Where("portal_id = ? AND chain_index IS NOT NULL", portalID).
+		Order("chain_index").
+		Find(&chain).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load portal chain: %w", err)
+	}
+
+	// Controls are loaded separately: an unscoped preload would include
+	// deleted controls and hide their deletion
+	interventionIDs := make([]uint, len(chain))
+	for i := range chain {
+		interventionIDs[i] = chain[i].ID
+	}
+	var controls []models.Control
+	if len(interventionIDs) > 0 {
+		if err := db.Preload("Photos").Where("intervention_id IN ?", interventionIDs).Find(&controls).Error; err != nil {
+			return nil, fmt.Errorf("failed to load portal chain controls: %w", err)
+		}
+	}
+	for i := range chain {
+		for _, control := range controls {
+			if control.InterventionID == chain[i].ID {
+				chain[i].Controls = append(chain[i].Controls, control)
+			}
+		}
+	}
+
+	// Interventions waiting for validation are sealed once validated
+	var unsealed int64
+	if err := db.Model(&models.Intervention{}).Where("portal_id = ? AND status = ? AND chain_index IS NULL", portalID, models.InterventionStatusValidated).Count(&unsealed).Error; err != nil {
+		return nil, fmt.Errorf("failed to count unsealed interventions: %w", err)
+	}
+
+	breaks, err := chainBreaks(chain)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ChainReport{PortalID: portalID, Length: len(chain), Unsealed: int(unsealed), Breaks: breaks}, nil
+}
+
+// chainBreaks walks sealed interventions ordered by chain index, with their
+// controls loaded
+func chainBreaks(chain []models.Intervention) ([]models.ChainBreak, error) {
+	var breaks []models.ChainBreak
+	previousIndex := 0
+	previousHash := ""
+	for _, intervention := range chain {
+		chainIndex := *intervention.ChainIndex
+		addBreak := func(reason models.ChainBreakReason) {
+			breaks = append(breaks, models.ChainBreak{InterventionID: intervention.ID, ChainIndex: chainIndex, Reason: reason})
+		}
+
+		if chainIndex != previousIndex+1 {
+			addBreak(models.ChainBreakGap)
+		}
+
+		storedPrevious := ""
+		if intervention.PreviousHash != nil {
+			storedPrevious = *intervention.PreviousHash
+		}
+		if storedPrevious != previousHash {
+			addBreak(models.ChainBreakLink)
+		}
+
+		if intervention.DeletedAt.Valid {
+			addBreak(models.ChainBreakDeleted)
+		}
+
+		// Amended interventions are hashed with the content they were sealed with
+		sealed, err := sealedContent(&intervention)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := ChainHash(sealed, chainIndex, storedPrevious)
+		if err != nil {
+			return nil, err
+		}
+		contentEdited := intervention.ChainHash == nil || hash != *intervention.ChainHash
+
+		amendmentReasons, err := amendmentBreaks(&intervention)
+		if err != nil {
+			return nil, err
+		}
+		for _, reason := range amendmentReasons {
+			if reason == models.ChainBreakContent {
+				contentEdited = true
+			} else {
+				addBreak(reason)
+			}
+		}
+		if contentEdited {
+			addBreak(models.ChainBreakContent)
+		}
+
+		previousIndex = chainIndex
+		if intervention.ChainHash != nil {
+			previousHash = *intervention.ChainHash
+		}
+	}
+	return breaks, nil
+}
@@ -0,0 +1,152 @@
+package interventions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// sealedChain seals interventions in memory the way SealIntervention does
+func sealedChain(t *testing.T, chain []models.Intervention) []models.Intervention {
+	previousHash := ""
+	for i := range chain {
+		index := i + 1
+		hash, err := ChainHash(&chain[i], index, previousHash)
+		require.NoError(t, err)
+
+		chain[i].ChainIndex = &index
+		if previousHash != "" {
+			previous := previousHash
+			chain[i].PreviousHash = &previous
+		}
+		chain[i].ChainHash = &hash
+		previousHash = hash
+	}
+	return chain
+}
+
+func testChain(t *testing.T) []models.Intervention {
+	value := 310.0
+	return sealedChain(t, []models.Intervention{
+		{ID: 1, PortalID: 4, Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), UserName: "John Doe", Controls: []models.Control{
+			{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant},
+		}},
+		{ID: 2, PortalID: 4, Date: time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), UserName: "John Doe", Controls: []models.Control{
+			{Kind: "force_limiter", Outcome: models.ControlOutcomeCompliant, MeasuredValue: &value},
+		}},
+		{ID: 3, PortalID: 4, Date: time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC), UserName: "Jane Doe"},
+	})
+}
+
+func TestChainHash_IsCanonical(t *testing.T) {
+	intervention := models.Intervention{
+		PortalID: 4,
+		Date:     time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+		Controls: []models.Control{
+			{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant, Photos: []models.ControlPhoto{{SHA256: "b"}, {SHA256: "a"}}},
+			{Kind: "emergency_stop", Outcome: models.ControlOutcomeNonCompliant},
+		},
+	}
+	hash, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// Loading order and database time zone do not change the hash
+	reordered := intervention
+	reordered.Date = intervention.Date.In(time.FixedZone("UTC+2", 2*60*60))
+	reordered.Controls = []models.Control{
+		intervention.Controls[1],
+		{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant, Photos: []models.ControlPhoto{{SHA256: "a"}, {SHA256: "b"}}},
+	}
+	reorderedHash, err := ChainHash(&reordered, 1, "")
+	require.NoError(t, err)
+	assert.Equal(t, hash, reorderedHash)
+
+	otherPosition, err := ChainHash(&intervention, 2, hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, otherPosition)
+}
+
+func TestChainHash_CoversSignatures(t *testing.T) {
+	intervention := models.Intervention{PortalID: 4, Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)}
+	unsigned, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+
+	intervention.Signatures = []models.InterventionSignature{
+		{Party: models.SignaturePartyCustomer, SignerName: "Marie Martin", SHA256: "abc", SignedAt: time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)},
+	}
+	signed, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, unsigned, signed)
+
+	intervention.Signatures[0].SHA256 = "def"
+	replaced, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, signed, replaced)
+}
+
+func TestChainHash_CoversInterventionDetails(t *testing.T) {
+	intervention := models.Intervention{PortalID: 4, Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)}
+	legacy, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+
+	status := models.PortalStatusInService
+	intervention.PortalStatusAfter = &status
+	withStatus, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, legacy, withStatus)
+
+	status = models.PortalStatusOutOfService
+	outOfService, err := ChainHash(&intervention, 1, "")
+	require.NoError(t, err)
+	assert.NotEqual(t, withStatus, outOfService)
+}
+
+func TestChainBreaks_ValidChain(t *testing.T) {
+	breaks, err := chainBreaks(testChain(t))
+	require.NoError(t, err)
+	assert.Empty(t, breaks)
+}
+
+func TestChainBreaks_EditedContent(t *testing.T) {
+	chain := testChain(t)
+	chain[1].Controls[0].Outcome = models.ControlOutcomeNonCompliant
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 2, ChainIndex: 2, Reason: models.ChainBreakContent}}, breaks)
+}
+
+func TestChainBreaks_Backdated(t *testing.T) {
+	chain := testChain(t)
+	chain[2].Date = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 3, ChainIndex: 3, Reason: models.ChainBreakContent}}, breaks)
+}
+
+func TestChainBreaks_RemovedIntervention(t *testing.T) {
+	chain := testChain(t)
+	chain = append(chain[:1], chain[2:]...)
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{
+		{InterventionID: 3, ChainIndex: 3, Reason: models.ChainBreakGap},
+		{InterventionID: 3, ChainIndex: 3, Reason: models.ChainBreakLink},
+	}, breaks)
+}
+
+func TestChainBreaks_DeletedIntervention(t *testing.T) {
+	chain := testChain(t)
+	chain[0].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	breaks, err := chainBreaks(chain)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChainBreak{{InterventionID: 1, ChainIndex: 1, Reason: models.ChainBreakDeleted}}, breaks)
+}
@@ -5,28 +5,49 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/skip2/go-qrcode"
 	"github.com/troptropcontent/qr_code_maintenance/internal/models"
 	"github.com/troptropcontent/qr_code_maintenance/internal/services"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
 	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
 	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
 )
 
+// reportQRCodeFileName is the name of the verification QR code among the
+// files sent to Gotenberg alongside the report
+const reportQRCodeFileName = "verification_qr.png"
+
 // PDFService handles PDF generation for interventions
 type PDFService struct {
 	gotenbergService *services.GotenbergService
+	storage          *storage.Service
 }
 
-// NewPDFService creates a new intervention PDF service
-func NewPDFService(gotenbergURL string) *PDFService {
+// NewPDFService creates a new intervention PDF service. Photos embedded in
+// the reports are read from store.
+func NewPDFService(gotenbergURL string, store *storage.Service) *PDFService {
 	return &PDFService{
 		gotenbergService: services.NewGotenbergService(gotenbergURL),
+		storage:          store,
 	}
 }
 
-// GenerateReport generates a PDF report for an intervention
-func (s *PDFService) GenerateReportPDF(intervention *models.Intervention) (*os.File, error) {
+// GenerateReport generates a PDF report for an intervention. When a
+// verification code is given, the report embeds it along with a QR code
+// pointing to the public verification page. Photos and signatures are read
+// from the storage with ctx.
+func (s *PDFService) GenerateReportPDF(ctx context.Context, intervention *models.Intervention, verificationCode string) (*os.File, error) {
+	var verification *templates.ReportVerification
+	if verificationCode != "" {
+		verification = &templates.ReportVerification{
+			Code:      FormatVerificationCode(verificationCode),
+			URL:       VerificationURL(verificationCode),
+			QRCodeURL: reportQRCodeFileName,
+		}
+	}
+
 	// Render intervention template
-	html_string, err := s.renderInterventionHTML(intervention)
+	html_string, err := s.renderInterventionHTML(ctx, intervention, verification)
 	if err != nil {
 		return nil, fmt.Errorf("failed to render intervention HTML: %w", err)
 	}
@@ -50,6 +71,42 @@ func (s *PDFService) GenerateReportPDF(intervention *models.Intervention) (*os.F
 		ContentBytes: css_bytes,
 	})
 
+	if verification != nil {
+		qr_bytes, err := qrcode.Encode(verification.URL, qrcode.Medium, 256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate verification QR code: %w", err)
+		}
+		files = append(files, services.ConvertHtmlToPdfFiles{
+			Name:         reportQRCodeFileName,
+			ContentBytes: qr_bytes,
+		})
+	}
+
+	// Photos are sent alongside the HTML and referenced by file name
+	for _, control := range intervention.Controls {
+		for _, photo := range control.Photos {
+			photo_bytes, err := s.storage.ReadAll(ctx, photo.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read photo %d: %w", photo.ID, err)
+			}
+			files = append(files, services.ConvertHtmlToPdfFiles{
+				Name:         templates.ReportPhotoFileName(photo),
+				ContentBytes: photo_bytes,
+			})
+		}
+	}
+
+	for _, signature := range intervention.Signatures {
+		signature_bytes, err := s.storage.ReadAll(ctx, signature.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature %d: %w", signature.ID, err)
+		}
+		files = append(files, services.ConvertHtmlToPdfFiles{
+			Name:         templates.ReportSignatureFileName(signature),
+			ContentBytes: signature_bytes,
+		})
+	}
+
 	// Generate PDF using Gotenberg service
 	tempFile, err := s.gotenbergService.ConvertHTMLToPDF(files, "intervention_report")
 	if err != nil {
@@ -60,11 +117,17 @@ func (s *PDFService) GenerateReportPDF(intervention *models.Intervention) (*os.F
 }
 
 // renderInterventionHTML renders the intervention template to HTML string
-func (s *PDFService) renderInterventionHTML(intervention *models.Intervention) (string, error) {
+func (s *PDFService) renderInterventionHTML(ctx context.Context, intervention *models.Intervention, verification *templates.ReportVerification) (string, error) {
 	var buf []byte
 	htmlBuffer := &htmlWriter{buf: buf}
 
-	if err := templates.InterventionReport(templates.InterventionReportConfig{Intervention: intervention, StylesheetPath: "output.css"}).Render(context.Background(), htmlBuffer); err != nil {
+	if err := templates.InterventionReport(templates.InterventionReportConfig{
+		Intervention:   intervention,
+		StylesheetPath: "output.css",
+		PhotoURL:       templates.ReportPhotoFileName,
+		SignatureURL:   templates.ReportSignatureFileName,
+		Verification:   verification,
+	}).Render(ctx, htmlBuffer); err != nil {
 		return "", fmt.Errorf("failed to render template: %w", err)
 	}
 
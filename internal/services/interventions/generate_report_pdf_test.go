@@ -1,21 +1,24 @@
 package interventions
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
 )
 
 func TestNewPDFService(t *testing.T) {
 	gotenbergURL := "http://localhost:3000"
-	service := NewPDFService(gotenbergURL)
+	service := NewPDFService(gotenbergURL, nil)
 
 	assert.NotNil(t, service)
 	assert.NotNil(t, service.gotenbergService)
@@ -46,6 +49,7 @@ func TestPDFService_GenerateReportPDF_Success(t *testing.T) {
 		assert.Contains(t, htmlContent, "Test Portal")
 		assert.Contains(t, htmlContent, "John Doe")
 		assert.Contains(t, htmlContent, "Test summary")
+		assert.Contains(t, htmlContent, "Cellules de sécurité")
 
 		// Return mock PDF
 		w.Header().Set("Content-Type", "application/pdf")
@@ -53,10 +57,10 @@ func TestPDFService_GenerateReportPDF_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	service := NewPDFService(server.URL)
+	service := NewPDFService(server.URL, nil)
 	intervention := createTestIntervention()
 
-	tempFile, err := service.GenerateReportPDF(intervention)
+	tempFile, err := service.GenerateReportPDF(context.Background(), intervention, "")
 	require.NoError(t, err)
 	defer func() {
 		tempFile.Close()
@@ -73,12 +77,128 @@ func TestPDFService_GenerateReportPDF_Success(t *testing.T) {
 	assert.Equal(t, mockPDF, content)
 }
 
+// gotenbergFiles returns the content of the files sent to Gotenberg by name
+func gotenbergFiles(t *testing.T, r *http.Request) map[string]string {
+	require.NoError(t, r.ParseMultipartForm(10<<20))
+
+	files := map[string]string{}
+	for _, header := range r.MultipartForm.File["files"] {
+		file, err := header.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		file.Close()
+		files[header.Filename] = string(content)
+	}
+	return files
+}
+
+func TestPDFService_GenerateReportPDF_WithPhotos(t *testing.T) {
+	backend := storage.NewLocalBackend(t.TempDir())
+	store := storage.NewService(backend, "secret")
+
+	photo := models.ControlPhoto{ID: 7, FileName: "cell.jpg", ContentType: "image/jpeg", Key: "photos/ab/cd/abcd.jpg"}
+	require.NoError(t, backend.Put(context.Background(), photo.Key, strings.NewReader("jpeg bytes"), 10, photo.ContentType))
+
+	signature := models.InterventionSignature{ID: 3, Party: models.SignaturePartyCustomer, SignerName: "Marie Martin", SignerRole: "Gardienne", Key: "signatures/ab/cd/abcd.svg", SHA256: "abcd"}
+	require.NoError(t, backend.Put(context.Background(), signature.Key, strings.NewReader(signatureSVG), int64(len(signatureSVG)), "image/svg+xml"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files := gotenbergFiles(t, r)
+
+		assert.Equal(t, "jpeg bytes", files["photo_7.jpg"])
+		assert.Contains(t, files["index.html"], `src="photo_7.jpg"`)
+		assert.Contains(t, files["index.html"], "Annexe - Photos")
+		assert.Contains(t, files["index.html"], "Cellule encrassée")
+
+		assert.Equal(t, signatureSVG, files["signature_customer.svg"])
+		assert.Contains(t, files["index.html"], `src="signature_customer.svg"`)
+		assert.Contains(t, files["index.html"], "Marie Martin")
+		assert.Contains(t, files["index.html"], "Non signé")
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	}))
+	defer server.Close()
+
+	remark := "Cellule encrassée"
+	intervention := createTestIntervention()
+	intervention.Controls = []models.Control{
+		{Kind: "safety_cells", Outcome: models.ControlOutcomeNonCompliant, Remark: &remark, Photos: []models.ControlPhoto{photo}},
+	}
+	intervention.Signatures = []models.InterventionSignature{signature}
+
+	tempFile, err := NewPDFService(server.URL, store).GenerateReportPDF(context.Background(), intervention, "")
+	require.NoError(t, err)
+	tempFile.Close()
+	os.Remove(tempFile.Name())
+}
+
+func TestPDFService_GenerateReportPDF_WithVerification(t *testing.T) {
+	t.Setenv(PUBLIC_BASE_URL_ENV_VAR, "https://portails.example.com")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files := gotenbergFiles(t, r)
+
+		assert.True(t, strings.HasPrefix(files[reportQRCodeFileName], "\x89PNG"))
+		assert.Contains(t, files["index.html"], `src="verification_qr.png"`)
+		assert.Contains(t, files["index.html"], "https://portails.example.com/verify/ABCDEFGH23")
+		assert.Contains(t, files["index.html"], "ABCDE-FGH23")
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	}))
+	defer server.Close()
+
+	tempFile, err := NewPDFService(server.URL, nil).GenerateReportPDF(context.Background(), createTestIntervention(), "ABCDEFGH23")
+	require.NoError(t, err)
+	tempFile.Close()
+	os.Remove(tempFile.Name())
+}
+
+func TestPDFService_GenerateReportPDF_InterventionType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		html := gotenbergFiles(t, r)["index.html"]
+
+		assert.Contains(t, html, "Dépannage d&#39;urgence")
+		assert.Contains(t, html, "Moteur bloqué")
+		assert.Contains(t, html, "Carte électronique remplacée")
+		assert.Contains(t, html, "Hors service")
+		assert.Contains(t, html, "durée 1 h 05")
+		assert.NotContains(t, html, "Tableau des contrôles")
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	}))
+	defer server.Close()
+
+	interventionType := models.InterventionTypeEmergency
+	status := models.PortalStatusOutOfService
+	fault := "Moteur bloqué"
+	workDone := "Carte électronique remplacée"
+	arrivedAt := time.Date(2025, 1, 15, 9, 0, 0, 0, time.Local)
+	departedAt := arrivedAt.Add(65 * time.Minute)
+
+	intervention := createTestIntervention()
+	intervention.Type = &interventionType
+	intervention.PortalStatusAfter = &status
+	intervention.Fault = &fault
+	intervention.WorkDone = &workDone
+	intervention.ArrivedAt = &arrivedAt
+	intervention.DepartedAt = &departedAt
+
+	tempFile, err := NewPDFService(server.URL, nil).GenerateReportPDF(context.Background(), intervention, "")
+	require.NoError(t, err)
+	tempFile.Close()
+	os.Remove(tempFile.Name())
+}
+
 func TestPDFService_GenerateReportPDF_EmptyData(t *testing.T) {
 	// Use invalid URL to simulate network error and test early failure
-	service := NewPDFService("http://invalid-url:9999")
+	service := NewPDFService("http://invalid-url:9999", nil)
 	intervention := &models.Intervention{}
 
-	tempFile, err := service.GenerateReportPDF(intervention)
+	tempFile, err := service.GenerateReportPDF(context.Background(), intervention, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, tempFile)
@@ -93,10 +213,10 @@ func TestPDFService_GenerateReportPDF_GotenbergError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	service := NewPDFService(server.URL)
+	service := NewPDFService(server.URL, nil)
 	intervention := createTestIntervention()
 
-	tempFile, err := service.GenerateReportPDF(intervention)
+	tempFile, err := service.GenerateReportPDF(context.Background(), intervention, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, tempFile)
@@ -105,10 +225,10 @@ func TestPDFService_GenerateReportPDF_GotenbergError(t *testing.T) {
 
 func TestPDFService_GenerateReportPDF_NetworkError(t *testing.T) {
 	// Use invalid URL to simulate network error
-	service := NewPDFService("http://invalid-gotenberg-url:9999")
+	service := NewPDFService("http://invalid-gotenberg-url:9999", nil)
 	intervention := createTestIntervention()
 
-	tempFile, err := service.GenerateReportPDF(intervention)
+	tempFile, err := service.GenerateReportPDF(context.Background(), intervention, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, tempFile)
@@ -154,10 +274,10 @@ func TestPDFService_Integration_EndToEnd(t *testing.T) {
 	}))
 	defer server.Close()
 
-	service := NewPDFService(server.URL)
+	service := NewPDFService(server.URL, nil)
 	intervention := createTestIntervention()
 
-	tempFile, err := service.GenerateReportPDF(intervention)
+	tempFile, err := service.GenerateReportPDF(context.Background(), intervention, "")
 	require.NoError(t, err, "Failed to generate PDF report")
 
 	defer func() {
@@ -196,6 +316,13 @@ func createTestIntervention() *models.Intervention {
 			ID:   1,
 			Name: "Test Portal",
 		},
+		ChecklistVersion: &models.ChecklistVersion{
+			ID:      1,
+			Version: 1,
+			Items: models.ChecklistItems{
+				{Code: "safety_cells", Kind: models.ControlKindSecurity, Label: "Cellules de sécurité"},
+			},
+		},
 		User: models.User{
 			ID:        1,
 			FirstName: "John",
@@ -0,0 +1,29 @@
+package interventions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"gorm.io/gorm"
+)
+
+// SendReportJob attaches the latest report of an intervention to its report
+// emails queued when it was validated or amended
+const SendReportJob jobs.Type[SendReportPayload] = "intervention.send_report"
+
+type SendReportPayload struct {
+	InterventionID uint `json:"intervention_id"`
+}
+
+// SendReport returns the handler of SendReportJob
+func SendReport(notificationService *NotificationService) func(context.Context, SendReportPayload) error {
+	return func(ctx context.Context, payload SendReportPayload) error {
+		err := notificationService.AttachInterventionReport(ctx, payload.InterventionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return jobs.Permanent(fmt.Errorf("intervention %d not found", payload.InterventionID))
+		}
+		return err
+	}
+}
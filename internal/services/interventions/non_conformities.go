@@ -0,0 +1,127 @@
+package interventions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/notifications"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrackNonConformities updates the non-conformities of the portal with a
+// validated intervention: the open ones it resolves are closed, and its
+// failed controls open new ones, which are emailed to the recipients of the
+// portal
+func TrackNonConformities(tx *gorm.DB, intervention *models.Intervention, at time.Time) error {
+	if err := tx.Preload("Controls").Preload("ChecklistVersion.Items").Preload("CorrectiveActions").First(intervention, intervention.ID).Error; err != nil {
+		return fmt.Errorf("failed to load intervention: %w", err)
+	}
+
+	var open []models.NonConformity
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("portal_id = ? AND status = ?", intervention.PortalID, models.NonConformityStatusOpen).
+		Find(&open).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch open non-conformities: %w", err)
+	}
+
+	// An amended intervention does not open again the non-conformities it
+	// opened when it was validated
+	var reported []string
+	if err := tx.Model(&models.NonConformity{}).Where("opened_intervention_id = ?", intervention.ID).Pluck("code", &reported).Error; err != nil {
+		return fmt.Errorf("failed to fetch reported non-conformities: %w", err)
+	}
+
+	closed, opened := nonConformityChanges(intervention, open, reported, at)
+	for _, nonConformity := range closed {
+		err := tx.Model(&nonConformity).Updates(map[string]any{
+			"status":                 nonConformity.Status,
+			"resolution":             nonConformity.Resolution,
+			"closed_intervention_id": nonConformity.ClosedInterventionID,
+			"closed_at":              nonConformity.ClosedAt,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to close non-conformity: %w", err)
+		}
+	}
+	if len(opened) == 0 {
+		return nil
+	}
+	if err := tx.Omit(clause.Associations).Create(&opened).Error; err != nil {
+		return fmt.Errorf("failed to open non-conformities: %w", err)
+	}
+
+	var portal models.Portal
+	if err := tx.First(&portal, intervention.PortalID).Error; err != nil {
+		return fmt.Errorf("failed to load portal: %w", err)
+	}
+	_, err = notifications.Notify(tx.Statement.Context, tx, &portal, models.NotificationEventNonConformityOpened, nonConformitiesEmail(&portal, intervention, opened), templates.NonConformitiesEmail(&portal, intervention, opened))
+	return err
+}
+
+// nonConformitiesEmail returns the email telling about the non-conformities
+// an intervention opened, to be sent to the recipients of the portal
+func nonConformitiesEmail(portal *models.Portal, intervention *models.Intervention, opened []models.NonConformity) *models.OutboxEmail {
+	return &models.OutboxEmail{
+		Kind:           models.OutboxEmailKindNonConformity,
+		Subject:        fmt.Sprintf("Portail %s : %d non-conformité(s) ouverte(s)", portal.Name, len(opened)),
+		InterventionID: &intervention.ID,
+	}
+}
+
+// nonConformityChanges returns the open non-conformities the intervention
+// closes, because it found the item compliant or recorded a corrective
+// action, and the ones its non-compliant controls open. Items that stay
+// non-compliant keep their open non-conformity, and the items reported are
+// those the intervention already opened a non-conformity for.
+func nonConformityChanges(intervention *models.Intervention, open []models.NonConformity, reported []string, at time.Time) (closed []models.NonConformity, opened []models.NonConformity) {
+	stillOpen := make(map[string]bool, len(open)+len(reported))
+	for _, code := range reported {
+		stillOpen[code] = true
+	}
+	for _, nonConformity := range open {
+		var resolution models.NonConformityResolution
+		if control := intervention.Control(nonConformity.Code); control != nil && control.Outcome == models.ControlOutcomeCompliant {
+			resolution = models.NonConformityResolutionCompliant
+		} else if intervention.CorrectiveAction(nonConformity.ID) != nil {
+			resolution = models.NonConformityResolutionCorrectiveAction
+		} else {
+			stillOpen[nonConformity.Code] = true
+			continue
+		}
+
+		closedAt := at
+		nonConformity.Status = models.NonConformityStatusClosed
+		nonConformity.Resolution = &resolution
+		nonConformity.ClosedInterventionID = &intervention.ID
+		nonConformity.ClosedAt = &closedAt
+		closed = append(closed, nonConformity)
+	}
+
+	checklist := intervention.Checklist()
+	for _, control := range intervention.Controls {
+		if control.Outcome != models.ControlOutcomeNonCompliant || stillOpen[control.Kind] {
+			continue
+		}
+
+		severity := models.NonConformitySeverityMinor
+		if item := checklist.Find(control.Kind); item != nil {
+			severity = models.SeverityForKind(item.Kind)
+		}
+		assigneeID := intervention.UserID
+		opened = append(opened, models.NonConformity{
+			PortalID:             intervention.PortalID,
+			Code:                 control.Kind,
+			Label:                checklist.Label(control.Kind),
+			Severity:             severity,
+			Status:               models.NonConformityStatusOpen,
+			DueDate:              intervention.Date.Add(severity.ResolutionDelay()),
+			AssigneeID:           &assigneeID,
+			OpenedInterventionID: intervention.ID,
+		})
+	}
+	return closed, opened
+}
@@ -0,0 +1,114 @@
+package interventions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+func nonConformityIntervention(controls ...models.Control) *models.Intervention {
+	return &models.Intervention{
+		ID:       7,
+		PortalID: 4,
+		UserID:   2,
+		Date:     time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+		ChecklistVersion: &models.ChecklistVersion{Items: models.ChecklistItems{
+			{Code: "safety_cells", Kind: models.ControlKindSecurity, Label: "Cellules de sécurité"},
+			{Code: "lubrication", Kind: models.ControlKindOther, Label: "Graissage"},
+		}},
+		Controls: controls,
+	}
+}
+
+func TestNonConformityChanges_OpensFailedControls(t *testing.T) {
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeNonCompliant},
+		models.Control{Kind: "lubrication", Outcome: models.ControlOutcomeNonCompliant},
+		models.Control{Kind: "unknown", Outcome: models.ControlOutcomeCompliant},
+	)
+
+	closed, opened := nonConformityChanges(intervention, nil, nil, time.Now())
+	assert.Empty(t, closed)
+	require.Len(t, opened, 2)
+
+	assert.Equal(t, "safety_cells", opened[0].Code)
+	assert.Equal(t, "Cellules de sécurité", opened[0].Label)
+	assert.Equal(t, models.NonConformitySeverityMajor, opened[0].Severity)
+	assert.Equal(t, time.Date(2025, 3, 25, 0, 0, 0, 0, time.UTC), opened[0].DueDate)
+	assert.Equal(t, uint(7), opened[0].OpenedInterventionID)
+	assert.Equal(t, uint(4), opened[0].PortalID)
+	require.NotNil(t, opened[0].AssigneeID)
+	assert.Equal(t, uint(2), *opened[0].AssigneeID)
+
+	assert.Equal(t, models.NonConformitySeverityMinor, opened[1].Severity)
+	assert.Equal(t, time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC), opened[1].DueDate)
+}
+
+func TestNonConformityChanges_ClosesResolved(t *testing.T) {
+	at := time.Date(2025, 3, 11, 9, 0, 0, 0, time.UTC)
+	open := []models.NonConformity{
+		{ID: 1, Code: "safety_cells", Status: models.NonConformityStatusOpen},
+		{ID: 2, Code: "lubrication", Status: models.NonConformityStatusOpen},
+		{ID: 3, Code: "force_limiter", Status: models.NonConformityStatusOpen},
+	}
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant},
+		models.Control{Kind: "lubrication", Outcome: models.ControlOutcomeNonCompliant},
+	)
+	intervention.CorrectiveActions = []models.CorrectiveAction{{NonConformityID: 3, Description: "Limiteur remplacé"}}
+
+	closed, opened := nonConformityChanges(intervention, open, nil, at)
+	require.Len(t, closed, 2)
+
+	assert.Equal(t, uint(1), closed[0].ID)
+	assert.Equal(t, models.NonConformityStatusClosed, closed[0].Status)
+	assert.Equal(t, models.NonConformityResolutionCompliant, *closed[0].Resolution)
+	assert.Equal(t, uint(7), *closed[0].ClosedInterventionID)
+	assert.Equal(t, at, *closed[0].ClosedAt)
+
+	assert.Equal(t, uint(3), closed[1].ID)
+	assert.Equal(t, models.NonConformityResolutionCorrectiveAction, *closed[1].Resolution)
+
+	// Still failing, the non-conformity already open keeps being tracked
+	assert.Empty(t, opened)
+}
+
+func TestNonConformityChanges_Amended(t *testing.T) {
+	at := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	open := []models.NonConformity{
+		{ID: 1, Code: "safety_cells", Status: models.NonConformityStatusOpen, OpenedInterventionID: 7},
+	}
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant},
+		models.Control{Kind: "lubrication", Outcome: models.ControlOutcomeNonCompliant},
+	)
+
+	// The failed check corrected to compliant closes its non-conformity, the
+	// one closed since is not opened again
+	closed, opened := nonConformityChanges(intervention, open, []string{"safety_cells", "lubrication"}, at)
+	require.Len(t, closed, 1)
+	assert.Equal(t, uint(1), closed[0].ID)
+	assert.Equal(t, models.NonConformityResolutionCompliant, *closed[0].Resolution)
+	assert.Empty(t, opened)
+}
+
+func TestNonConformitiesEmail(t *testing.T) {
+	intervention := nonConformityIntervention(models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeNonCompliant})
+	_, opened := nonConformityChanges(intervention, nil, nil, time.Now())
+	portal := &models.Portal{ID: 4, Name: "Portail Nord", AddressStreet: "1 rue du Port", AddressZipcode: "13002", AddressCity: "Marseille"}
+
+	outgoing := nonConformitiesEmail(portal, intervention, opened)
+	require.NoError(t, outbox.Render(context.Background(), outgoing, templates.NonConformitiesEmail(portal, intervention, opened)))
+	assert.Equal(t, models.OutboxEmailKindNonConformity, outgoing.Kind)
+	assert.Equal(t, "Portail Portail Nord : 1 non-conformité(s) ouverte(s)", outgoing.Subject)
+	assert.Contains(t, outgoing.Body, "L'intervention du 10/03/2025 sur le portail Portail Nord")
+	assert.Contains(t, outgoing.Body, "- Cellules de sécurité (Majeure), à lever avant le 25/03/2025")
+	assert.Equal(t, uint(7), *outgoing.InterventionID)
+	assert.Empty(t, outgoing.To, "addressed by the notification routing")
+}
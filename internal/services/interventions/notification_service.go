@@ -1,74 +1,91 @@
 package interventions
 
 import (
+	"context"
 	"fmt"
-	"os"
 
 	"github.com/troptropcontent/qr_code_maintenance/internal/models"
-	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/notifications"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
 )
 
 // NotificationService handles sending intervention notifications
 type NotificationService struct {
-	pdfService   *PDFService
-	emailService email.EmailService
+	db            *gorm.DB
+	reportService *ReportService
 }
 
 // NewNotificationService creates a new notification service
-func NewNotificationService(pdfService *PDFService, emailService email.EmailService) *NotificationService {
+func NewNotificationService(db *gorm.DB, reportService *ReportService) *NotificationService {
 	return &NotificationService{
-		pdfService:   pdfService,
-		emailService: emailService,
+		db:            db,
+		reportService: reportService,
 	}
 }
 
-// SendInterventionReport generates a PDF report and sends it via email
-func (s *NotificationService) SendInterventionReport(intervention *models.Intervention) error {
-	// Generate PDF report
-	pdfFile, err := s.pdfService.GenerateReportPDF(intervention)
-	if err != nil {
-		return fmt.Errorf("failed to generate PDF report: %w", err)
+// QueueInterventionReport queues the email of the report of a validated or
+// amended intervention to the recipients of the portal, in the transaction tx
+// of the validation or amendment. The emails wait for the PDF report, which
+// SendReportJob generates and attaches. Each revision of the intervention is
+// emailed once, and not at all when the portal has nobody to notify.
+func QueueInterventionReport(ctx context.Context, tx *gorm.DB, interventionID uint) error {
+	var intervention models.Intervention
+	if err := tx.Preload("Portal").Preload("Revisions").First(&intervention, interventionID).Error; err != nil {
+		return fmt.Errorf("failed to load intervention: %w", err)
 	}
-	defer pdfFile.Close()
-	defer os.Remove(pdfFile.Name()) // Clean up temporary file
 
-	// Prepare email content
-	subject := fmt.Sprintf("Rapport d'Intervention #%d - %s", intervention.ID, intervention.Portal.Name)
-	body := s.buildEmailBody(intervention)
+	subject := fmt.Sprintf("Rapport d'Intervention %s - %s", intervention.ReportReference(), intervention.Portal.Name)
+	if intervention.CurrentRevision() > 1 {
+		subject += fmt.Sprintf(" - Révision %d", intervention.CurrentRevision())
+	}
 
-	if err := s.emailService.Send([]string{intervention.User.Email}, subject, body, []string{pdfFile.Name()}); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	dedupKey := fmt.Sprintf("intervention_report:%d:r%d", intervention.ID, intervention.CurrentRevision())
+	outgoing := &models.OutboxEmail{
+		Kind:           models.OutboxEmailKindInterventionReport,
+		DedupKey:       &dedupKey,
+		Subject:        subject,
+		Status:         models.OutboxEmailStatusWaiting,
+		InterventionID: &intervention.ID,
+	}
+	queued, err := notifications.Notify(ctx, tx, &intervention.Portal, models.NotificationEventInterventionValidated, outgoing, templates.InterventionReportEmail(&intervention))
+	if err != nil || queued == 0 {
+		return err
 	}
 
-	return nil
+	_, err = jobs.Enqueue(tx, SendReportJob, SendReportPayload{InterventionID: intervention.ID})
+	return err
 }
 
-// buildEmailBody creates the email body content
-func (s *NotificationService) buildEmailBody(intervention *models.Intervention) string {
-	body := fmt.Sprintf(`Cher/Chère %s %s,
-
-Veuillez trouver en pièce jointe le rapport d'intervention pour :
-
-Portail : %s
-ID d'intervention : %d
-Date : %s
-Technicien : %s`,
-		intervention.User.FirstName,
-		intervention.User.LastName,
-		intervention.Portal.Name,
-		intervention.ID,
-		intervention.Date.Format("2006-01-02"),
-		intervention.UserName)
-
-	if intervention.Summary != nil && *intervention.Summary != "" {
-		body += fmt.Sprintf(`
-Résumé : %s`, *intervention.Summary)
+// AttachInterventionReport attaches the latest PDF report of the intervention,
+// generating it first if needed, to its report emails waiting for it and
+// queues their delivery
+func (s *NotificationService) AttachInterventionReport(ctx context.Context, interventionID uint) error {
+	var waiting []models.OutboxEmail
+	err := s.db.WithContext(ctx).
+		Where("intervention_id = ? AND kind = ? AND status = ?", interventionID, models.OutboxEmailKindInterventionReport, models.OutboxEmailStatusWaiting).
+		Find(&waiting).Error
+	if err != nil {
+		return fmt.Errorf("failed to find report emails: %w", err)
+	}
+	if len(waiting) == 0 {
+		return nil
 	}
 
-	body += `
-
-Cordialement,
-Système de Maintenance QR Code`
+	report, err := s.reportService.Latest(ctx, interventionID)
+	if err != nil {
+		return fmt.Errorf("failed to get PDF report: %w", err)
+	}
 
-	return body
+	attachments := []models.OutboxAttachment{{Key: report.Key, FileName: report.FileName()}}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, outgoing := range waiting {
+			if _, err := outbox.Attach(tx, outgoing.ID, attachments); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
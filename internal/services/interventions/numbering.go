@@ -0,0 +1,49 @@
+package interventions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// FormatReportNumber formats the n-th report number of a year, e.g. 2026-000123
+func FormatReportNumber(year int, n int) string {
+	return fmt.Sprintf("%d-%06d", year, n)
+}
+
+// AssignReportNumber gives the intervention the next report number of the
+// organization for the year of at. It must run in the transaction that
+// finalizes the intervention: the sequence row stays locked until commit so
+// concurrent finalizations are serialized, and a rollback releases the number
+// so the sequence has no gaps.
+func AssignReportNumber(tx *gorm.DB, intervention *models.Intervention, organizationID uint, at time.Time) error {
+	if intervention.ReportNumber != nil {
+		return nil
+	}
+
+	year := at.Year()
+	var number int
+	err := tx.Raw(`
+		INSERT INTO report_sequences (organization_id, year, last_number) VALUES (?, ?, 1)
+		ON CONFLICT (organization_id, year) DO UPDATE SET last_number = report_sequences.last_number + 1
+		RETURNING last_number`,
+		organizationID, year,
+	).Scan(&number).Error
+	if err != nil {
+		return fmt.Errorf("failed to increment report sequence: %w", err)
+	}
+
+	reportNumber := FormatReportNumber(year, number)
+	if err := tx.Model(intervention).Updates(map[string]any{
+		"organization_id": organizationID,
+		"report_number":   reportNumber,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to assign report number: %w", err)
+	}
+
+	intervention.OrganizationID = &organizationID
+	intervention.ReportNumber = &reportNumber
+	return nil
+}
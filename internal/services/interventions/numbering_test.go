@@ -0,0 +1,12 @@
+package interventions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatReportNumber(t *testing.T) {
+	assert.Equal(t, "2025-000001", FormatReportNumber(2025, 1))
+	assert.Equal(t, "2026-001234", FormatReportNumber(2026, 1234))
+}
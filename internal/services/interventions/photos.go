@@ -0,0 +1,68 @@
+package interventions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/images"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+// ErrInvalidPhoto is returned when an uploaded file is not an accepted image
+var ErrInvalidPhoto = errors.New("invalid photo")
+
+// SaveControlPhoto runs an uploaded photo through the image pipeline and
+// stores its web sized copy and thumbnail. Only the sanitized copies are
+// kept, the original and its metadata are discarded.
+func SaveControlPhoto(ctx context.Context, store *storage.Service, controlID uint, fileHeader *multipart.FileHeader) (*models.ControlPhoto, error) {
+	if fileHeader.Size > storage.Photos.MaxSize {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPhoto, fileHeader.Filename, storage.ErrTooLarge)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded photo: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, storage.Photos.MaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded photo: %w", err)
+	}
+
+	processed, err := images.Process(data)
+	if err != nil {
+		if errors.Is(err, images.ErrInvalidImage) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPhoto, fileHeader.Filename, err)
+		}
+		return nil, err
+	}
+
+	web, err := store.Save(ctx, storage.Photos, bytes.NewReader(processed.Web))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store photo: %w", err)
+	}
+	thumbnail, err := store.Save(ctx, storage.Photos, bytes.NewReader(processed.Thumbnail))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	return &models.ControlPhoto{
+		ControlID:    controlID,
+		FileName:     filepath.Base(fileHeader.Filename),
+		ContentType:  web.ContentType,
+		Size:         web.Size,
+		Key:          web.Key,
+		SHA256:       web.SHA256,
+		ThumbnailKey: thumbnail.Key,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		CapturedAt:   processed.CapturedAt,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package interventions
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/images"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+func uploadedFile(t *testing.T, name string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("photo", name)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	return req.MultipartForm.File["photo"][0]
+}
+
+func TestSaveControlPhoto(t *testing.T) {
+	store := storage.NewService(storage.NewLocalBackend(t.TempDir()), "secret")
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2000, 1000))))
+	photo, err := SaveControlPhoto(context.Background(), store, 3, uploadedFile(t, "IMG_0001.PNG", buf.Bytes()))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint(3), photo.ControlID)
+	assert.Equal(t, "IMG_0001.PNG", photo.FileName)
+	assert.Equal(t, "image/jpeg", photo.ContentType)
+	assert.Equal(t, images.WebSize, photo.Width)
+	assert.Equal(t, images.WebSize/2, photo.Height)
+	assert.Equal(t, photo.SHA256, strings.TrimSuffix(filepath.Base(photo.Key), ".jpg"))
+	assert.NotEqual(t, photo.Key, photo.ThumbnailKey)
+
+	web, err := store.ReadAll(context.Background(), photo.Key)
+	require.NoError(t, err)
+	assert.Equal(t, photo.Size, int64(len(web)))
+
+	thumbnail, err := jpeg.DecodeConfig(mustOpen(t, store, photo.ThumbnailKey))
+	require.NoError(t, err)
+	assert.Equal(t, images.ThumbnailSize, thumbnail.Width)
+}
+
+func mustOpen(t *testing.T, store *storage.Service, key string) io.Reader {
+	content, err := store.ReadAll(context.Background(), key)
+	require.NoError(t, err)
+	return bytes.NewReader(content)
+}
+
+func TestSaveControlPhoto_RejectsNonImages(t *testing.T) {
+	store := storage.NewService(storage.NewLocalBackend(t.TempDir()), "secret")
+
+	_, err := SaveControlPhoto(context.Background(), store, 3, uploadedFile(t, "photo.jpg", []byte("<html>not a photo</html>")))
+	assert.ErrorIs(t, err, ErrInvalidPhoto)
+}
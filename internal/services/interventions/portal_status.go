@@ -0,0 +1,52 @@
+package interventions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/portals"
+	"gorm.io/gorm"
+)
+
+// UpdatePortalStatus applies the status a validated intervention leaves its
+// portal in. The intervention must have its controls and checklist loaded.
+func UpdatePortalStatus(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User) error {
+	status, reason, ok := portalStatusAfter(intervention)
+	if !ok {
+		return nil
+	}
+
+	_, err := portals.ChangeStatus(tx, intervention.PortalID, status, models.PortalStatusChange{
+		Reason:         reason,
+		InterventionID: &intervention.ID,
+		UserID:         &reviewer.ID,
+		UserName:       reviewer.FullName(),
+	})
+	return err
+}
+
+// portalStatusAfter returns the status the intervention leaves the portal in
+// and why. A failed security control puts the portal out of service whatever
+// the technician recorded. ok is false when the intervention does not tell.
+func portalStatusAfter(intervention *models.Intervention) (status models.PortalStatus, reason string, ok bool) {
+	reference := intervention.ReportReference()
+	if intervention.InterventionType() == models.InterventionTypeDecommissioning {
+		return models.PortalStatusDecommissioned, fmt.Sprintf("Désaffectation lors de l'intervention n° %s", reference), true
+	}
+
+	var failed []string
+	for _, item := range intervention.Checklist().ByKind(models.ControlKindSecurity) {
+		if control := intervention.Control(item.Code); control != nil && control.Outcome == models.ControlOutcomeNonCompliant {
+			failed = append(failed, item.Label)
+		}
+	}
+	if len(failed) > 0 {
+		return models.PortalStatusOutOfService, fmt.Sprintf("Contrôle de sécurité non conforme lors de l'intervention n° %s : %s", reference, strings.Join(failed, ", ")), true
+	}
+
+	if intervention.PortalStatusAfter != nil {
+		return *intervention.PortalStatusAfter, fmt.Sprintf("État du portail à la sortie de l'intervention n° %s", reference), true
+	}
+	return "", "", false
+}
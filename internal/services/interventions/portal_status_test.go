@@ -0,0 +1,56 @@
+package interventions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+func TestPortalStatusAfter_Decommissioning(t *testing.T) {
+	interventionType := models.InterventionTypeDecommissioning
+	intervention := nonConformityIntervention()
+	intervention.Type = &interventionType
+
+	status, reason, ok := portalStatusAfter(intervention)
+	assert.True(t, ok)
+	assert.Equal(t, models.PortalStatusDecommissioned, status)
+	assert.Contains(t, reason, "#7")
+}
+
+func TestPortalStatusAfter_FailedSecurityControl(t *testing.T) {
+	inService := models.PortalStatusInService
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeNonCompliant},
+		models.Control{Kind: "lubrication", Outcome: models.ControlOutcomeNonCompliant},
+	)
+	intervention.PortalStatusAfter = &inService
+
+	status, reason, ok := portalStatusAfter(intervention)
+	assert.True(t, ok)
+	assert.Equal(t, models.PortalStatusOutOfService, status)
+	assert.Contains(t, reason, "Cellules de sécurité")
+	assert.NotContains(t, reason, "Graissage")
+}
+
+func TestPortalStatusAfter_RecordedStatus(t *testing.T) {
+	degraded := models.PortalStatusDegraded
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant},
+		models.Control{Kind: "lubrication", Outcome: models.ControlOutcomeNonCompliant},
+	)
+	intervention.PortalStatusAfter = &degraded
+
+	status, _, ok := portalStatusAfter(intervention)
+	assert.True(t, ok)
+	assert.Equal(t, models.PortalStatusDegraded, status)
+}
+
+func TestPortalStatusAfter_Unknown(t *testing.T) {
+	intervention := nonConformityIntervention(
+		models.Control{Kind: "safety_cells", Outcome: models.ControlOutcomeCompliant},
+	)
+
+	_, _, ok := portalStatusAfter(intervention)
+	assert.False(t, ok)
+}
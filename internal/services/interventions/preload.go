@@ -0,0 +1,21 @@
+package interventions
+
+import (
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// PreloadReport preloads the associations needed to render an intervention
+// report, including the earlier validated interventions of the portal used to
+// show measurement trends
+func PreloadReport(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Portal").
+		Preload("Portal.Interventions", "status = ?", models.InterventionStatusValidated).
+		Preload("Portal.Interventions.Controls").
+		Preload("ChecklistVersion.Items").
+		Preload("Controls.Photos").
+		Preload("Signatures").
+		Preload("Revisions").
+		Preload("CorrectiveActions.NonConformity")
+}
@@ -0,0 +1,206 @@
+package interventions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotValidated is returned when generating the report of an intervention
+// that was not validated by a supervisor yet
+var ErrNotValidated = errors.New("intervention is not validated")
+
+// ReportService generates intervention reports once, stores them and keeps
+// every revision
+type ReportService struct {
+	db         *gorm.DB
+	pdfService *PDFService
+	storage    *storage.Service
+}
+
+// NewReportService creates a new intervention report service
+func NewReportService(db *gorm.DB, pdfService *PDFService, store *storage.Service) *ReportService {
+	return &ReportService{
+		db:         db,
+		pdfService: pdfService,
+		storage:    store,
+	}
+}
+
+// Latest returns the latest revision of the intervention report, generating
+// a new one if the report was never generated or the intervention was
+// amended since
+func (s *ReportService) Latest(ctx context.Context, interventionID uint) (*models.InterventionReport, error) {
+	return s.generate(ctx, interventionID, false)
+}
+
+// Regenerate renders the current data of the intervention into a new
+// revision. Earlier revisions are kept untouched.
+func (s *ReportService) Regenerate(ctx context.Context, interventionID uint) (*models.InterventionReport, error) {
+	return s.generate(ctx, interventionID, true)
+}
+
+// Revision returns a given revision of the intervention report
+func (s *ReportService) Revision(ctx context.Context, interventionID uint, revision int) (*models.InterventionReport, error) {
+	var report models.InterventionReport
+	if err := s.db.WithContext(ctx).Preload("Intervention").Where("intervention_id = ? AND revision = ?", interventionID, revision).First(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ByVerificationCode returns the report revision printed with the given
+// verification code, along with its intervention and portal
+func (s *ReportService) ByVerificationCode(ctx context.Context, code string) (*models.InterventionReport, error) {
+	var report models.InterventionReport
+	if err := s.db.WithContext(ctx).Preload("Intervention.Portal").Where("verification_code = ?", NormalizeVerificationCode(code)).First(&report).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// LatestRevision returns the number of the latest revision of the
+// intervention report, 0 when none was generated yet
+func (s *ReportService) LatestRevision(ctx context.Context, interventionID uint) (int, error) {
+	var revision int
+	err := s.db.WithContext(ctx).Model(&models.InterventionReport{}).
+		Where("intervention_id = ?", interventionID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&revision).Error
+	return revision, err
+}
+
+// Open opens the stored PDF of a report revision. The caller must close it.
+func (s *ReportService) Open(ctx context.Context, report *models.InterventionReport) (io.ReadCloser, error) {
+	return s.storage.Open(ctx, report.Key)
+}
+
+// reportAttempts bounds how many times a report is rendered again when the
+// intervention is amended while its PDF is being generated
+const reportAttempts = 3
+
+// errInterventionAmended tells that the intervention was amended while its
+// report was rendered, the rendered PDF is outdated
+var errInterventionAmended = errors.New("intervention was amended while its report was rendered")
+
+// generate renders the PDF outside of any transaction, the intervention is
+// only locked to record the revision so that a slow PDF service does not
+// block the intervention
+func (s *ReportService) generate(ctx context.Context, interventionID uint, force bool) (*models.InterventionReport, error) {
+	for attempt := 1; ; attempt++ {
+		report, err := s.render(ctx, interventionID, force)
+		if errors.Is(err, errInterventionAmended) && attempt < reportAttempts {
+			continue
+		}
+		return report, err
+	}
+}
+
+func (s *ReportService) render(ctx context.Context, interventionID uint, force bool) (*models.InterventionReport, error) {
+	db := s.db.WithContext(ctx)
+
+	var intervention models.Intervention
+	if err := PreloadReport(db).First(&intervention, interventionID).Error; err != nil {
+		return nil, err
+	}
+	if intervention.Status != models.InterventionStatusValidated {
+		return nil, ErrNotValidated
+	}
+
+	latest, err := latestReport(db, interventionID)
+	if err != nil {
+		return nil, err
+	}
+	// An amended intervention gets a new report for its current revision
+	if latest != nil && !force && latest.InterventionRevision >= intervention.CurrentRevision() {
+		return latest, nil
+	}
+
+	verificationCode, err := NewVerificationCode()
+	if err != nil {
+		return nil, err
+	}
+
+	pdfFile, err := s.pdfService.GenerateReportPDF(ctx, &intervention, verificationCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PDF report: %w", err)
+	}
+	defer os.Remove(pdfFile.Name())
+	defer pdfFile.Close()
+
+	// Reports are stored under content addressed keys, a PDF whose revision is
+	// not recorded only leaves an unreferenced object behind
+	object, err := s.storage.Save(ctx, storage.Reports, pdfFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store PDF report: %w", err)
+	}
+
+	var report *models.InterventionReport
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock the intervention so that concurrent requests agree on revisions
+		var locked models.Intervention
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "status", "revision").First(&locked, interventionID).Error; err != nil {
+			return err
+		}
+		if locked.Status != models.InterventionStatusValidated {
+			return ErrNotValidated
+		}
+		if locked.CurrentRevision() != intervention.CurrentRevision() {
+			return errInterventionAmended
+		}
+
+		latest, err := latestReport(tx, interventionID)
+		if err != nil {
+			return err
+		}
+		// A concurrent request recorded the report of this revision first
+		if latest != nil && !force && latest.InterventionRevision >= locked.CurrentRevision() {
+			report = latest
+			return nil
+		}
+
+		revision := 1
+		if latest != nil {
+			revision = latest.Revision + 1
+		}
+		report = &models.InterventionReport{
+			InterventionID:       interventionID,
+			Revision:             revision,
+			InterventionRevision: intervention.CurrentRevision(),
+			Key:                  object.Key,
+			SHA256:               object.SHA256,
+			Size:                 object.Size,
+			VerificationCode:     &verificationCode,
+		}
+		if err := tx.Omit(clause.Associations).Create(report).Error; err != nil {
+			return err
+		}
+		report.Intervention = intervention
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// latestReport returns the latest revision of the intervention report, nil
+// when none was generated yet
+func latestReport(db *gorm.DB, interventionID uint) (*models.InterventionReport, error) {
+	var latest models.InterventionReport
+	err := db.Preload("Intervention").Where("intervention_id = ?", interventionID).Order("revision desc").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
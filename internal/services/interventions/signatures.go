@@ -0,0 +1,158 @@
+package interventions
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+// ErrInvalidSignature is returned when a captured signature is not an
+// accepted image
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureInput is a signature captured by the signature pad of the
+// intervention form
+type SignatureInput struct {
+	Party      models.SignatureParty
+	DataURL    string
+	SignerName string
+	SignerRole string
+}
+
+// DecodeSignatureDataURL decodes a PNG or SVG data URL sent by the signature
+// pad. SVG images are only accepted when they contain drawing elements.
+func DecodeSignatureDataURL(dataURL string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimSpace(dataURL), ",")
+	if !ok || !strings.HasPrefix(header, "data:") {
+		return nil, fmt.Errorf("%w: not a data URL", ErrInvalidSignature)
+	}
+
+	mediaType, encoding, _ := strings.Cut(strings.TrimPrefix(header, "data:"), ";")
+
+	var data []byte
+	var err error
+	if encoding == "base64" {
+		data, err = base64.StdEncoding.DecodeString(payload)
+	} else {
+		var unescaped string
+		unescaped, err = url.PathUnescape(payload)
+		data = []byte(unescaped)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if int64(len(data)) > storage.Signatures.MaxSize {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, storage.ErrTooLarge)
+	}
+
+	switch mediaType {
+	case "image/png":
+		if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	case "image/svg+xml":
+		if err := validateSignatureSVG(data); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %q", ErrInvalidSignature, mediaType)
+	}
+	return data, nil
+}
+
+// signatureSVGElements lists the SVG elements a signature pad produces
+var signatureSVGElements = map[string]bool{
+	"svg": true, "g": true, "path": true, "polyline": true, "line": true,
+	"circle": true, "ellipse": true, "rect": true, "title": true, "desc": true,
+}
+
+// validateSignatureSVG only lets through SVG made of drawing elements, with
+// no script, event handler or external reference
+func validateSignatureSVG(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := true
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("malformed SVG: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root && t.Name.Local != "svg" {
+				return errors.New("root element is not svg")
+			}
+			root = false
+			if !signatureSVGElements[t.Name.Local] {
+				return fmt.Errorf("element %q is not allowed", t.Name.Local)
+			}
+			for _, attr := range t.Attr {
+				name := strings.ToLower(attr.Name.Local)
+				if strings.HasPrefix(name, "on") || name == "href" || strings.Contains(strings.ToLower(attr.Value), "url(") {
+					return fmt.Errorf("attribute %q is not allowed", attr.Name.Local)
+				}
+			}
+		case xml.Directive, xml.ProcInst:
+			if pi, ok := t.(xml.ProcInst); ok && pi.Target == "xml" {
+				continue
+			}
+			return errors.New("directives are not allowed")
+		}
+	}
+	if root {
+		return errors.New("empty SVG")
+	}
+	return nil
+}
+
+// SaveSignature stores a captured signature and returns the record to
+// persist. The signature is timestamped with the time the server received it.
+func SaveSignature(ctx context.Context, store *storage.Service, interventionID uint, input SignatureInput, signedAt time.Time) (*models.InterventionSignature, error) {
+	if !input.Party.IsValid() {
+		return nil, fmt.Errorf("%w: unknown party %q", ErrInvalidSignature, input.Party)
+	}
+	if strings.TrimSpace(input.SignerName) == "" {
+		return nil, fmt.Errorf("%w: signer name is required", ErrInvalidSignature)
+	}
+
+	data, err := DecodeSignatureDataURL(input.DataURL)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := store.Save(ctx, storage.Signatures, bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, storage.ErrTypeNotAllowed) || errors.Is(err, storage.ErrTooLarge) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		return nil, fmt.Errorf("failed to store signature: %w", err)
+	}
+
+	return &models.InterventionSignature{
+		InterventionID: interventionID,
+		Party:          input.Party,
+		SignerName:     strings.TrimSpace(input.SignerName),
+		SignerRole:     strings.TrimSpace(input.SignerRole),
+		ContentType:    object.ContentType,
+		Size:           object.Size,
+		Key:            object.Key,
+		SHA256:         object.SHA256,
+		// Stored with second precision so that the chain hash survives the
+		// database round trip
+		SignedAt: signedAt.UTC().Truncate(time.Second),
+	}, nil
+}
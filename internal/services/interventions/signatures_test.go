@@ -0,0 +1,87 @@
+package interventions
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+)
+
+const signatureSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 300 100"><path d="M10 80 C 40 10, 65 10, 95 80" stroke="black" fill="none"/></svg>`
+
+func signaturePNGDataURL(t *testing.T) string {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 30, 10))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecodeSignatureDataURL(t *testing.T) {
+	data, err := DecodeSignatureDataURL(signaturePNGDataURL(t))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x89PNG"), data[:4])
+
+	data, err = DecodeSignatureDataURL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(signatureSVG)))
+	require.NoError(t, err)
+	assert.Equal(t, signatureSVG, string(data))
+
+	data, err = DecodeSignatureDataURL("data:image/svg+xml," + url.PathEscape(signatureSVG))
+	require.NoError(t, err)
+	assert.Equal(t, signatureSVG, string(data))
+}
+
+func TestDecodeSignatureDataURL_Invalid(t *testing.T) {
+	invalid := map[string]string{
+		"not a data URL":     "https://example.com/signature.png",
+		"unsupported type":   "data:image/jpeg;base64,/9j/4AAQ",
+		"corrupted PNG":      "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("not a png")),
+		"script":             "data:image/svg+xml," + url.PathEscape(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+		"event handler":      "data:image/svg+xml," + url.PathEscape(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><path d="M0 0"/></svg>`),
+		"external reference": "data:image/svg+xml," + url.PathEscape(`<svg xmlns="http://www.w3.org/2000/svg"><image href="https://example.com/x.png"/></svg>`),
+		"foreign object":     "data:image/svg+xml," + url.PathEscape(`<svg xmlns="http://www.w3.org/2000/svg"><foreignObject><div/></foreignObject></svg>`),
+		"not an SVG":         "data:image/svg+xml," + url.PathEscape(`<html><body/></html>`),
+	}
+	for name, dataURL := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeSignatureDataURL(dataURL)
+			assert.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestSaveSignature(t *testing.T) {
+	store := storage.NewService(storage.NewLocalBackend(t.TempDir()), "secret")
+	signedAt := time.Date(2025, 3, 4, 10, 30, 15, 123456789, time.FixedZone("CET", 3600))
+
+	signature, err := SaveSignature(context.Background(), store, 12, SignatureInput{
+		Party:      models.SignaturePartyCustomer,
+		DataURL:    "data:image/svg+xml," + url.PathEscape(signatureSVG),
+		SignerName: " Marie Martin ",
+		SignerRole: "Gardienne",
+	}, signedAt)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint(12), signature.InterventionID)
+	assert.Equal(t, "Marie Martin", signature.SignerName)
+	assert.Equal(t, "image/svg+xml", signature.ContentType)
+	assert.Len(t, signature.SHA256, 64)
+	assert.Equal(t, time.Date(2025, 3, 4, 9, 30, 15, 0, time.UTC), signature.SignedAt)
+
+	content, err := store.ReadAll(context.Background(), signature.Key)
+	require.NoError(t, err)
+	assert.Equal(t, signatureSVG, string(content))
+
+	_, err = SaveSignature(context.Background(), store, 12, SignatureInput{
+		Party:   models.SignaturePartyCustomer,
+		DataURL: signaturePNGDataURL(t),
+	}, signedAt)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "signer name is required")
+}
@@ -0,0 +1,39 @@
+package interventions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempFilePrefix starts the names of the PDFs rendered by Gotenberg and of
+// the directories reports are downloaded to before being emailed
+const tempFilePrefix = "intervention_report_"
+
+// RemoveStaleTempFiles deletes the report files and directories left in dir
+// by interrupted generations or sends, once they are older than maxAge. It
+// returns how many were removed.
+func RemoveStaleTempFiles(dir string, maxAge time.Duration, now time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list temp dir: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), tempFilePrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < maxAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
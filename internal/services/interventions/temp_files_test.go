@@ -0,0 +1,44 @@
+package interventions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+
+	create := func(name string, modTime time.Time, isDir bool) {
+		path := filepath.Join(dir, name)
+		if isDir {
+			require.NoError(t, os.Mkdir(path, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(path, "report.pdf"), []byte("pdf"), 0o644))
+		} else {
+			require.NoError(t, os.WriteFile(path, []byte("pdf"), 0o644))
+		}
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	create("intervention_report_1.pdf", old, false)
+	create("intervention_report_2", old, true)
+	create("intervention_report_3.pdf", now, false)
+	create("other_file.pdf", old, false)
+
+	removed, err := RemoveStaleTempFiles(dir, time.Hour, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"intervention_report_3.pdf", "other_file.pdf"}, names)
+}
@@ -0,0 +1,68 @@
+package interventions
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
+)
+
+const (
+	PUBLIC_BASE_URL_ENV_VAR = utils.PUBLIC_BASE_URL_ENV_VAR
+
+	// VerificationPath is the public route prefix where reports are verified
+	VerificationPath = "/verify/"
+
+	// verificationCodeLength is the number of characters of a verification
+	// code, about 49 bits of entropy
+	verificationCodeLength = 10
+)
+
+// verificationAlphabet leaves out characters that are easily confused when
+// read on paper (0/O, 1/I/L, U/V)
+const verificationAlphabet = "ABCDEFGHJKMNPQRSTWXYZ23456789"
+
+// NewVerificationCode returns a random code identifying a report revision on
+// the public verification page
+func NewVerificationCode() (string, error) {
+	// Each character is drawn uniformly, a byte modulo the alphabet size would
+	// favor its first characters
+	size := big.NewInt(int64(len(verificationAlphabet)))
+	code := make([]byte, verificationCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate verification code: %w", err)
+		}
+		code[i] = verificationAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeVerificationCode turns a code typed by a user into its stored
+// form, ignoring case, spaces and dashes
+func NormalizeVerificationCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// FormatVerificationCode groups a code in blocks of five characters so that
+// it is easier to read and type
+func FormatVerificationCode(code string) string {
+	if len(code) != verificationCodeLength {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+// VerificationURL returns the absolute URL of the public verification page of
+// a report, as encoded in the QR code printed on it
+func VerificationURL(code string) string {
+	return utils.PublicURL(VerificationPath + code)
+}
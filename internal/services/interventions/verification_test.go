@@ -0,0 +1,35 @@
+package interventions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVerificationCode(t *testing.T) {
+	code, err := NewVerificationCode()
+	require.NoError(t, err)
+
+	assert.Len(t, code, verificationCodeLength)
+	for _, r := range code {
+		assert.True(t, strings.ContainsRune(verificationAlphabet, r), "unexpected character %q", r)
+	}
+
+	other, err := NewVerificationCode()
+	require.NoError(t, err)
+	assert.NotEqual(t, code, other)
+}
+
+func TestVerificationCodeFormatting(t *testing.T) {
+	assert.Equal(t, "ABCDE-FGH23", FormatVerificationCode("ABCDEFGH23"))
+	assert.Equal(t, "ABCDEFGH23", NormalizeVerificationCode(" abcde-fgh23 "))
+	assert.Equal(t, "ABCDEFGH23", NormalizeVerificationCode(FormatVerificationCode("ABCDEFGH23")))
+}
+
+func TestVerificationURL(t *testing.T) {
+	t.Setenv(PUBLIC_BASE_URL_ENV_VAR, "https://portails.example.com/")
+
+	assert.Equal(t, "https://portails.example.com/verify/ABCDEFGH23", VerificationURL("ABCDEFGH23"))
+}
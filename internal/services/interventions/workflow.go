@@ -0,0 +1,129 @@
+package interventions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidTransition is returned when the workflow does not allow the
+	// intervention to move to the requested status
+	ErrInvalidTransition = errors.New("invalid intervention status transition")
+	// ErrRejectionCommentRequired is returned when an intervention is rejected
+	// without telling the technician why
+	ErrRejectionCommentRequired = errors.New("a comment is required to reject an intervention")
+	// ErrNotSupervisor is returned when a user who is not a supervisor
+	// reviews an intervention
+	ErrNotSupervisor = errors.New("only supervisors can review interventions")
+)
+
+// LockIntervention loads an intervention and locks its row until the end of
+// the transaction, so that concurrent saves and reviews apply one after the
+// other
+func LockIntervention(tx *gorm.DB, interventionID uint) (*models.Intervention, error) {
+	var intervention models.Intervention
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&intervention, interventionID).Error; err != nil {
+		return nil, err
+	}
+	return &intervention, nil
+}
+
+// SubmitIntervention submits a draft or rejected intervention for validation
+func SubmitIntervention(tx *gorm.DB, intervention *models.Intervention, at time.Time) error {
+	return transition(tx, intervention, models.InterventionStatusSubmitted, map[string]any{
+		"submitted_at": at,
+	})
+}
+
+// ValidateIntervention makes a submitted intervention final: it gets its
+// report number, updates the non-conformities and the status of its portal
+// and is sealed into the hash chain of its portal. The customer and the
+// portal contact are only notified once the transaction commits.
+func ValidateIntervention(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User, at time.Time) error {
+	if !reviewer.IsSupervisor() {
+		return ErrNotSupervisor
+	}
+
+	var portal models.Portal
+	if err := tx.Select("id", "organization_id").First(&portal, intervention.PortalID).Error; err != nil {
+		return fmt.Errorf("failed to fetch portal: %w", err)
+	}
+	if portal.OrganizationID == nil {
+		return fmt.Errorf("portal %d has no organization", portal.ID)
+	}
+
+	if err := transition(tx, intervention, models.InterventionStatusValidated, map[string]any{
+		"validated_at": at,
+	}); err != nil {
+		return err
+	}
+	if err := recordReview(tx, intervention, reviewer, models.InterventionStatusValidated, nil); err != nil {
+		return err
+	}
+	if err := AssignReportNumber(tx, intervention, *portal.OrganizationID, at); err != nil {
+		return err
+	}
+	if err := TrackNonConformities(tx, intervention, at); err != nil {
+		return err
+	}
+	if err := UpdatePortalStatus(tx, intervention, reviewer); err != nil {
+		return err
+	}
+	return SealIntervention(tx, intervention.ID)
+}
+
+// RejectIntervention sends a submitted intervention back to its technician
+// with the reason of the rejection
+func RejectIntervention(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User, comment string, at time.Time) error {
+	if !reviewer.IsSupervisor() {
+		return ErrNotSupervisor
+	}
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return ErrRejectionCommentRequired
+	}
+
+	if err := transition(tx, intervention, models.InterventionStatusRejected, nil); err != nil {
+		return err
+	}
+	return recordReview(tx, intervention, reviewer, models.InterventionStatusRejected, &comment)
+}
+
+// transition moves the intervention to the next status along with the given
+// column updates, when the workflow allows it
+func transition(tx *gorm.DB, intervention *models.Intervention, next models.InterventionStatus, updates map[string]any) error {
+	if !intervention.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, intervention.Status, next)
+	}
+
+	if updates == nil {
+		updates = map[string]any{}
+	}
+	updates["status"] = next
+	if err := tx.Model(intervention).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update intervention status: %w", err)
+	}
+	intervention.Status = next
+	return nil
+}
+
+func recordReview(tx *gorm.DB, intervention *models.Intervention, reviewer *models.User, decision models.InterventionStatus, comment *string) error {
+	review := models.InterventionReview{
+		InterventionID: intervention.ID,
+		Decision:       decision,
+		Comment:        comment,
+		ReviewerID:     reviewer.ID,
+		ReviewerName:   reviewer.FullName(),
+	}
+	if err := tx.Omit(clause.Associations).Create(&review).Error; err != nil {
+		return fmt.Errorf("failed to record review: %w", err)
+	}
+	intervention.Reviews = append(intervention.Reviews, review)
+	return nil
+}
@@ -0,0 +1,45 @@
+package interventions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+// The checks below run before the workflow touches the database
+
+func TestSubmitIntervention_InvalidTransition(t *testing.T) {
+	for _, status := range []models.InterventionStatus{models.InterventionStatusSubmitted, models.InterventionStatusValidated} {
+		intervention := &models.Intervention{Status: status}
+		err := SubmitIntervention(nil, intervention, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidTransition)
+		assert.Equal(t, status, intervention.Status)
+	}
+}
+
+func TestRejectIntervention_Checks(t *testing.T) {
+	supervisor := &models.User{Role: models.UserRoleSupervisor}
+	technician := &models.User{Role: models.UserRoleTechnician}
+	submitted := func() *models.Intervention {
+		return &models.Intervention{Status: models.InterventionStatusSubmitted}
+	}
+
+	err := RejectIntervention(nil, submitted(), technician, "Photos manquantes", time.Now())
+	assert.ErrorIs(t, err, ErrNotSupervisor)
+
+	err = RejectIntervention(nil, submitted(), supervisor, "   ", time.Now())
+	assert.ErrorIs(t, err, ErrRejectionCommentRequired)
+
+	draft := &models.Intervention{Status: models.InterventionStatusDraft}
+	err = RejectIntervention(nil, draft, supervisor, "Photos manquantes", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+}
+
+func TestValidateIntervention_RequiresSupervisor(t *testing.T) {
+	intervention := &models.Intervention{Status: models.InterventionStatusSubmitted}
+	err := ValidateIntervention(nil, intervention, &models.User{Role: models.UserRoleTechnician}, time.Now())
+	assert.ErrorIs(t, err, ErrNotSupervisor)
+	assert.Equal(t, models.InterventionStatusSubmitted, intervention.Status)
+}
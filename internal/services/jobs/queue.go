@@ -0,0 +1,127 @@
+// Package jobs runs background work from a queue stored in Postgres. Jobs are
+// enqueued in the transaction of the change that needs them, so they are
+// neither lost nor run for a change that was rolled back.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultMaxAttempts is the number of times a job is tried before it is
+// moved to the dead state
+const DefaultMaxAttempts = 5
+
+// ErrNotRetryable is returned when retrying a job that has not failed
+var ErrNotRetryable = errors.New("only dead jobs can be retried")
+
+// Type names a kind of job whose payload is a T
+type Type[T any] string
+
+// Enqueue adds a job of the given type to the queue, to run as soon as a
+// worker is free
+func Enqueue[T any](tx *gorm.DB, jobType Type[T], payload T) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := models.Job{
+		Type:        string(jobType),
+		Payload:     string(data),
+		Status:      models.JobStatusPending,
+		RunAt:       time.Now(),
+		MaxAttempts: DefaultMaxAttempts,
+	}
+	if err := tx.Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return &job, nil
+}
+
+// Retry puts a dead job back in the queue with all its attempts
+func Retry(db *gorm.DB, jobID uint) error {
+	result := db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", jobID, models.JobStatusDead).
+		Updates(map[string]any{
+			"status":      models.JobStatusPending,
+			"run_at":      time.Now(),
+			"attempts":    0,
+			"finished_at": nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to retry job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotRetryable
+	}
+	return nil
+}
+
+// PurgeSucceeded deletes the jobs that succeeded before the given time. Dead
+// jobs are kept until retried or looked at.
+func PurgeSucceeded(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("status = ? AND finished_at < ?", models.JobStatusSucceeded, before).Delete(&models.Job{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge jobs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Backoff returns the delay before retrying a job that failed the given
+// attempt: 30 seconds, doubled at each attempt, at most one hour
+func Backoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	return min(delay, time.Hour)
+}
+
+// permanentError marks a failure retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error returned by a job handler so the job is moved to
+// the dead state without being retried
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// applyResult records the outcome of an attempt on the job: done on success,
+// scheduled for a retry after a failure, or dead once it has no attempts left
+// or the failure is permanent
+func applyResult(job *models.Job, err error, now time.Time) {
+	job.LockedAt = nil
+	job.LockedBy = nil
+	if err == nil {
+		job.Status = models.JobStatusSucceeded
+		job.FinishedAt = &now
+		return
+	}
+
+	message := err.Error()
+	job.LastError = &message
+	if job.Attempts >= job.MaxAttempts || IsPermanent(err) {
+		job.Status = models.JobStatusDead
+		job.FinishedAt = &now
+		return
+	}
+	job.Status = models.JobStatusPending
+	job.RunAt = now.Add(Backoff(job.Attempts))
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 2*time.Minute, Backoff(3))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(8))
+	assert.Equal(t, time.Hour, Backoff(50))
+}
+
+func runningJob(attempts int) *models.Job {
+	lockedAt := time.Now()
+	lockedBy := "worker"
+	return &models.Job{
+		Status:      models.JobStatusRunning,
+		Attempts:    attempts,
+		MaxAttempts: 3,
+		LockedAt:    &lockedAt,
+		LockedBy:    &lockedBy,
+	}
+}
+
+func TestApplyResult_Success(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	job := runningJob(1)
+
+	applyResult(job, nil, now)
+	assert.Equal(t, models.JobStatusSucceeded, job.Status)
+	require.NotNil(t, job.FinishedAt)
+	assert.Equal(t, now, *job.FinishedAt)
+	assert.Nil(t, job.LockedAt)
+	assert.Nil(t, job.LockedBy)
+}
+
+func TestApplyResult_Retry(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	job := runningJob(2)
+
+	applyResult(job, errors.New("smtp down"), now)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.Equal(t, now.Add(time.Minute), job.RunAt)
+	assert.Equal(t, "smtp down", *job.LastError)
+	assert.Nil(t, job.FinishedAt)
+	assert.Nil(t, job.LockedAt)
+}
+
+func TestApplyResult_LastAttempt(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	job := runningJob(3)
+
+	applyResult(job, errors.New("smtp down"), now)
+	assert.Equal(t, models.JobStatusDead, job.Status)
+	require.NotNil(t, job.FinishedAt)
+}
+
+func TestApplyResult_Permanent(t *testing.T) {
+	job := runningJob(1)
+	cause := errors.New("intervention not found")
+
+	err := Permanent(cause)
+	assert.ErrorIs(t, err, cause)
+	applyResult(job, err, time.Now())
+	assert.Equal(t, models.JobStatusDead, job.Status)
+	assert.Equal(t, "intervention not found", *job.LastError)
+}
@@ -0,0 +1,235 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// staleCheckInterval is the wait between two looks for jobs abandoned by a
+// stopped worker
+const staleCheckInterval = time.Minute
+
+// WorkerOptions configures a worker, zero values use the defaults
+type WorkerOptions struct {
+	// Concurrency is the number of jobs run at the same time, 2 by default
+	Concurrency int
+	// PollInterval is the wait between two looks at an empty queue, 1 second
+	// by default
+	PollInterval time.Duration
+	// StaleAfter is the time after which a running job is considered
+	// abandoned by a stopped worker and queued again, 30 minutes by default.
+	// It must exceed the timeout of every job type.
+	StaleAfter time.Duration
+}
+
+type handler struct {
+	timeout time.Duration
+	run     func(ctx context.Context, payload []byte) error
+}
+
+// Worker claims jobs from the queue and runs them with the handler
+// registered for their type. Several workers, in one or more processes, can
+// share the queue.
+type Worker struct {
+	db       *gorm.DB
+	options  WorkerOptions
+	id       string
+	handlers map[string]handler
+}
+
+// NewWorker creates a worker with no job types registered
+func NewWorker(db *gorm.DB, options WorkerOptions) *Worker {
+	if options.Concurrency <= 0 {
+		options.Concurrency = 2
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.StaleAfter <= 0 {
+		options.StaleAfter = 30 * time.Minute
+	}
+
+	hostname, _ := os.Hostname()
+	return &Worker{
+		db:       db,
+		options:  options,
+		id:       hostname + ":" + strconv.Itoa(os.Getpid()),
+		handlers: make(map[string]handler),
+	}
+}
+
+// Register sets the handler of a job type. Each attempt gets a context
+// cancelled after timeout.
+func Register[T any](w *Worker, jobType Type[T], timeout time.Duration, handle func(ctx context.Context, payload T) error) {
+	w.handlers[string(jobType)] = handler{
+		timeout: timeout,
+		run: func(ctx context.Context, data []byte) error {
+			var payload T
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return Permanent(fmt.Errorf("invalid payload: %w", err))
+			}
+			return handle(ctx, payload)
+		},
+	}
+}
+
+// Run processes jobs until ctx is cancelled. It then stops claiming jobs and
+// returns once the running ones have finished. Jobs abandoned by workers of
+// other replicas that stopped are queued again while it runs.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.requeueLoop(ctx)
+	}()
+	for i := 0; i < w.options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) requeueLoop(ctx context.Context) {
+	ticker := time.NewTicker(staleCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.requeueStale(time.Now()); err != nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.claim(time.Now())
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.options.PollInterval):
+			}
+			continue
+		}
+		w.process(job)
+	}
+}
+
+// claim locks the next pending job due at now and marks it running, nil is
+// returned when there is none. Jobs locked by other workers are skipped.
+func (w *Worker) claim(now time.Time) (*models.Job, error) {
+	var job *models.Job
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		var next models.Job
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", models.JobStatusPending, now).
+			Order("run_at, id").
+			Limit(1).
+			Find(&next)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		next.Status = models.JobStatusRunning
+		next.Attempts++
+		next.LockedAt = &now
+		next.LockedBy = &w.id
+		err := tx.Model(&next).Updates(map[string]any{
+			"status":    next.Status,
+			"attempts":  next.Attempts,
+			"locked_at": next.LockedAt,
+			"locked_by": next.LockedBy,
+		}).Error
+		if err != nil {
+			return err
+		}
+		job = &next
+		return nil
+	})
+	return job, err
+}
+
+// process runs a claimed job and stores its outcome. The job context does not
+// derive from the worker one so stopping the worker lets it finish. The
+// outcome is dropped when the job was queued again as stale meanwhile, the
+// worker then lost its lease and the state belongs to the next attempt.
+func (w *Worker) process(job *models.Job) {
+	err := w.run(job)
+	if err != nil {
+		log.Printf("Job %d (%s) failed attempt %d/%d: %v", job.ID, job.Type, job.Attempts, job.MaxAttempts, err)
+	}
+
+	applyResult(job, err, time.Now())
+	// The attempt count tells apart a claim of the same worker after a requeue
+	result := w.db.Model(job).Where("locked_by = ? AND attempts = ?", w.id, job.Attempts).Updates(map[string]any{
+		"status":      job.Status,
+		"run_at":      job.RunAt,
+		"last_error":  job.LastError,
+		"locked_at":   nil,
+		"locked_by":   nil,
+		"finished_at": job.FinishedAt,
+	})
+	if result.Error != nil {
+		log.Printf("Failed to save result of job %d: %v", job.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("Job %d (%s) lost its lease, result of attempt %d dropped", job.ID, job.Type, job.Attempts)
+	}
+}
+
+func (w *Worker) run(job *models.Job) (err error) {
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handler.timeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	err = handler.run(ctx, []byte(job.Payload))
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("job timed out after %s: %w", handler.timeout, err)
+	}
+	return err
+}
+
+// requeueStale queues again the jobs left running by a worker that stopped
+// without finishing them. Their attempt counts as failed, the ones that had
+// no attempts left are dead and finished.
+func (w *Worker) requeueStale(now time.Time) error {
+	return w.db.Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", models.JobStatusRunning, now.Add(-w.options.StaleAfter)).
+		Updates(map[string]any{
+			"status":      gorm.Expr("CASE WHEN attempts >= max_attempts THEN ? ELSE ? END", models.JobStatusDead, models.JobStatusPending),
+			"run_at":      now,
+			"last_error":  "abandoned by a stopped worker",
+			"locked_at":   nil,
+			"locked_by":   nil,
+			"finished_at": gorm.Expr("CASE WHEN attempts >= max_attempts THEN CAST(? AS timestamptz) END", now),
+		}).Error
+}
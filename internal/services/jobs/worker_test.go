@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+type testPayload struct {
+	Name string `json:"name"`
+}
+
+const testJob Type[testPayload] = "test.job"
+
+func TestWorker_RunDecodesPayload(t *testing.T) {
+	worker := NewWorker(nil, WorkerOptions{})
+	var received testPayload
+	Register(worker, testJob, time.Second, func(ctx context.Context, payload testPayload) error {
+		received = payload
+		return nil
+	})
+
+	err := worker.run(&models.Job{Type: string(testJob), Payload: `{"name":"portail"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "portail", received.Name)
+}
+
+func TestWorker_RunInvalidPayload(t *testing.T) {
+	worker := NewWorker(nil, WorkerOptions{})
+	Register(worker, testJob, time.Second, func(ctx context.Context, payload testPayload) error {
+		return nil
+	})
+
+	var permanent *permanentError
+	err := worker.run(&models.Job{Type: string(testJob), Payload: `[`})
+	assert.ErrorAs(t, err, &permanent)
+}
+
+func TestWorker_RunUnknownType(t *testing.T) {
+	var permanent *permanentError
+	err := NewWorker(nil, WorkerOptions{}).run(&models.Job{Type: "unknown", Payload: `{}`})
+	assert.ErrorAs(t, err, &permanent)
+}
+
+func TestWorker_RunTimeout(t *testing.T) {
+	worker := NewWorker(nil, WorkerOptions{})
+	Register(worker, testJob, 10*time.Millisecond, func(ctx context.Context, payload testPayload) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := worker.run(&models.Job{Type: string(testJob), Payload: `{}`})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "timed out")
+}
+
+func TestWorker_RunRecoversPanic(t *testing.T) {
+	worker := NewWorker(nil, WorkerOptions{})
+	Register(worker, testJob, time.Second, func(ctx context.Context, payload testPayload) error {
+		panic("boom")
+	})
+
+	err := worker.run(&models.Job{Type: string(testJob), Payload: `{}`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestWorker_RunReturnsHandlerError(t *testing.T) {
+	worker := NewWorker(nil, WorkerOptions{})
+	failure := errors.New("smtp down")
+	Register(worker, testJob, time.Second, func(ctx context.Context, payload testPayload) error {
+		return failure
+	})
+
+	assert.ErrorIs(t, worker.run(&models.Job{Type: string(testJob), Payload: `{}`}), failure)
+}
@@ -0,0 +1,166 @@
+// Package notifications routes the events of a portal to its notification
+// recipients and lets them unsubscribe from the link of the emails.
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UnsubscribePath is the public route prefix of the unsubscribe page
+const UnsubscribePath = "/notifications/unsubscribe/"
+
+// Recipients returns the notification recipients of the portal, its own and
+// those of its contract
+func Recipients(tx *gorm.DB, portal *models.Portal) ([]models.NotificationRecipient, error) {
+	query := tx.Where("portal_id = ?", portal.ID)
+	if portal.ContractID != nil {
+		query = query.Or("contract_id = ?", *portal.ContractID)
+	}
+
+	var recipients []models.NotificationRecipient
+	if err := query.Order("id").Find(&recipients).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notification recipients: %w", err)
+	}
+	return recipients, nil
+}
+
+// Notify queues an event of the portal to its notification recipients in
+// the transaction tx: one copy of the outgoing email per address, rendered
+// from component with the unsubscribe link of that address. Every copy lists
+// the same To and Cc recipients in its headers. It returns how many copies
+// were queued, none when nobody is to be notified.
+func Notify(ctx context.Context, tx *gorm.DB, portal *models.Portal, event models.NotificationEvent, outgoing *models.OutboxEmail, component templ.Component) (int, error) {
+	recipients, err := Recipients(tx, portal)
+	if err != nil {
+		return 0, err
+	}
+	notifications, err := notificationCopies(outgoing, models.RouteNotification(event, portal.ContactEmail, recipients))
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for i := range notifications {
+		notification := &notifications[i]
+		notification.PortalID = &portal.ID
+		options := templates.EmailOptions{UnsubscribeURL: UnsubscribeURL(*notification.UnsubscribeToken)}
+		if err := outbox.Render(templates.WithEmailOptions(ctx, options), notification, component); err != nil {
+			return queued, err
+		}
+		enqueued, err := outbox.Enqueue(tx, notification)
+		if err != nil {
+			return queued, err
+		}
+		if enqueued {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// notificationCopies returns a copy of the outgoing email for each address
+// of the routing, delivered to it only and with its own unsubscribe token
+func notificationCopies(outgoing *models.OutboxEmail, routing models.NotificationRouting) ([]models.OutboxEmail, error) {
+	var notifications []models.OutboxEmail
+	for _, addresses := range [][]string{routing.To, routing.Cc, routing.Bcc} {
+		for _, address := range addresses {
+			token, err := newToken()
+			if err != nil {
+				return nil, err
+			}
+
+			notification := *outgoing
+			notification.To, notification.Cc, notification.Bcc = routing.To, routing.Cc, routing.Bcc
+			notification.Recipient = &address
+			notification.UnsubscribeToken = &token
+			if outgoing.DedupKey != nil {
+				dedupKey := *outgoing.DedupKey + ":" + strings.ToLower(address)
+				notification.DedupKey = &dedupKey
+			}
+			notifications = append(notifications, notification)
+		}
+	}
+	return notifications, nil
+}
+
+// Unsubscribe stops notifying the recipient of a notification of the events
+// of its portal. The contact of the portal, who is notified without being
+// listed, is listed as unsubscribed.
+func Unsubscribe(db *gorm.DB, token string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var outgoing models.OutboxEmail
+		err := tx.Where("unsubscribe_token = ? AND recipient IS NOT NULL AND portal_id IS NOT NULL", token).
+			First(&outgoing).Error
+		if err != nil {
+			return err
+		}
+		address := *outgoing.Recipient
+
+		var portal models.Portal
+		if err := tx.First(&portal, *outgoing.PortalID).Error; err != nil {
+			return err
+		}
+		recipients, err := Recipients(tx, &portal)
+		if err != nil {
+			return err
+		}
+
+		var ids []uint
+		for _, recipient := range recipients {
+			if strings.EqualFold(strings.TrimSpace(recipient.Email), address) {
+				ids = append(ids, recipient.ID)
+			}
+		}
+		if len(ids) > 0 {
+			err := tx.Model(&models.NotificationRecipient{}).
+				Where("id IN ? AND unsubscribed_at IS NULL", ids).
+				Update("unsubscribed_at", now).Error
+			if err != nil {
+				return fmt.Errorf("failed to unsubscribe: %w", err)
+			}
+			return nil
+		}
+		if !strings.EqualFold(strings.TrimSpace(portal.ContactEmail), address) {
+			return nil
+		}
+
+		contact := models.NotificationRecipient{
+			PortalID:       &portal.ID,
+			Email:          address,
+			Field:          models.RecipientFieldTo,
+			Events:         models.NotificationEvents,
+			UnsubscribedAt: &now,
+		}
+		if err := tx.Omit(clause.Associations).Create(&contact).Error; err != nil {
+			return fmt.Errorf("failed to unsubscribe: %w", err)
+		}
+		return nil
+	})
+}
+
+// UnsubscribeURL returns the absolute URL of the unsubscribe page of an
+// email
+func UnsubscribeURL(token string) string {
+	return utils.PublicURL(UnsubscribePath + token)
+}
+
+func newToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate unsubscribe token: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
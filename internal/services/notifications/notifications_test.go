@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
+)
+
+func TestUnsubscribeURL(t *testing.T) {
+	t.Setenv(utils.PUBLIC_BASE_URL_ENV_VAR, "https://portails.example.com/")
+
+	assert.Equal(t, "https://portails.example.com/notifications/unsubscribe/abc123", UnsubscribeURL("abc123"))
+}
+
+func TestNewToken(t *testing.T) {
+	token, err := newToken()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{64}$`, token)
+
+	other, err := newToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestNotificationCopies(t *testing.T) {
+	dedupKey := "intervention_report:7:r1"
+	outgoing := &models.OutboxEmail{Subject: "Rapport", DedupKey: &dedupKey}
+	routing := models.NotificationRouting{
+		To:  []string{"contact@example.com"},
+		Cc:  []string{"Syndic@example.com"},
+		Bcc: []string{"archive@example.com"},
+	}
+
+	notifications, err := notificationCopies(outgoing, routing)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+
+	tokens := map[string]bool{}
+	for i, address := range []string{"contact@example.com", "Syndic@example.com", "archive@example.com"} {
+		notification := notifications[i]
+		assert.Equal(t, address, *notification.Recipient)
+		assert.Equal(t, routing, notification.Routing(), "every copy lists the same recipients")
+		assert.Equal(t, "Rapport", notification.Subject)
+		require.NotNil(t, notification.UnsubscribeToken)
+		tokens[*notification.UnsubscribeToken] = true
+	}
+	assert.Len(t, tokens, 3, "each recipient has its own unsubscribe token")
+	assert.Equal(t, "intervention_report:7:r1:syndic@example.com", *notifications[1].DedupKey)
+	assert.Equal(t, "intervention_report:7:r1", *outgoing.DedupKey)
+
+	notifications, err = notificationCopies(outgoing, models.NotificationRouting{})
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
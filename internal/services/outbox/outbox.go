@@ -0,0 +1,212 @@
+// Package outbox queues outgoing emails in the transaction of the change
+// they report and delivers them in the background with the job queue. Every
+// delivery attempt is recorded with the reply of the SMTP server.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNoRecipients is returned when queueing an email without recipients
+	ErrNoRecipients = errors.New("email has no recipients")
+	// ErrNotResendable is returned when sending again an email that has not
+	// failed
+	ErrNotResendable = errors.New("only failed emails can be sent again")
+)
+
+// DeliverJob sends an email of the outbox
+const DeliverJob jobs.Type[DeliverPayload] = "email.deliver"
+
+type DeliverPayload struct {
+	EmailID uint `json:"email_id"`
+}
+
+// Enqueue adds an email to the outbox in the transaction tx, along with the
+// job delivering it once tx commits. An email queued with the waiting status
+// is only delivered once Attach adds its attachments. It reports false,
+// without error, when an email with the same DedupKey was already queued.
+func Enqueue(tx *gorm.DB, outgoing *models.OutboxEmail) (bool, error) {
+	if outgoing.Routing().IsEmpty() {
+		return false, ErrNoRecipients
+	}
+	if outgoing.Status != models.OutboxEmailStatusWaiting {
+		outgoing.Status = models.OutboxEmailStatusPending
+	}
+	outgoing.Attempts = 0
+	outgoing.MaxAttempts = jobs.DefaultMaxAttempts
+
+	result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedup_key"}},
+		DoNothing: true,
+	}).Create(outgoing)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to queue email: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if outgoing.Status == models.OutboxEmailStatusWaiting {
+		return true, nil
+	}
+
+	if _, err := jobs.Enqueue(tx, DeliverJob, DeliverPayload{EmailID: outgoing.ID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Attach adds the attachments of a waiting email and queues its delivery in
+// the transaction tx. It reports false, without error, when the email is no
+// longer waiting.
+func Attach(tx *gorm.DB, emailID uint, attachments []models.OutboxAttachment) (bool, error) {
+	result := tx.Model(&models.OutboxEmail{ID: emailID}).
+		Where("status = ?", models.OutboxEmailStatusWaiting).
+		Select("attachments", "status").
+		Updates(&models.OutboxEmail{Attachments: attachments, Status: models.OutboxEmailStatusPending})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to attach files to email %d: %w", emailID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if _, err := jobs.Enqueue(tx, DeliverJob, DeliverPayload{EmailID: emailID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Resend queues a failed email again with all its attempts
+func Resend(db *gorm.DB, emailID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OutboxEmail{}).
+			Where("id = ? AND status = ?", emailID, models.OutboxEmailStatusFailed).
+			Updates(map[string]any{
+				"status":     models.OutboxEmailStatusPending,
+				"attempts":   0,
+				"last_error": nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to resend email: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotResendable
+		}
+		_, err := jobs.Enqueue(tx, DeliverJob, DeliverPayload{EmailID: emailID})
+		return err
+	})
+}
+
+// Deliver returns the handler of DeliverJob. Emails that are no longer
+// pending are skipped, so a job run twice does not send twice.
+func Deliver(db *gorm.DB, sender email.EmailService, files *storage.Service) func(context.Context, DeliverPayload) error {
+	return func(ctx context.Context, payload DeliverPayload) error {
+		var outgoing models.OutboxEmail
+		if err := db.WithContext(ctx).First(&outgoing, payload.EmailID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return jobs.Permanent(fmt.Errorf("email %d not found", payload.EmailID))
+			}
+			return fmt.Errorf("failed to load email: %w", err)
+		}
+		if outgoing.Status != models.OutboxEmailStatusPending {
+			return nil
+		}
+
+		msg := &email.EmailMessage{To: outgoing.To, Cc: outgoing.Cc, Bcc: outgoing.Bcc, Subject: outgoing.Subject, Body: outgoing.Body, HTMLBody: outgoing.HTMLBody}
+		if logo := email.LogoPath(); logo != "" && strings.Contains(outgoing.HTMLBody, "cid:"+email.LogoContentID) {
+			msg.Inline = append(msg.Inline, email.Attachment{FilePath: logo, ContentID: email.LogoContentID})
+		}
+		if outgoing.Recipient != nil {
+			msg.DeliverTo = []string{*outgoing.Recipient}
+		}
+		if outgoing.MessageID != nil {
+			msg.MessageID = *outgoing.MessageID
+		}
+
+		startedAt := time.Now()
+		var delivery *email.Delivery
+		err := attachFiles(ctx, files, msg, outgoing.Attachments)
+		if err == nil {
+			delivery, err = sender.Deliver(ctx, msg)
+		}
+		attempt, result := recordAttempt(&outgoing, msg.MessageID, delivery, err, startedAt, time.Now())
+
+		saveErr := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&attempt).Error; err != nil {
+				return err
+			}
+			return tx.Model(&outgoing).Select("status", "attempts", "message_id", "smtp_response", "last_error", "sent_at").Updates(&outgoing).Error
+		})
+		if saveErr != nil {
+			return fmt.Errorf("failed to record delivery of email %d: %w", outgoing.ID, saveErr)
+		}
+		return result
+	}
+}
+
+// attachFiles reads the stored attachments of an email into the message
+func attachFiles(ctx context.Context, files *storage.Service, msg *email.EmailMessage, attachments []models.OutboxAttachment) error {
+	for _, attachment := range attachments {
+		content, err := files.ReadAll(ctx, attachment.Key)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment %s: %w", attachment.FileName, err)
+		}
+		msg.Attachments = append(msg.Attachments, email.Attachment{FileName: attachment.FileName, Content: content})
+	}
+	return nil
+}
+
+// recordAttempt applies the result of a delivery attempt to the email and
+// returns the attempt to log along with the error to hand to the job queue:
+// nil when sent, a permanent error once the email failed for good, else the
+// delivery error so that the job is retried.
+func recordAttempt(outgoing *models.OutboxEmail, messageID string, delivery *email.Delivery, err error, startedAt time.Time, finishedAt time.Time) (models.EmailDeliveryAttempt, error) {
+	outgoing.Attempts++
+	if messageID != "" {
+		outgoing.MessageID = &messageID
+	}
+	attempt := models.EmailDeliveryAttempt{
+		EmailID:    outgoing.ID,
+		Attempt:    outgoing.Attempts,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+	}
+
+	if err == nil {
+		attempt.Success = true
+		attempt.SMTPResponse = &delivery.Response
+		outgoing.Status = models.OutboxEmailStatusSent
+		outgoing.SMTPResponse = &delivery.Response
+		outgoing.LastError = nil
+		outgoing.SentAt = &finishedAt
+		return attempt, nil
+	}
+
+	message := err.Error()
+	attempt.Error = &message
+	outgoing.LastError = &message
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		response := fmt.Sprintf("%d %s", protocolErr.Code, protocolErr.Msg)
+		attempt.SMTPResponse = &response
+		outgoing.SMTPResponse = &response
+	}
+	if email.IsPermanent(err) || outgoing.Attempts >= outgoing.MaxAttempts {
+		outgoing.Status = models.OutboxEmailStatusFailed
+		return attempt, jobs.Permanent(err)
+	}
+	return attempt, err
+}
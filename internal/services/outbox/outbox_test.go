@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/jobs"
+)
+
+func TestEnqueue_NoRecipients(t *testing.T) {
+	_, err := Enqueue(nil, &models.OutboxEmail{Subject: "Rapport"})
+	assert.ErrorIs(t, err, ErrNoRecipients)
+}
+
+func TestRecordAttempt(t *testing.T) {
+	startedAt := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Second)
+	pending := func() *models.OutboxEmail {
+		return &models.OutboxEmail{ID: 7, Status: models.OutboxEmailStatusPending, MaxAttempts: 3}
+	}
+
+	t.Run("sent", func(t *testing.T) {
+		outgoing := pending()
+		attempt, err := recordAttempt(outgoing, "abc@example.com", &email.Delivery{MessageID: "abc@example.com", Response: "250 2.0.0 OK queued as 4F2A1"}, nil, startedAt, finishedAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.OutboxEmailStatusSent, outgoing.Status)
+		assert.Equal(t, 1, outgoing.Attempts)
+		assert.Equal(t, "abc@example.com", *outgoing.MessageID)
+		assert.Equal(t, "250 2.0.0 OK queued as 4F2A1", *outgoing.SMTPResponse)
+		assert.Equal(t, finishedAt, *outgoing.SentAt)
+		assert.Equal(t, uint(7), attempt.EmailID)
+		assert.Equal(t, 1, attempt.Attempt)
+		assert.True(t, attempt.Success)
+	})
+
+	t.Run("temporary failure is retried", func(t *testing.T) {
+		outgoing := pending()
+		sendErr := fmt.Errorf("message rejected: %w", &textproto.Error{Code: 451, Msg: "4.7.1 try again later"})
+		attempt, err := recordAttempt(outgoing, "abc@example.com", nil, sendErr, startedAt, finishedAt)
+
+		assert.ErrorIs(t, err, sendErr)
+		assert.False(t, jobs.IsPermanent(err))
+		assert.Equal(t, models.OutboxEmailStatusPending, outgoing.Status)
+		assert.Equal(t, "451 4.7.1 try again later", *outgoing.SMTPResponse)
+		assert.Equal(t, sendErr.Error(), *outgoing.LastError)
+		assert.False(t, attempt.Success)
+		assert.Equal(t, "451 4.7.1 try again later", *attempt.SMTPResponse)
+	})
+
+	t.Run("rejected recipient fails for good", func(t *testing.T) {
+		outgoing := pending()
+		sendErr := fmt.Errorf("failed to set recipient: %w", &textproto.Error{Code: 550, Msg: "5.1.1 user unknown"})
+		_, err := recordAttempt(outgoing, "", nil, sendErr, startedAt, finishedAt)
+
+		assert.True(t, jobs.IsPermanent(err))
+		assert.Equal(t, models.OutboxEmailStatusFailed, outgoing.Status)
+		assert.Nil(t, outgoing.MessageID)
+	})
+
+	t.Run("last attempt fails for good", func(t *testing.T) {
+		outgoing := pending()
+		outgoing.Attempts = 2
+		_, err := recordAttempt(outgoing, "abc@example.com", nil, errors.New("failed to connect to SMTP server"), startedAt, finishedAt)
+
+		assert.True(t, jobs.IsPermanent(err))
+		assert.Equal(t, models.OutboxEmailStatusFailed, outgoing.Status)
+		assert.Equal(t, 3, outgoing.Attempts)
+	})
+}
@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/email"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+// Render sets the body of an outgoing email from its template: the HTML
+// version, and the plain-text version derived from it. The logo is shown
+// when configured, and the options already set on ctx are kept, such as the
+// unsubscribe link of notifications.
+func Render(ctx context.Context, outgoing *models.OutboxEmail, component templ.Component) error {
+	options := templates.EmailOptionsFrom(ctx)
+	if email.LogoPath() != "" {
+		options.LogoSrc = "cid:" + email.LogoContentID
+	}
+
+	var html strings.Builder
+	if err := component.Render(templates.WithEmailOptions(ctx, options), &html); err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+	outgoing.HTMLBody = html.String()
+	outgoing.Body = email.HTMLToText(outgoing.HTMLBody)
+	return nil
+}
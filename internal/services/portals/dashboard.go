@@ -0,0 +1,68 @@
+package portals
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// maintenanceScheduleQuery computes in one pass the last validated preventive
+// visit of every portal, its next due date and how many days it is overdue.
+// The visit frequency comes from the contract of the portal, else from its
+// equipment type. A portal never visited is due at the start of its contract,
+// or at its installation date. Decommissioned portals have no due date. The
+// interval mirrors models.Contract.VisitIntervalMonths.
+const maintenanceScheduleQuery = `
+WITH last_visits AS (
+	SELECT portal_id, MAX(date) AS last_visit
+	FROM interventions
+	WHERE deleted_at IS NULL
+		AND status = @validated
+		AND (type IS NULL OR type = @preventive)
+	GROUP BY portal_id
+), schedules AS (
+	SELECT
+		p.id AS portal_id,
+		p.name,
+		p.address_street,
+		p.address_zipcode,
+		p.address_city,
+		p.contractor_company,
+		p.status,
+		c.reference AS contract_reference,
+		COALESCE(c.visits_per_year, e.visits_per_year, @min_visits) AS visits_per_year,
+		lv.last_visit,
+		CASE
+			WHEN p.status = @decommissioned THEN NULL
+			WHEN lv.last_visit IS NOT NULL THEN (lv.last_visit::date + make_interval(months => GREATEST(12 / COALESCE(c.visits_per_year, e.visits_per_year, @min_visits), 1)))::date
+			ELSE COALESCE(c.start_date, p.installation_date::date)
+		END AS due_date
+	FROM portals p
+	LEFT JOIN contracts c ON c.id = p.contract_id AND c.deleted_at IS NULL
+	LEFT JOIN equipment_types e ON e.id = p.equipment_type_id AND e.deleted_at IS NULL
+	LEFT JOIN last_visits lv ON lv.portal_id = p.id
+	WHERE p.deleted_at IS NULL
+)
+SELECT *, CAST(@today AS date) - due_date AS days_overdue
+FROM schedules
+ORDER BY due_date NULLS LAST, name`
+
+// MaintenanceSchedules returns the maintenance schedule of every portal, the
+// most overdue first
+func MaintenanceSchedules(db *gorm.DB, now time.Time) ([]models.PortalMaintenance, error) {
+	var schedules []models.PortalMaintenance
+	err := db.Raw(maintenanceScheduleQuery,
+		sql.Named("validated", models.InterventionStatusValidated),
+		sql.Named("preventive", models.InterventionTypePreventive),
+		sql.Named("decommissioned", models.PortalStatusDecommissioned),
+		sql.Named("min_visits", models.MinVisitsPerYear),
+		sql.Named("today", now.Format("2006-01-02")),
+	).Scan(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute maintenance schedules: %w", err)
+	}
+	return schedules, nil
+}
@@ -0,0 +1,69 @@
+package portals
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+)
+
+// SendWeeklyDigest queues an email to the active supervisors listing the
+// portals overdue or due this month and the open non-conformities past their
+// due date. Nothing is sent when there is nothing to report, and the digest
+// goes out once per day even when the task runs again.
+func SendWeeklyDigest(ctx context.Context, db *gorm.DB, now time.Time) error {
+	db = db.WithContext(ctx)
+
+	schedules, err := MaintenanceSchedules(db, now)
+	if err != nil {
+		return err
+	}
+	dashboard := models.NewMaintenanceDashboard(schedules, now)
+
+	var nonConformities []models.NonConformity
+	err = db.Preload("Portal").
+		Where("status = ? AND due_date < ?", models.NonConformityStatusOpen, now.Format("2006-01-02")).
+		Order("due_date").
+		Find(&nonConformities).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch overdue non-conformities: %w", err)
+	}
+
+	overdue := dashboard[models.MaintenanceUrgencyOverdue]
+	dueThisMonth := dashboard[models.MaintenanceUrgencyDueThisMonth]
+	if len(overdue) == 0 && len(dueThisMonth) == 0 && len(nonConformities) == 0 {
+		return nil
+	}
+
+	var recipients []string
+	err = db.Model(&models.User{}).
+		Where("role = ? AND is_active = ?", models.UserRoleSupervisor, true).
+		Order("email").
+		Pluck("email", &recipients).Error
+	if err != nil {
+		return fmt.Errorf("failed to fetch supervisors: %w", err)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Maintenance : %d portail(s) en retard, %d à prévoir ce mois-ci", len(overdue), len(dueThisMonth))
+	dedupKey := fmt.Sprintf("weekly_digest:%s", now.Format("2006-01-02"))
+	outgoing := &models.OutboxEmail{
+		Kind:     models.OutboxEmailKindWeeklyDigest,
+		DedupKey: &dedupKey,
+		To:       recipients,
+		Subject:  subject,
+	}
+	if err := outbox.Render(ctx, outgoing, templates.WeeklyDigestEmail(overdue, dueThisMonth, nonConformities)); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		_, err := outbox.Enqueue(tx, outgoing)
+		return err
+	})
+}
@@ -0,0 +1,30 @@
+package portals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+func TestWeeklyDigestEmail(t *testing.T) {
+	due := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	daysOverdue := 9
+	overdue := []models.PortalMaintenance{{Name: "Portail Nord", AddressCity: "Lyon", DueDate: &due, DaysOverdue: &daysOverdue}}
+	dueThisMonth := []models.PortalMaintenance{{Name: "Portail Sud", AddressCity: "Vienne", DueDate: &later}}
+
+	outgoing := &models.OutboxEmail{}
+	require.NoError(t, outbox.Render(context.Background(), outgoing, templates.WeeklyDigestEmail(overdue, dueThisMonth, nil)))
+
+	assert.Contains(t, outgoing.Body, "Visites en retard (1)")
+	assert.Contains(t, outgoing.Body, "- Portail Nord, Lyon : prévue le 01/03/2025 (9 jours de retard)")
+	assert.Contains(t, outgoing.Body, "- Portail Sud, Vienne : prévue le 20/03/2025")
+	assert.Contains(t, outgoing.Body, "Non-conformités en retard (0)\n\n- aucune")
+	assert.Contains(t, outgoing.HTMLBody, "<strong>Portail Nord</strong>")
+}
@@ -0,0 +1,139 @@
+package portals
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SendMaintenanceReminders queues an email to the recipients of every
+// portal whose next preventive visit matches an active reminder rule today.
+// Each reminder is recorded along with its email in one transaction, so a
+// recipient is never sent the same one twice; failed deliveries are retried
+// by the outbox. It returns how many emails were queued.
+func SendMaintenanceReminders(ctx context.Context, db *gorm.DB, now time.Time) (int, error) {
+	db = db.WithContext(ctx)
+
+	var rules []models.ReminderRule
+	if err := db.Where("active = ?", true).Find(&rules).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch reminder rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	schedules, err := MaintenanceSchedules(db, now)
+	if err != nil {
+		return 0, err
+	}
+
+	type dueReminder struct {
+		schedule   models.PortalMaintenance
+		rule       *models.ReminderRule
+		occurrence int
+	}
+	due := make(map[uint]dueReminder)
+	var portalIDs []uint
+	for _, schedule := range schedules {
+		if schedule.DueDate == nil || schedule.DaysOverdue == nil {
+			continue
+		}
+		rule, occurrence := models.DueReminderRule(rules, *schedule.DaysOverdue)
+		if rule == nil {
+			continue
+		}
+		due[schedule.PortalID] = dueReminder{schedule: schedule, rule: rule, occurrence: occurrence}
+		portalIDs = append(portalIDs, schedule.PortalID)
+	}
+	if len(portalIDs) == 0 {
+		return 0, nil
+	}
+
+	var portals []models.Portal
+	err = db.Preload("Contract").Preload("Technician").
+		Where("id IN ? AND reminders_opt_out = ?", portalIDs, false).
+		Find(&portals).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch portals: %w", err)
+	}
+
+	queued := 0
+	for i := range portals {
+		portal := &portals[i]
+		reminder := due[portal.ID]
+		rendered := &models.OutboxEmail{Subject: reminderSubject(portal, &reminder.schedule)}
+		if err := outbox.Render(ctx, rendered, templates.MaintenanceReminderEmail(portal, &reminder.schedule)); err != nil {
+			return queued, err
+		}
+		for _, recipient := range portal.ReminderRecipients() {
+			record := models.MaintenanceReminder{
+				PortalID:      portal.ID,
+				RuleID:        reminder.rule.ID,
+				DueDate:       *reminder.schedule.DueDate,
+				Occurrence:    reminder.occurrence,
+				Recipient:     recipient.Email,
+				RecipientRole: recipient.Role,
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				claimed, err := claimReminder(tx, &record)
+				if err != nil || !claimed {
+					return err
+				}
+
+				portalID := portal.ID
+				outgoing := &models.OutboxEmail{
+					Kind:     models.OutboxEmailKindMaintenanceReminder,
+					To:       []string{recipient.Email},
+					Subject:  rendered.Subject,
+					Body:     rendered.Body,
+					HTMLBody: rendered.HTMLBody,
+					PortalID: &portalID,
+				}
+				if _, err := outbox.Enqueue(tx, outgoing); err != nil {
+					return err
+				}
+				if err := tx.Model(&record).Update("email_id", outgoing.ID).Error; err != nil {
+					return fmt.Errorf("failed to record reminder: %w", err)
+				}
+				queued++
+				return nil
+			})
+			if err != nil {
+				return queued, err
+			}
+		}
+	}
+	return queued, nil
+}
+
+// claimReminder records a reminder about to be queued. It reports false
+// when the same reminder was already queued for the recipient.
+func claimReminder(tx *gorm.DB, record *models.MaintenanceReminder) (bool, error) {
+	result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "portal_id"}, {Name: "rule_id"}, {Name: "due_date"}, {Name: "occurrence"}, {Name: "recipient"}},
+		DoNothing: true,
+	}).Create(record)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record reminder: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// reminderSubject returns the subject of the reminder of a due visit
+func reminderSubject(portal *models.Portal, schedule *models.PortalMaintenance) string {
+	daysOverdue := *schedule.DaysOverdue
+	switch {
+	case daysOverdue < 0:
+		return fmt.Sprintf("Visite de maintenance à prévoir : %s le %s", portal.Name, schedule.DueDate.Format("02/01/2006"))
+	case daysOverdue == 0:
+		return fmt.Sprintf("Visite de maintenance due aujourd'hui : %s", portal.Name)
+	default:
+		return fmt.Sprintf("Visite de maintenance en retard de %d jour(s) : %s", daysOverdue, portal.Name)
+	}
+}
@@ -0,0 +1,43 @@
+package portals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+func TestReminderEmail(t *testing.T) {
+	portal := &models.Portal{Name: "Portail Nord", AddressStreet: "1 rue du Port", AddressZipcode: "69001", AddressCity: "Lyon"}
+	due := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	lastVisit := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	reference := "CT-2025-01"
+
+	tests := []struct {
+		daysOverdue int
+		subject     string
+		situation   string
+	}{
+		{-30, "Visite de maintenance à prévoir : Portail Nord le 01/04/2025", "avant le 01/04/2025, dans 30 jour(s)"},
+		{0, "Visite de maintenance due aujourd'hui : Portail Nord", "est due aujourd'hui, le 01/04/2025"},
+		{14, "Visite de maintenance en retard de 14 jour(s) : Portail Nord", "était due le 01/04/2025"},
+	}
+	for _, tt := range tests {
+		daysOverdue := tt.daysOverdue
+		schedule := &models.PortalMaintenance{DueDate: &due, DaysOverdue: &daysOverdue, LastVisit: &lastVisit, ContractReference: &reference}
+
+		assert.Equal(t, tt.subject, reminderSubject(portal, schedule))
+
+		outgoing := &models.OutboxEmail{}
+		require.NoError(t, outbox.Render(context.Background(), outgoing, templates.MaintenanceReminderEmail(portal, schedule)))
+		assert.Contains(t, outgoing.Body, tt.situation)
+		assert.Contains(t, outgoing.Body, "Adresse : 1 rue du Port, 69001 Lyon")
+		assert.Contains(t, outgoing.Body, "Dernière visite : 01/10/2024")
+		assert.Contains(t, outgoing.Body, "Contrat : CT-2025-01")
+	}
+}
@@ -0,0 +1,82 @@
+package portals
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/notifications"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidStatus is returned when changing a portal to an unknown status
+	ErrInvalidStatus = errors.New("invalid portal status")
+	// ErrStatusReasonRequired is returned when a status is changed by hand
+	// without saying why
+	ErrStatusReasonRequired = errors.New("a reason is required to change the portal status")
+)
+
+// ChangeStatus moves a portal to a new operational status and logs the
+// change. The change carries the reason and who made it, nil is returned
+// when the portal already has the status. The email to the contact and
+// notification recipients of the portal is queued in the same transaction.
+func ChangeStatus(tx *gorm.DB, portalID uint, status models.PortalStatus, change models.PortalStatusChange) (*models.PortalStatusChange, error) {
+	if !status.IsValid() {
+		return nil, ErrInvalidStatus
+	}
+	change.Reason = strings.TrimSpace(change.Reason)
+	if change.Reason == "" {
+		return nil, ErrStatusReasonRequired
+	}
+
+	var portal models.Portal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&portal, portalID).Error; err != nil {
+		return nil, err
+	}
+	if portal.OperationalStatus() == status {
+		return nil, nil
+	}
+
+	change.PortalID = portal.ID
+	change.FromStatus = portal.OperationalStatus()
+	change.ToStatus = status
+	if err := tx.Omit(clause.Associations).Create(&change).Error; err != nil {
+		return nil, fmt.Errorf("failed to log status change: %w", err)
+	}
+	err := tx.Model(&portal).Updates(map[string]any{
+		"status":            status,
+		"status_changed_at": change.CreatedAt,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update portal status: %w", err)
+	}
+
+	outgoing := statusEmail(&portal, &change)
+	if _, err := notifications.Notify(tx.Statement.Context, tx, &portal, statusEvent(change.ToStatus), outgoing, templates.PortalStatusEmail(&portal, &change)); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// statusEvent returns the notification event of a move to a status. Other
+// status changes are only notified to the contact of the portal.
+func statusEvent(status models.PortalStatus) models.NotificationEvent {
+	if status == models.PortalStatusOutOfService {
+		return models.NotificationEventPortalOutOfService
+	}
+	return ""
+}
+
+// statusEmail returns the email telling about a status change, to be sent
+// to the recipients of the portal
+func statusEmail(portal *models.Portal, change *models.PortalStatusChange) *models.OutboxEmail {
+	return &models.OutboxEmail{
+		Kind:     models.OutboxEmailKindPortalStatus,
+		Subject:  fmt.Sprintf("Portail %s : %s", portal.Name, change.ToStatus.Label()),
+		PortalID: &portal.ID,
+	}
+}
@@ -0,0 +1,64 @@
+package portals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"github.com/troptropcontent/qr_code_maintenance/internal/services/outbox"
+	"github.com/troptropcontent/qr_code_maintenance/internal/templates"
+)
+
+func statusChange(to models.PortalStatus) *models.PortalStatusChange {
+	return &models.PortalStatusChange{
+		FromStatus: models.PortalStatusInService,
+		ToStatus:   to,
+		Reason:     "Cellules de sécurité hors d'usage",
+		CreatedAt:  time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestChangeStatus_InvalidStatus(t *testing.T) {
+	_, err := ChangeStatus(nil, 1, "broken", models.PortalStatusChange{Reason: "test"})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestChangeStatus_ReasonRequired(t *testing.T) {
+	_, err := ChangeStatus(nil, 1, models.PortalStatusDegraded, models.PortalStatusChange{Reason: "  "})
+	assert.ErrorIs(t, err, ErrStatusReasonRequired)
+}
+
+func TestStatusEmail(t *testing.T) {
+	portal := &models.Portal{ID: 3, Name: "Portail Nord", ContactEmail: "contact@example.com", ContactPhone: "0600000000"}
+
+	change := statusChange(models.PortalStatusOutOfService)
+	outgoing := statusEmail(portal, change)
+	require.NotNil(t, outgoing)
+	require.NoError(t, outbox.Render(context.Background(), outgoing, templates.PortalStatusEmail(portal, change)))
+	assert.Equal(t, models.OutboxEmailKindPortalStatus, outgoing.Kind)
+	assert.Equal(t, uint(3), *outgoing.PortalID)
+	assert.Equal(t, "Portail Portail Nord : Hors service", outgoing.Subject)
+	assert.Contains(t, outgoing.Body, "Ancien statut : En service")
+	assert.Contains(t, outgoing.Body, "Motif : Cellules de sécurité hors d'usage")
+	assert.Contains(t, outgoing.Body, "ne doit pas être utilisé")
+	assert.Contains(t, outgoing.Body, "Téléphone astreinte : 0600000000")
+}
+
+func TestStatusEmail_WithoutWarning(t *testing.T) {
+	portal := &models.Portal{Name: "Portail Nord", ContactEmail: "contact@example.com"}
+
+	change := statusChange(models.PortalStatusDegraded)
+	outgoing := statusEmail(portal, change)
+	require.NotNil(t, outgoing)
+	require.NoError(t, outbox.Render(context.Background(), outgoing, templates.PortalStatusEmail(portal, change)))
+	assert.NotContains(t, outgoing.Body, "ne doit pas être utilisé")
+	assert.NotContains(t, outgoing.Body, "astreinte")
+}
+
+func TestStatusEvent(t *testing.T) {
+	assert.Equal(t, models.NotificationEventPortalOutOfService, statusEvent(models.PortalStatusOutOfService))
+	assert.Equal(t, models.NotificationEvent(""), statusEvent(models.PortalStatusDegraded))
+}
@@ -0,0 +1,52 @@
+package portals
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+)
+
+// LastPreventiveVisits returns the date of the last validated preventive
+// intervention of each of the portals. Portals never visited are missing
+// from the map.
+func LastPreventiveVisits(db *gorm.DB, portalIDs []uint) (map[uint]time.Time, error) {
+	visits := make(map[uint]time.Time, len(portalIDs))
+	if len(portalIDs) == 0 {
+		return visits, nil
+	}
+
+	var rows []struct {
+		PortalID  uint
+		LastVisit time.Time
+	}
+	// Interventions recorded before types existed are preventive visits
+	err := db.Model(&models.Intervention{}).
+		Select("portal_id, MAX(date) AS last_visit").
+		Where("portal_id IN ? AND status = ?", portalIDs, models.InterventionStatusValidated).
+		Where("type IS NULL OR type = ?", models.InterventionTypePreventive).
+		Group("portal_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch last preventive visits: %w", err)
+	}
+
+	for _, row := range rows {
+		visits[row.PortalID] = row.LastVisit
+	}
+	return visits, nil
+}
+
+// LastPreventiveVisit returns the date of the last validated preventive
+// intervention of a portal, nil when it was never visited
+func LastPreventiveVisit(db *gorm.DB, portalID uint) (*time.Time, error) {
+	visits, err := LastPreventiveVisits(db, []uint{portalID})
+	if err != nil {
+		return nil, err
+	}
+	if visit, ok := visits[portalID]; ok {
+		return &visit, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		_, err := ParseSchedule(expression)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, expression)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	from := time.Date(2025, 3, 12, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 12, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 3, 12, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, 3, 12, 10, 25, 0, 0, time.UTC)},
+		{"0,30 8-18 * * *", time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 12, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 7 * * 1", time.Date(2025, 3, 17, 7, 0, 0, 0, time.UTC)},
+		{"0 7 * * 7", time.Date(2025, 3, 16, 7, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{"0 0 20 * 5", time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expression)
+		require.NoError(t, err, tt.expression)
+		assert.Equal(t, tt.want, schedule.Next(from), tt.expression)
+	}
+}
+
+func TestSchedule_Next_StrictlyAfter(t *testing.T) {
+	schedule, err := ParseSchedule("30 3 * * *")
+	require.NoError(t, err)
+
+	from := time.Date(2025, 3, 12, 3, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 13, 3, 30, 0, 0, time.UTC), schedule.Next(from))
+}
+
+func TestSchedule_Next_Never(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	require.NoError(t, err)
+
+	assert.True(t, schedule.Next(time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)).IsZero())
+}
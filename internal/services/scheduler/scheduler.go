@@ -0,0 +1,355 @@
+// Package scheduler runs recurring tasks on cron schedules. Every replica runs
+// a scheduler; a Postgres advisory lock per task elects the one that runs it,
+// and the shared scheduled_tasks rows make sure a due run happens only once.
+// Local tasks, which work on the host, run on every replica instead.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownTask is returned when requesting a run of a task that does not
+// exist
+var ErrUnknownTask = errors.New("unknown scheduled task")
+
+// Task is a recurring piece of work
+type Task struct {
+	// Name identifies the task across restarts and replicas
+	Name        string
+	Description string
+	// Schedule is the cron expression of the task, in the server time zone
+	Schedule string
+	// Timeout cancels the context of a run that takes too long
+	Timeout time.Duration
+	// Local tasks run on every replica on their schedule, for work on the
+	// files of the host. They are neither elected nor recorded, and cannot be
+	// run from the admin pages.
+	Local bool
+	Run   func(ctx context.Context) error
+}
+
+type registeredTask struct {
+	Task
+	schedule Schedule
+}
+
+// Scheduler runs the registered tasks when they are due or were requested
+// from the admin pages
+type Scheduler struct {
+	db       *gorm.DB
+	tick     time.Duration
+	instance string
+	tasks    map[string]registeredTask
+
+	mu      sync.Mutex
+	running map[string]bool
+	// localNext holds the next run of the local tasks
+	localNext map[string]time.Time
+	wg        sync.WaitGroup
+}
+
+// New creates a scheduler checking for due tasks every tick, 30 seconds when
+// zero
+func New(db *gorm.DB, tick time.Duration) *Scheduler {
+	if tick <= 0 {
+		tick = 30 * time.Second
+	}
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		db:        db,
+		tick:      tick,
+		instance:  hostname + ":" + strconv.Itoa(os.Getpid()),
+		tasks:     make(map[string]registeredTask),
+		running:   make(map[string]bool),
+		localNext: make(map[string]time.Time),
+	}
+}
+
+// Register adds a task to the scheduler, before it runs
+func (s *Scheduler) Register(task Task) error {
+	schedule, err := ParseSchedule(task.Schedule)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", task.Name, err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("task %s: %w: %q never runs", task.Name, ErrInvalidSchedule, task.Schedule)
+	}
+	if task.Timeout <= 0 {
+		task.Timeout = 10 * time.Minute
+	}
+	s.tasks[task.Name] = registeredTask{Task: task, schedule: schedule}
+	return nil
+}
+
+// Run saves the registered tasks then starts the due ones until ctx is
+// cancelled. It returns once the running tasks have finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	if err := s.syncTasks(time.Now()); err != nil {
+		log.Printf("Failed to save scheduled tasks: %v", err)
+	}
+
+	for name, task := range s.tasks {
+		if task.Local {
+			s.localNext[name] = task.schedule.Next(time.Now())
+		}
+	}
+
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+	for {
+		s.dispatchDue(time.Now())
+		s.dispatchLocal(time.Now())
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RequestRun asks the scheduler to run a task as soon as possible, on
+// whichever replica gets it first
+func RequestRun(db *gorm.DB, taskID uint) error {
+	result := db.Model(&models.ScheduledTask{}).Where("id = ?", taskID).Update("run_requested_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to request task run: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUnknownTask
+	}
+	return nil
+}
+
+// PurgeRuns deletes the run history older than before
+func PurgeRuns(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("started_at < ? AND status <> ?", before, models.TaskRunStatusRunning).Delete(&models.TaskRun{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge task runs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// syncTasks creates the rows of new tasks and updates the description and
+// schedule of existing ones. A changed schedule moves the next run. Local
+// tasks have no row, those left by tasks made local are deleted.
+func (s *Scheduler) syncTasks(now time.Time) error {
+	var local []string
+	for name, task := range s.tasks {
+		if task.Local {
+			local = append(local, name)
+		}
+	}
+	if len(local) > 0 {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			tasks := tx.Model(&models.ScheduledTask{}).Select("id").Where("name IN ?", local)
+			if err := tx.Where("task_id IN (?)", tasks).Delete(&models.TaskRun{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("name IN ?", local).Delete(&models.ScheduledTask{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete local tasks: %w", err)
+		}
+	}
+
+	for _, task := range s.tasks {
+		if task.Local {
+			continue
+		}
+		row := models.ScheduledTask{
+			Name:        task.Name,
+			Description: task.Description,
+			Schedule:    task.Schedule,
+			NextRunAt:   task.schedule.Next(now),
+		}
+		err := s.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "name"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "description"}, Value: row.Description},
+				{Column: clause.Column{Name: "schedule"}, Value: row.Schedule},
+				{Column: clause.Column{Name: "next_run_at"}, Value: gorm.Expr("CASE WHEN scheduled_tasks.schedule = ? THEN scheduled_tasks.next_run_at ELSE ? END", row.Schedule, row.NextRunAt)},
+				{Column: clause.Column{Name: "updated_at"}, Value: now},
+			},
+		}).Create(&row).Error
+		if err != nil {
+			return fmt.Errorf("task %s: %w", task.Name, err)
+		}
+	}
+	return nil
+}
+
+// dispatchDue starts the registered tasks that are due or were requested and
+// are not already running in this process
+func (s *Scheduler) dispatchDue(now time.Time) {
+	var due []models.ScheduledTask
+	if err := s.db.Where("next_run_at <= ? OR run_requested_at IS NOT NULL", now).Find(&due).Error; err != nil {
+		log.Printf("Failed to fetch due tasks: %v", err)
+		return
+	}
+
+	for _, row := range due {
+		task, ok := s.tasks[row.Name]
+		if !ok || task.Local || !s.markRunning(task.Name) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.markDone(task.Name)
+			if err := s.runExclusive(task, row.ID); err != nil {
+				log.Printf("Scheduled task %s: %v", task.Name, err)
+			}
+		}()
+	}
+}
+
+// dispatchLocal starts the local tasks that are due and are not already
+// running
+func (s *Scheduler) dispatchLocal(now time.Time) {
+	for name, next := range s.localNext {
+		task := s.tasks[name]
+		if next.After(now) || !s.markRunning(name) {
+			continue
+		}
+		s.localNext[name] = task.schedule.Next(now)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.markDone(task.Name)
+			if err := execute(task); err != nil {
+				log.Printf("Local task %s: %v", task.Name, err)
+			}
+		}()
+	}
+}
+
+func (s *Scheduler) markRunning(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *Scheduler) markDone(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
+
+// runExclusive runs the task if this replica wins its advisory lock and the
+// task is still due once the lock is held. The session lock lives on one
+// pooled connection and is released when the run ends, or when the
+// connection is lost with the replica.
+func (s *Scheduler) runExclusive(task registeredTask, taskID uint) error {
+	return s.db.Connection(func(conn *gorm.DB) error {
+		key := lockKey(task.Name)
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to take lock: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				log.Printf("Failed to release lock of task %s: %v", task.Name, err)
+			}
+		}()
+
+		// Another replica may have run it between the check and the lock
+		var row models.ScheduledTask
+		if err := conn.First(&row, taskID).Error; err != nil {
+			return fmt.Errorf("failed to reload task: %w", err)
+		}
+		now := time.Now()
+		trigger, ok := runTrigger(&row, now)
+		if !ok {
+			return nil
+		}
+
+		updates := map[string]any{"run_requested_at": nil}
+		if trigger == models.TaskRunTriggerSchedule {
+			updates["next_run_at"] = task.schedule.Next(now)
+		}
+		if err := conn.Model(&row).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
+
+		run := models.TaskRun{
+			TaskID:    row.ID,
+			Trigger:   trigger,
+			Status:    models.TaskRunStatusRunning,
+			Instance:  s.instance,
+			StartedAt: now,
+		}
+		if err := conn.Omit(clause.Associations).Create(&run).Error; err != nil {
+			return fmt.Errorf("failed to record run: %w", err)
+		}
+
+		runErr := execute(task)
+		finishedAt := time.Now()
+		run.Status = models.TaskRunStatusSucceeded
+		if runErr != nil {
+			message := runErr.Error()
+			run.Status = models.TaskRunStatusFailed
+			run.Error = &message
+		}
+		err := conn.Model(&run).Updates(map[string]any{
+			"status":      run.Status,
+			"error":       run.Error,
+			"finished_at": finishedAt,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to record run result: %w", err)
+		}
+		return runErr
+	})
+}
+
+// runTrigger tells why the task should run now, a pending manual request
+// first. ok is false when it should not.
+func runTrigger(row *models.ScheduledTask, now time.Time) (trigger models.TaskRunTrigger, ok bool) {
+	switch {
+	case row.RunRequestedAt != nil:
+		return models.TaskRunTriggerManual, true
+	case !row.NextRunAt.After(now):
+		return models.TaskRunTriggerSchedule, true
+	}
+	return "", false
+}
+
+// execute runs the task with its timeout. Its context does not derive from
+// the scheduler one so stopping the scheduler lets it finish.
+func execute(task registeredTask) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), task.Timeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("task panicked: %v", recovered)
+		}
+	}()
+	return task.Run(ctx)
+}
+
+// lockKey derives the advisory lock key of a task from its name
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("scheduler:" + name))
+	return int64(hash.Sum64())
+}
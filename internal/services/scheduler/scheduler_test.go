@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/troptropcontent/qr_code_maintenance/internal/models"
+)
+
+func TestScheduler_Register(t *testing.T) {
+	s := New(nil, 0)
+
+	assert.ErrorIs(t, s.Register(Task{Name: "broken", Schedule: "* *"}), ErrInvalidSchedule)
+	assert.ErrorIs(t, s.Register(Task{Name: "never", Schedule: "0 0 30 2 *"}), ErrInvalidSchedule)
+	require.NoError(t, s.Register(Task{Name: "daily", Schedule: "@daily"}))
+	assert.Equal(t, 10*time.Minute, s.tasks["daily"].Timeout)
+}
+
+func TestRunTrigger(t *testing.T) {
+	now := time.Date(2025, 3, 12, 10, 0, 0, 0, time.UTC)
+
+	trigger, ok := runTrigger(&models.ScheduledTask{NextRunAt: now.Add(time.Hour)}, now)
+	assert.False(t, ok)
+	assert.Empty(t, trigger)
+
+	trigger, ok = runTrigger(&models.ScheduledTask{NextRunAt: now}, now)
+	assert.True(t, ok)
+	assert.Equal(t, models.TaskRunTriggerSchedule, trigger)
+
+	// A manual request wins and leaves the schedule untouched
+	trigger, ok = runTrigger(&models.ScheduledTask{NextRunAt: now.Add(-time.Minute), RunRequestedAt: &now}, now)
+	assert.True(t, ok)
+	assert.Equal(t, models.TaskRunTriggerManual, trigger)
+}
+
+func TestExecute(t *testing.T) {
+	err := execute(registeredTask{Task: Task{Timeout: time.Minute, Run: func(ctx context.Context) error {
+		panic("boom")
+	}}})
+	assert.EqualError(t, err, "task panicked: boom")
+
+	err = execute(registeredTask{Task: Task{Timeout: time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestScheduler_DispatchLocal(t *testing.T) {
+	s := New(nil, 0)
+	runs := make(chan struct{}, 2)
+	require.NoError(t, s.Register(Task{Name: "cleanup", Schedule: "15 * * * *", Local: true, Run: func(ctx context.Context) error {
+		runs <- struct{}{}
+		return nil
+	}}))
+
+	now := time.Date(2025, 3, 12, 10, 20, 0, 0, time.UTC)
+	s.localNext["cleanup"] = now.Add(-time.Minute)
+	s.dispatchLocal(now)
+	s.wg.Wait()
+	assert.Len(t, runs, 1)
+	assert.Equal(t, time.Date(2025, 3, 12, 11, 15, 0, 0, time.UTC), s.localNext["cleanup"])
+
+	// Not due again before the next run
+	s.dispatchLocal(now.Add(time.Minute))
+	s.wg.Wait()
+	assert.Len(t, runs, 1)
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by backends when no object exists under a key
+var ErrNotFound = errors.New("object not found")
+
+// Backend defines the interface of the places files can be stored in.
+// This interface abstracts storage operations to allow for different
+// implementations (local filesystem, S3 compatible object stores, etc.)
+// while maintaining a consistent API.
+type Backend interface {
+	// Put stores the content read from body under the given key, replacing any
+	// existing object. size is the exact number of bytes body yields.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object stored under the given key. The caller must close
+	// the returned reader. Returns ErrNotFound when the object does not exist.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether an object is stored under the given key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the object stored under the given key. Deleting a missing
+	// object is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBackend(t *testing.T) {
+	backend := NewLocalBackend(t.TempDir())
+	ctx := context.Background()
+
+	require.NoError(t, backend.Put(ctx, "reports/a.pdf", strings.NewReader("content"), 7, "application/pdf"))
+
+	exists, err := backend.Exists(ctx, "reports/a.pdf")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, backend.Delete(ctx, "reports/a.pdf"))
+	_, err = backend.Get(ctx, "reports/a.pdf")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, backend.Delete(ctx, "reports/a.pdf"))
+
+	assert.Error(t, backend.Put(ctx, "../escape", strings.NewReader("x"), 1, ""))
+	assert.Error(t, backend.Put(ctx, "reports/short", strings.NewReader("x"), 2, ""))
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is an in-memory stand-in for MinIO answering the subset of the S3
+// API used by S3Backend
+func fakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=minio/") ||
+			!strings.Contains(authorization, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") ||
+			r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(body)), r.ContentLength)
+			objects[r.URL.Path] = body
+		case http.MethodGet, http.MethodHead:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func testS3Backend(t *testing.T, backend *S3Backend) {
+	ctx := context.Background()
+	key := "reports/ab/cd/report.pdf"
+
+	require.NoError(t, backend.Put(ctx, key, strings.NewReader("%PDF-1.4"), 8, "application/pdf"))
+
+	exists, err := backend.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	reader, err := backend.Get(ctx, key)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(content))
+
+	require.NoError(t, backend.Delete(ctx, key))
+	_, err = backend.Get(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	exists, err = backend.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestS3Backend(t *testing.T) {
+	server := fakeS3(t)
+	defer server.Close()
+
+	testS3Backend(t, NewS3Backend(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "maintenance",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+	}))
+}
+
+func TestS3Backend_Signature(t *testing.T) {
+	fixedTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	backend := NewS3Backend(S3Config{
+		Endpoint:        "http://localhost:9000",
+		Region:          "eu-west-3",
+		Bucket:          "maintenance",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+
+	req, err := backend.newRequest(context.Background(), http.MethodGet, "photos/a b.png", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/maintenance/photos/a%20b.png", req.URL.EscapedPath())
+
+	first := req.Clone(context.Background())
+	backend.sign(first, fixedTime)
+	second := req.Clone(context.Background())
+	backend.sign(second, fixedTime)
+
+	authorization := first.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/20250102/eu-west-3/s3/aws4_request, "))
+	assert.Equal(t, authorization, second.Header.Get("Authorization"))
+	assert.Equal(t, "20250102T030405Z", first.Header.Get("X-Amz-Date"))
+}
+
+// Integration test against a real MinIO server, e.g. the one of the dev container.
+// Skipped unless INTEGRATION_TEST and the S3 environment variables are set.
+func TestS3Backend_Integration(t *testing.T) {
+	if os.Getenv("INTEGRATION_TEST") == "" {
+		t.Skip("Skipping integration test. Set INTEGRATION_TEST=1 to run")
+	}
+	if os.Getenv(S3_ENDPOINT_ENV_VAR) == "" || os.Getenv(S3_BUCKET_ENV_VAR) == "" {
+		t.Skip("Skipping integration test. Required environment variables not set: S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY")
+	}
+
+	testS3Backend(t, NewS3Backend(S3Config{
+		Endpoint:        os.Getenv(S3_ENDPOINT_ENV_VAR),
+		Region:          os.Getenv(S3_REGION_ENV_VAR),
+		Bucket:          os.Getenv(S3_BUCKET_ENV_VAR),
+		AccessKeyID:     os.Getenv(S3_ACCESS_KEY_ID_ENV_VAR),
+		SecretAccessKey: os.Getenv(S3_SECRET_ACCESS_KEY_ENV_VAR),
+	}))
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/troptropcontent/qr_code_maintenance/internal/utils"
+)
+
+const STORAGE_BACKEND_ENV_VAR string = "STORAGE_BACKEND"
+const STORAGE_LOCAL_DIR_ENV_VAR string = "STORAGE_LOCAL_DIR"
+const STORAGE_URL_SECRET_ENV_VAR string = "STORAGE_URL_SECRET"
+const S3_ENDPOINT_ENV_VAR string = "S3_ENDPOINT"
+const S3_REGION_ENV_VAR string = "S3_REGION"
+const S3_BUCKET_ENV_VAR string = "S3_BUCKET"
+const S3_ACCESS_KEY_ID_ENV_VAR string = "S3_ACCESS_KEY_ID"
+const S3_SECRET_ACCESS_KEY_ENV_VAR string = "S3_SECRET_ACCESS_KEY"
+
+// FilesPath is the path signed download URLs are served under
+const FilesPath = "/files/"
+
+var (
+	// ErrTooLarge is returned when a file exceeds the size allowed for its namespace
+	ErrTooLarge = errors.New("file too large")
+	// ErrTypeNotAllowed is returned when the type of a file is not allowed in its namespace
+	ErrTypeNotAllowed = errors.New("file type not allowed")
+	// ErrInvalidSignature is returned when a download URL is forged or expired
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+// Namespace groups files of the same nature under a common key prefix and
+// upload policy
+type Namespace struct {
+	Prefix  string
+	MaxSize int64
+	// Types maps the accepted content types to the extension of their keys
+	Types map[string]string
+}
+
+var (
+	Photos = Namespace{
+		Prefix:  "photos",
+		MaxSize: 15 << 20,
+		Types:   map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp"},
+	}
+	Signatures = Namespace{
+		Prefix:  "signatures",
+		MaxSize: 1 << 20,
+		Types:   map[string]string{"image/png": ".png", "image/svg+xml": ".svg"},
+	}
+	Reports = Namespace{
+		Prefix:  "reports",
+		MaxSize: 20 << 20,
+		Types:   map[string]string{"application/pdf": ".pdf"},
+	}
+)
+
+// Object describes a file saved in the storage
+type Object struct {
+	Key         string
+	SHA256      string
+	Size        int64
+	ContentType string
+}
+
+// Service saves files in a backend under content addressed keys and signs
+// time limited download URLs for them
+type Service struct {
+	backend Backend
+	secret  []byte
+}
+
+// NewService creates a storage service on top of a backend. secret is used to
+// sign download URLs.
+func NewService(backend Backend, secret string) *Service {
+	return &Service{backend: backend, secret: []byte(secret)}
+}
+
+// NewServiceFromEnv creates a storage service using the backend selected by
+// the STORAGE_BACKEND environment variable ("local", the default, or "s3")
+func NewServiceFromEnv() (*Service, error) {
+	secret := utils.GetEnv(STORAGE_URL_SECRET_ENV_VAR, "")
+	if secret == "" {
+		return nil, fmt.Errorf("environment variable %s is not set or empty", STORAGE_URL_SECRET_ENV_VAR)
+	}
+
+	switch backend := utils.GetEnv(STORAGE_BACKEND_ENV_VAR, "local"); backend {
+	case "local":
+		root := utils.GetEnv(STORAGE_LOCAL_DIR_ENV_VAR, utils.MustGetPathFromRoot("uploads"))
+		return NewService(NewLocalBackend(root), secret), nil
+	case "s3":
+		config := S3Config{
+			Endpoint:        utils.GetEnv(S3_ENDPOINT_ENV_VAR, ""),
+			Region:          utils.GetEnv(S3_REGION_ENV_VAR, ""),
+			Bucket:          utils.GetEnv(S3_BUCKET_ENV_VAR, ""),
+			AccessKeyID:     utils.GetEnv(S3_ACCESS_KEY_ID_ENV_VAR, ""),
+			SecretAccessKey: utils.GetEnv(S3_SECRET_ACCESS_KEY_ENV_VAR, ""),
+		}
+		for envVar, value := range map[string]string{
+			S3_ENDPOINT_ENV_VAR:          config.Endpoint,
+			S3_BUCKET_ENV_VAR:            config.Bucket,
+			S3_ACCESS_KEY_ID_ENV_VAR:     config.AccessKeyID,
+			S3_SECRET_ACCESS_KEY_ENV_VAR: config.SecretAccessKey,
+		} {
+			if value == "" {
+				return nil, fmt.Errorf("environment variable %s is not set or empty", envVar)
+			}
+		}
+		return NewService(NewS3Backend(config), secret), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// ContentKey returns the key a file with the given SHA-256 digest is stored
+// under in a namespace. Identical files share the same key.
+func ContentKey(namespace Namespace, digest string, extension string) string {
+	return path.Join(namespace.Prefix, digest[:2], digest[2:4], digest+extension)
+}
+
+// Save streams a file into the storage after checking it against the policy
+// of its namespace. The content is spooled to a temporary file while being
+// hashed so that it is only read once from r.
+func (s *Service) Save(ctx context.Context, namespace Namespace, r io.Reader) (*Object, error) {
+	spool, err := os.CreateTemp("", "storage_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), io.LimitReader(r, namespace.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if size > namespace.MaxSize {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrTooLarge, namespace.MaxSize)
+	}
+
+	head := make([]byte, 512)
+	n, err := spool.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	contentType := detectContentType(head[:n])
+	extension, ok := namespace.Types[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	object := &Object{
+		Key:         ContentKey(namespace, digest, extension),
+		SHA256:      digest,
+		Size:        size,
+		ContentType: contentType,
+	}
+
+	exists, err := s.backend.Exists(ctx, object.Key)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return object, nil
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind spool file: %w", err)
+	}
+	if err := s.backend.Put(ctx, object.Key, spool, size, contentType); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// Open opens the file stored under key. The caller must close the reader.
+func (s *Service) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.backend.Get(ctx, key)
+}
+
+// ReadAll returns the content of the file stored under key
+func (s *Service) ReadAll(ctx context.Context, key string) ([]byte, error) {
+	reader, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Delete removes the file stored under key
+func (s *Service) Delete(ctx context.Context, key string) error {
+	return s.backend.Delete(ctx, key)
+}
+
+// SignedURL returns a download URL for the file stored under key that is
+// valid for ttl
+func (s *Service) SignedURL(key string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(key, expires))
+	return FilesPath + key + "?" + query.Encode()
+}
+
+// VerifySignedURL checks the expiry and signature of a download URL built
+// by SignedURL
+func (s *Service) VerifySignedURL(key string, expires string, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.signature(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *Service) signature(key string, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// detectContentType sniffs the content type of a file from its first bytes.
+// SVG images, which http.DetectContentType reports as text, are recognized
+// by their root element.
+func detectContentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if strings.HasPrefix(contentType, "text/xml") || strings.HasPrefix(contentType, "text/plain") {
+		if bytes.Contains(head, []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+	return contentType
+}
+
+// ContentTypeOf returns the content type of a file from the extension of its key
+func ContentTypeOf(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
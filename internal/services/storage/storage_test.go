@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pngBytes = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR fake png content")
+
+func newTestService(t *testing.T) *Service {
+	return NewService(NewLocalBackend(t.TempDir()), "secret")
+}
+
+func TestService_Save(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	object, err := service.Save(ctx, Photos, bytes.NewReader(pngBytes))
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/png", object.ContentType)
+	assert.Equal(t, int64(len(pngBytes)), object.Size)
+	assert.Len(t, object.SHA256, 64)
+	assert.Equal(t, "photos/"+object.SHA256[:2]+"/"+object.SHA256[2:4]+"/"+object.SHA256+".png", object.Key)
+
+	content, err := service.ReadAll(ctx, object.Key)
+	require.NoError(t, err)
+	assert.Equal(t, pngBytes, content)
+
+	// Identical content is stored once under the same key
+	again, err := service.Save(ctx, Photos, bytes.NewReader(pngBytes))
+	require.NoError(t, err)
+	assert.Equal(t, object.Key, again.Key)
+}
+
+func TestService_Save_Policy(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	_, err := service.Save(ctx, Photos, strings.NewReader("%PDF-1.4 not a photo"))
+	assert.ErrorIs(t, err, ErrTypeNotAllowed)
+
+	small := Namespace{Prefix: "small", MaxSize: 10, Types: Photos.Types}
+	_, err = service.Save(ctx, small, bytes.NewReader(pngBytes))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestService_Save_SVG(t *testing.T) {
+	service := newTestService(t)
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><path d="M0 0L10 10"/></svg>`
+
+	object, err := service.Save(context.Background(), Signatures, strings.NewReader(svg))
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", object.ContentType)
+	assert.True(t, strings.HasSuffix(object.Key, ".svg"))
+	assert.Equal(t, "image/svg+xml", ContentTypeOf(object.Key))
+
+	_, err = service.Save(context.Background(), Photos, strings.NewReader(svg))
+	assert.ErrorIs(t, err, ErrTypeNotAllowed)
+}
+
+func TestService_SignedURL(t *testing.T) {
+	service := newTestService(t)
+	key := "photos/ab/cd/abcd.png"
+
+	signed, err := url.Parse(service.SignedURL(key, time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, FilesPath+key, signed.Path)
+
+	expires := signed.Query().Get("expires")
+	signature := signed.Query().Get("signature")
+	assert.NoError(t, service.VerifySignedURL(key, expires, signature))
+	assert.ErrorIs(t, service.VerifySignedURL("photos/ab/cd/other.png", expires, signature), ErrInvalidSignature)
+	assert.ErrorIs(t, NewService(service.backend, "other").VerifySignedURL(key, expires, signature), ErrInvalidSignature)
+
+	expired, err := url.Parse(service.SignedURL(key, -time.Minute))
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.VerifySignedURL(key, expired.Query().Get("expires"), expired.Query().Get("signature")), ErrInvalidSignature)
+}
@@ -1,6 +1,17 @@
 package utils
 
-import "os"
+import (
+	"os"
+	"strings"
+)
+
+// PUBLIC_BASE_URL_ENV_VAR holds the public URL of the application, used in
+// the links printed on reports and sent by email
+const PUBLIC_BASE_URL_ENV_VAR = "PUBLIC_BASE_URL"
+
+// APP_ENV_ENV_VAR holds the environment the application runs in, set to
+// "development" to enable the development tools
+const APP_ENV_ENV_VAR = "APP_ENV"
 
 func GetEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -15,3 +26,13 @@ func MustGetEnv(key string) string {
 	}
 	panic("required environment variable '" + key + "' is not set")
 }
+
+// PublicURL returns the absolute URL of a path of the application
+func PublicURL(path string) string {
+	return strings.TrimRight(GetEnv(PUBLIC_BASE_URL_ENV_VAR, "http://localhost:8080"), "/") + path
+}
+
+// IsDevelopment reports whether the application runs in development
+func IsDevelopment() bool {
+	return GetEnv(APP_ENV_ENV_VAR, "") == "development"
+}
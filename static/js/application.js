@@ -2,7 +2,13 @@ import "./htmx.min.js"
 import { Application } from "./stimulus.js"
 import QrCodeScannerController from "./controllers/qr_code_scanner_controller.js"
 import LogoutController from "./controllers/logout_controller.js"
+import SignaturePadController from "./controllers/signature_pad_controller.js"
+import InterventionTypeController from "./controllers/intervention_type_controller.js"
+import InterventionDraftController from "./controllers/intervention_draft_controller.js"
 
 window.Stimulus = Application.start()
 Stimulus.register("qr-code-scanner", QrCodeScannerController)
-Stimulus.register("logout", LogoutController)
\ No newline at end of file
+Stimulus.register("logout", LogoutController)
+Stimulus.register("signature-pad", SignaturePadController)
+Stimulus.register("intervention-type", InterventionTypeController)
+Stimulus.register("intervention-draft", InterventionDraftController)
\ No newline at end of file
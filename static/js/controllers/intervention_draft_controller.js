@@ -0,0 +1,77 @@
+import { Controller } from "../stimulus.js"
+
+// Autosaves the intervention form as a draft a moment after the technician
+// stops typing. Photos and signatures are only sent by an explicit save. The
+// first save creates the draft, whose ID is kept in the form for the next ones.
+export default class extends Controller {
+    static targets = ["id", "status"]
+    static values = { url: String, delay: { type: Number, default: 2000 } }
+
+    connect() {
+        this.saving = Promise.resolve()
+        this.pending = 0
+    }
+
+    disconnect() {
+        this.cancel()
+        this.abort?.abort()
+    }
+
+    schedule(event) {
+        if (this.submitting || event.target.type === "file" || (event.target.name || "").startsWith("signature_")) {
+            return
+        }
+        this.cancel()
+        this.timeout = setTimeout(() => {
+            // Saves run one after the other so that only the first one creates the draft
+            this.pending++
+            this.saving = this.saving.then(() => this.save()).finally(() => this.pending--)
+        }, this.delayValue)
+    }
+
+    cancel() {
+        clearTimeout(this.timeout)
+    }
+
+    // Waits for the autosave in flight before submitting the form, so that the
+    // submit updates the draft it created instead of creating another one
+    submit(event) {
+        this.cancel()
+        if (this.pending === 0) {
+            return
+        }
+        event.preventDefault()
+        this.submitting = true
+        this.saving.then(() => this.element.requestSubmit(event.submitter))
+    }
+
+    async save() {
+        const data = new URLSearchParams()
+        for (const [name, value] of new FormData(this.element)) {
+            if (value instanceof File || name.startsWith("signature_") || name === "action") {
+                continue
+            }
+            data.append(name, value)
+        }
+
+        try {
+            this.abort = new AbortController()
+            const response = await fetch(this.urlValue, { method: "POST", body: data, signal: this.abort.signal })
+            if (!response.ok) {
+                throw new Error(`autosave failed with status ${response.status}`)
+            }
+            this.statusTarget.innerHTML = await response.text()
+
+            const saved = this.statusTarget.querySelector("[data-intervention-id]")
+            if (saved) {
+                this.idTarget.value = saved.dataset.interventionId
+            }
+        } catch (error) {
+            if (error.name === "AbortError") {
+                return
+            }
+            console.error(error)
+            this.statusTarget.textContent = "Échec de l'enregistrement automatique"
+        }
+    }
+}
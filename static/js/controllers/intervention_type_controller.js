@@ -0,0 +1,24 @@
+import { Controller } from "../stimulus.js"
+
+// Shows the sections of the intervention form that apply to the selected
+// intervention type. Inputs of hidden sections are disabled so that they are
+// not submitted.
+export default class extends Controller {
+    static targets = ["select", "section"]
+
+    connect() {
+        this.change()
+    }
+
+    change() {
+        const type = this.selectTarget.value
+
+        this.sectionTargets.forEach((section) => {
+            const applies = section.dataset.types.split(" ").includes(type)
+            section.classList.toggle("hidden", !applies)
+            section.querySelectorAll("input, select, textarea").forEach((input) => {
+                input.disabled = !applies
+            })
+        })
+    }
+}
@@ -0,0 +1,84 @@
+import { Controller } from "../stimulus.js"
+
+// Captures a handwritten signature on a canvas and keeps the hidden input
+// filled with its PNG data URL, empty while nothing is drawn
+export default class extends Controller {
+    static targets = ["canvas", "input"]
+
+    connect() {
+        this.drawing = false
+        this.hasStrokes = false
+        this.context = this.canvasTarget.getContext("2d")
+
+        this.onPointerDown = this.start.bind(this)
+        this.onPointerMove = this.move.bind(this)
+        this.onPointerUp = this.end.bind(this)
+
+        this.canvasTarget.addEventListener("pointerdown", this.onPointerDown)
+        this.canvasTarget.addEventListener("pointermove", this.onPointerMove)
+        this.canvasTarget.addEventListener("pointerup", this.onPointerUp)
+        this.canvasTarget.addEventListener("pointerleave", this.onPointerUp)
+
+        this.resize()
+    }
+
+    disconnect() {
+        this.canvasTarget.removeEventListener("pointerdown", this.onPointerDown)
+        this.canvasTarget.removeEventListener("pointermove", this.onPointerMove)
+        this.canvasTarget.removeEventListener("pointerup", this.onPointerUp)
+        this.canvasTarget.removeEventListener("pointerleave", this.onPointerUp)
+    }
+
+    // Matches the canvas resolution to its displayed size, which clears it.
+    // Not done on window resize: mobile browsers resize while scrolling and
+    // the signature would be lost.
+    resize() {
+        const ratio = Math.max(window.devicePixelRatio || 1, 1)
+        this.canvasTarget.width = this.canvasTarget.offsetWidth * ratio
+        this.canvasTarget.height = this.canvasTarget.offsetHeight * ratio
+        this.context.scale(ratio, ratio)
+        this.context.lineWidth = 2
+        this.context.lineCap = "round"
+        this.context.lineJoin = "round"
+        this.context.strokeStyle = "#111827"
+        this.hasStrokes = false
+        this.inputTarget.value = ""
+    }
+
+    clear() {
+        this.resize()
+    }
+
+    start(event) {
+        event.preventDefault()
+        this.drawing = true
+        this.canvasTarget.setPointerCapture(event.pointerId)
+        const { x, y } = this.position(event)
+        this.context.beginPath()
+        this.context.moveTo(x, y)
+    }
+
+    move(event) {
+        if (!this.drawing) return
+
+        event.preventDefault()
+        const { x, y } = this.position(event)
+        this.context.lineTo(x, y)
+        this.context.stroke()
+        this.hasStrokes = true
+    }
+
+    end() {
+        if (!this.drawing) return
+
+        this.drawing = false
+        if (this.hasStrokes) {
+            this.inputTarget.value = this.canvasTarget.toDataURL("image/png")
+        }
+    }
+
+    position(event) {
+        const rect = this.canvasTarget.getBoundingClientRect()
+        return { x: event.clientX - rect.left, y: event.clientY - rect.top }
+    }
+}